@@ -0,0 +1,101 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// AbortedKey is the key of the attribute that is set to true on lines
+// emitted by [Recorder.EmitAborted] because the process was about to exit
+// before the operations they describe completed.
+const AbortedKey = "aborted"
+
+// EmitAborted emits the lines of rec that are still in flight with e, at
+// [slog.LevelError] and with [AbortedKey] set to true, and returns the
+// number of lines emitted, so that operations cut short by the process
+// exiting still leave a canonical line. If e is nil, NewEmitter(nil) is
+// used. The lines are emitted with a context carrying only the line, so
+// enrichers of e do not see the values of the contexts the lines were
+// created in.
+//
+// Lines emitted by EmitAborted are frozen, like those emitted by
+// [Emitter.Emit]; lines being emitted concurrently by their own operations
+// are emitted only once, by whichever gets there first. EmitAborted is
+// intended to be called while the process exits, as by
+// [Recorder.EmitAbortedOnPanic] and [Recorder.EmitAbortedOnSignal].
+func (rec *Recorder) EmitAborted(e *Emitter) int {
+	if e == nil {
+		e = NewEmitter(nil)
+	}
+	n := 0
+	for _, ll := range rec.inFlight() {
+		l := ll.l
+		if !l.freeze() {
+			continue
+		}
+		aborted := []slog.Attr{slog.Bool(AbortedKey, true)}
+		l.record(slog.LevelError, aborted)
+		e.logAll(NewContext(context.Background(), l), slog.LevelError, true, aborted...)
+		n++
+	}
+	return n
+}
+
+// EmitAbortedOnPanic emits the lines of rec that are still in flight, as
+// described for [Recorder.EmitAborted], if the goroutine it is deferred in
+// is panicking, and then continues panicking. It must be deferred directly,
+// typically at the start of main:
+//
+//	func main() {
+//		rec := canonlog.NewRecorder(100)
+//		canonlog.DefaultRegistry.SetRecorder(rec)
+//		defer rec.EmitAbortedOnPanic(nil)
+//		...
+//	}
+//
+// A panic in another goroutine crashes the process without running the
+// deferred calls of main, so goroutines that should be covered, such as
+// workers, must defer EmitAbortedOnPanic themselves. Fatal runtime errors,
+// such as concurrent map writes, cannot be handled at all.
+func (rec *Recorder) EmitAbortedOnPanic(e *Emitter) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	rec.EmitAborted(e)
+	panic(v)
+}
+
+// EmitAbortedOnSignal makes the process emit the lines of rec that are
+// still in flight, as described for [Recorder.EmitAborted], when it
+// receives one of sigs, such as [os.Interrupt] or syscall.SIGTERM, and then
+// receive the signal again with its default handling, which usually
+// terminates it. It returns a function that stops handling the signals.
+//
+// EmitAbortedOnSignal is for processes that do not otherwise handle sigs;
+// those that shut down gracefully on a signal should instead call
+// [Recorder.EmitAborted] once they give up waiting for operations to
+// complete.
+func (rec *Recorder) EmitAbortedOnSignal(e *Emitter, sigs ...os.Signal) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sigs...)
+	go func() {
+		select {
+		case sig := <-c:
+			rec.EmitAborted(e)
+			signal.Reset(sig)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+	return sync.OnceFunc(func() {
+		signal.Stop(c)
+		close(done)
+	})
+}
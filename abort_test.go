@@ -0,0 +1,69 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEmitAborted(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	rec := NewRecorder(10)
+	r.SetRecorder(rec)
+
+	var buf bytes.Buffer
+	logger := testLogger(&buf)
+
+	done := New(context.Background(), WithRegistry(r))
+	Set(done, attrUser, "usr_1")
+	Emit(done, logger, slog.LevelInfo)
+	inFlight := New(context.Background(), WithRegistry(r))
+	Set(inFlight, attrUser, "usr_2")
+
+	buf.Reset()
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Errorf("recovered %v, want the original panic", v)
+			}
+		}()
+		defer rec.EmitAbortedOnPanic(NewEmitter(logger))
+		panic("boom")
+	}()
+
+	want := "level=ERROR msg=canonical-log-line user=usr_2 aborted=true\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// The aborted line is emitted only once.
+	buf.Reset()
+	if n := rec.EmitAborted(NewEmitter(logger)); n != 0 {
+		t.Errorf("EmitAborted emitted %d lines again", n)
+	}
+	Emit(inFlight, logger, slog.LevelInfo) // by its own operation, too late
+	lines := rec.Lines()
+	if len(lines) != 2 || lines[1].Level != slog.LevelError || lines[1].InFlight {
+		t.Errorf("recorded lines = %+v, want the aborted line last", lines)
+	}
+	if got := buf.String(); !strings.Contains(got, " user=usr_2") {
+		t.Errorf("log output = %q, want the line logged again", got)
+	}
+}
+
+func TestEmitAbortedOnPanic_NoPanic(t *testing.T) {
+	r := testRegistry(t)
+	rec := NewRecorder(10)
+	r.SetRecorder(rec)
+	ctx := New(context.Background(), WithRegistry(r))
+
+	func() {
+		defer rec.EmitAbortedOnPanic(NewEmitter(slog.New(slog.DiscardHandler)))
+	}()
+	if FromContext(ctx).frozen.Load() {
+		t.Error("line was emitted without a panic")
+	}
+}
@@ -0,0 +1,217 @@
+package canonlog
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// maxAccumulatorShards bounds the number of shards in an accumulator, and so
+// the work done to combine them when a line is read.
+const maxAccumulatorShards = 64
+
+// accumulated is implemented by accumulators stored as raw values in a
+// [Line], to obtain their combined value regardless of type.
+type accumulated interface {
+	load() any
+}
+
+// adder is implemented by the accumulators of attributes of type T. add
+// reports whether v was merged, rather than dropped for exceeding the size
+// limit of the accumulator's line.
+type adder[T any] interface {
+	accumulated
+	add(v T) bool
+}
+
+// accumulator holds the value of an attribute whose merge function is
+// commutative and associative. Values are merged into a randomly chosen
+// shard, each with its own lock, and the shards are only merged with each
+// other when the value is read.
+type accumulator[T any] struct {
+	merge  func(old, new T) T
+	shards []accumulatorShard[T]
+
+	// line, if not nil, is the line whose size limit the accumulator's
+	// value counts towards, under key.
+	line *Line
+	key  string
+}
+
+type accumulatorShard[T any] struct {
+	mu   sync.Mutex
+	set  bool
+	v    T
+	size int // approximate bytes held, if the accumulator has a line
+
+	_ [64]byte // keep neighbouring shards off the same cache line
+}
+
+func newAccumulator[T any](merge func(old, new T) T) *accumulator[T] {
+	n := min(runtime.GOMAXPROCS(0), maxAccumulatorShards)
+	return &accumulator[T]{
+		merge:  merge,
+		shards: make([]accumulatorShard[T], n),
+	}
+}
+
+// newLimitedAccumulator creates an accumulator for the attribute with the
+// given key in l, whose value counts towards l's size limit. It has a single
+// shard, so that its size is that of the value emitted rather than the sum of
+// partial values repeating the same elements.
+func newLimitedAccumulator[T any](l *Line, key string, merge func(old, new T) T) *accumulator[T] {
+	return &accumulator[T]{
+		merge:  merge,
+		shards: make([]accumulatorShard[T], 1),
+		line:   l,
+		key:    key,
+	}
+}
+
+// add merges v into one of the accumulator's shards. If the accumulator has
+// a line and the merged value would take it over its size limit, v is
+// dropped and counted as such.
+func (a *accumulator[T]) add(v T) bool {
+	s := &a.shards[rand.IntN(len(a.shards))]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.set {
+		v = a.merge(s.v, v)
+	}
+	if a.line != nil {
+		size := sizeOf(a.key, v, a.line.maxBytes)
+		if !a.line.reserve(s.size, size) {
+			return false
+		}
+		s.size = size
+	}
+	s.v, s.set = v, true
+	return true
+}
+
+// charged returns the number of bytes the accumulator's value counts
+// towards the size limit of its line.
+func (a *accumulator[T]) charged() int {
+	var size int
+	for i := range a.shards {
+		s := &a.shards[i]
+		s.mu.Lock()
+		size += s.size
+		s.mu.Unlock()
+	}
+	return size
+}
+
+// load returns the merge of all shards' values.
+func (a *accumulator[T]) load() any {
+	var (
+		result T
+		set    bool
+	)
+	for i := range a.shards {
+		s := &a.shards[i]
+		s.mu.Lock()
+		if s.set {
+			if set {
+				result = a.merge(result, s.v)
+			} else {
+				result, set = s.v, true
+			}
+		}
+		s.mu.Unlock()
+	}
+	return result
+}
+
+// accumulate is the implementation of [Set] for attributes declared with
+// [WithCommutativeMerge]. Only the first Set of the attribute in a Line takes
+// the Line's lock, to create the accumulator.
+func accumulate[T any](ctx context.Context, l *Line, attr Attr[T], value T) {
+	if l == nil {
+		noLine(attr.key)
+		return
+	}
+	if l.lateSet(attr.key) {
+		return
+	}
+	if !attr.valid(value) {
+		return
+	}
+
+	accumulateOn(ctx, l, attr, value)
+}
+
+// accumulateOn merges value into the accumulator for attr in l, and reports
+// whether it did so within l's size limit.
+func accumulateOn[T any](ctx context.Context, l *Line, attr Attr[T], value T) bool {
+	v, ok := l.accums.Load(attr.key)
+	var created, merged bool
+	if !ok {
+		v, created, merged = loadOrCreateAccumulator(l, attr, value)
+	}
+	switch acc, ok := v.(adder[T]); {
+	case created:
+		if !merged {
+			return false
+		}
+	case !ok:
+		// The key is accumulating a different type, which can only
+		// happen with attributes of the same key from different
+		// registries; fall back to overwriting it.
+		if !store(l, attr, value, attr.merge) {
+			return false
+		}
+	case !acc.add(value):
+		return false
+	}
+	l.recordCaller(attr.key)
+	runSetHooks(ctx, l, attr.key, value)
+	return true
+}
+
+// loadOrCreateAccumulator returns the accumulator for attr in l. If it does
+// not exist yet, it is created with value merged into it, and created is
+// true; merged reports whether value was merged rather than dropped for
+// exceeding l's size limit. A value already stored for the key by a plain
+// Set of the same type becomes the accumulator's initial value. The
+// accumulator is not stored if it would hold no value.
+func loadOrCreateAccumulator[T any](l *Line, attr Attr[T], value T) (v any, created, merged bool) {
+	key := attr.key
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := l.accums.Load(key); ok {
+		return v, false, false
+	}
+
+	// The existing value is counted again by the accumulator, except by
+	// that of a counter, whose fixed-size value is not counted.
+	existing, _ := s.get(key)
+	l.bytes.Add(-int64(existing.charged()))
+
+	var acc adder[T]
+	switch {
+	case attr.newAdder != nil:
+		acc = attr.newAdder()
+	case l.maxBytes != 0:
+		acc = newLimitedAccumulator(l, key, attr.merge)
+	default:
+		acc = newAccumulator(attr.merge)
+	}
+	var kept bool
+	if v, ok := existing.raw.(T); ok {
+		kept = acc.add(v)
+	}
+	merged = acc.add(value)
+	if !kept && !merged {
+		l.bytes.Add(int64(existing.charged()))
+		return nil, true, false
+	}
+
+	l.put(s, key, newStoredValue(attr, acc))
+	l.accums.Store(key, acc)
+	return acc, true, merged
+}
@@ -0,0 +1,156 @@
+package canonlog
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"testing/quick"
+)
+
+// checkMergeProperties uses property-based testing to check that the merge
+// function of an attribute declared with [WithCommutativeMerge] is in fact
+// commutative and associative, comparing results with equal. If cfg is nil,
+// values are generated as described by [quick.Value].
+func checkMergeProperties[T any](t *testing.T, attr Attr[T], equal func(a, b T) bool, cfg *quick.Config) {
+	t.Helper()
+
+	if !attr.commutative || attr.merge == nil {
+		t.Fatalf("attribute %q is not declared with a commutative merge", attr.key)
+	}
+	merge := attr.merge
+
+	commutative := func(a, b T) bool {
+		return equal(merge(a, b), merge(b, a))
+	}
+	if err := quick.Check(commutative, cfg); err != nil {
+		t.Errorf("merge for %q is not commutative: %v", attr.key, err)
+	}
+
+	associative := func(a, b, c T) bool {
+		return equal(merge(merge(a, b), c), merge(a, merge(b, c)))
+	}
+	if err := quick.Check(associative, cfg); err != nil {
+		t.Errorf("merge for %q is not associative: %v", attr.key, err)
+	}
+}
+
+func TestMergeProperties(t *testing.T) {
+	r := testRegistry(t)
+
+	t.Run("set", func(t *testing.T) {
+		checkMergeProperties(t, RegisterSetWith[string](r, "set"), slices.Equal[[]string], nil)
+	})
+	t.Run("map", func(t *testing.T) {
+		checkMergeProperties(t, RegisterMapWith[int64](r, "map"), maps.Equal[map[string]int64], nil)
+	})
+	t.Run("sum", func(t *testing.T) {
+		attr := RegisterWith(r, "sum",
+			WithMerge(func(old, new uint32) uint32 { return old + new }),
+			WithCommutativeMerge[uint32](),
+		)
+		checkMergeProperties(t, attr, func(a, b uint32) bool { return a == b }, nil)
+	})
+}
+
+func TestWithCommutativeMerge_Order(t *testing.T) {
+	r := testRegistry(t)
+
+	// A later WithMerge replaces the merge function, and so also drops
+	// the commutativity declaration made for the earlier one.
+	attr := RegisterWith(r, "last",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+		WithMerge(func(old, new int) int { return new }),
+	)
+	if attr.commutative {
+		t.Error("attribute is declared commutative after WithMerge replaced its merge function")
+	}
+}
+
+func TestConcurrentSet_Commutative(t *testing.T) {
+	r := testRegistry(t)
+
+	attrCounter := RegisterWith(r, "counter",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+	attrOther := RegisterWith[string](r, "other")
+
+	ctx := New(context.Background())
+	Set(ctx, attrOther, "first")
+
+	var wg sync.WaitGroup
+	const numGoroutines, numSets = 32, 100
+	for range numGoroutines {
+		wg.Go(func() {
+			for range numSets {
+				Set(ctx, attrCounter, 1)
+			}
+		})
+	}
+	wg.Wait()
+
+	attrs := Attrs(ctx)
+	if len(attrs) != 2 {
+		t.Fatalf("Attrs() returned %d attributes, want 2", len(attrs))
+	}
+	if attrs[1].Key != "counter" {
+		t.Errorf("attrs[1].Key = %q, want %q", attrs[1].Key, "counter")
+	}
+	if got := attrs[1].Value.Int64(); got != numGoroutines*numSets {
+		t.Errorf("counter = %d, want %d", got, numGoroutines*numSets)
+	}
+}
+
+func TestCommutativeSetAfterPlainSet(t *testing.T) {
+	r := testRegistry(t)
+	sum := func(old, new int) int { return old + new }
+
+	plain := RegisterWith[int](r, "total")
+	commutative := RegisterWith(NewRegistry(), "total", WithMerge(sum), WithCommutativeMerge[int]())
+
+	ctx := New(context.Background())
+	Set(ctx, plain, 10)
+	Set(ctx, commutative, 5)
+	Set(ctx, commutative, 5)
+
+	attrs := Attrs(ctx)
+	if len(attrs) != 1 {
+		t.Fatalf("Attrs() returned %d attributes, want 1", len(attrs))
+	}
+	if got := attrs[0].Value.Int64(); got != 20 {
+		t.Errorf("total = %d, want 20", got)
+	}
+
+	// Overwriting with the plain attribute replaces the accumulated value.
+	Set(ctx, plain, 1)
+	Set(ctx, commutative, 2)
+	if got := Attrs(ctx)[0].Value.Int64(); got != 3 {
+		t.Errorf("total = %d, want 3", got)
+	}
+}
+
+func BenchmarkSetParallel(b *testing.B) {
+	sum := func(old, new int) int { return old + new }
+
+	b.Run("merge", func(b *testing.B) {
+		attr := RegisterWith(NewRegistry(), "counter", WithMerge(sum))
+		ctx := New(context.Background())
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				Set(ctx, attr, 1)
+			}
+		})
+	})
+	b.Run("commutative", func(b *testing.B) {
+		attr := RegisterWith(NewRegistry(), "counter", WithMerge(sum), WithCommutativeMerge[int]())
+		ctx := New(context.Background())
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				Set(ctx, attr, 1)
+			}
+		})
+	})
+}
@@ -0,0 +1,45 @@
+package canonlog
+
+import (
+	"log/slog"
+	"maps"
+)
+
+// WithAlias makes the attribute also emitted under each of the given keys,
+// so that an attribute can be renamed without breaking the dashboards and
+// queries that use its old key, by emitting both for a transition period:
+//
+//	var AttrUserID = canonlog.Register("user_id", canonlog.WithAlias[string]("uid"))
+//	// emitted as user_id=usr_123 uid=usr_123
+//
+// Each alias is emitted after the attribute, at the top level of the line
+// even if the attribute is registered [WithGroup]. Aliases
+// are reserved in the registry like the keys of attributes, and are
+// returned by [Registry.Aliases].
+func WithAlias[T any](keys ...string) Option[T] {
+	return func(a *Attr[T]) {
+		a.aliases = append(a.aliases, keys...)
+	}
+}
+
+// Aliases returns the aliases of the attributes registered in r with
+// [WithAlias], mapped to the keys of the attributes, for exporting the
+// registry's schema, so that consumers can tell aliases apart from
+// attributes in their own right.
+func (r *Registry) Aliases() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	aliases := maps.Clone(r.aliases)
+	if aliases == nil {
+		aliases = make(map[string]string)
+	}
+	return aliases
+}
+
+// addAliases appends the attributes emitted under the aliases of an
+// attribute with the given value to b.
+func (b *attrsBuilder) addAliases(aliases []string, value slog.Value) {
+	for _, alias := range aliases {
+		b.result = append(b.result, slog.Attr{Key: alias, Value: value})
+	}
+}
@@ -0,0 +1,50 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+)
+
+func TestWithAlias(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith(r, "user_id", WithAlias[string]("uid", "user"))
+	attrQueries := RegisterWith(r, "queries", WithGroup[int]("db"), WithAlias[int]("db_queries"))
+	attrRegion := RegisterWith(r, "region", WithAlias[string]("dc"))
+	SetGlobalWith(r, attrRegion, "eu")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrQueries, 4)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line user_id=usr_123 uid=usr_123 user=usr_123 db.queries=4 db_queries=4 region=eu dc=eu\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	wantAliases := map[string]string{
+		"uid":        "user_id",
+		"user":       "user_id",
+		"db_queries": "db.queries",
+		"dc":         "region",
+	}
+	if got := r.Aliases(); !maps.Equal(got, wantAliases) {
+		t.Errorf("Aliases = %v, want %v", got, wantAliases)
+	}
+
+	wantUsage := map[string]int64{"user_id": 1, "db.queries": 1, "region": 0}
+	if got := r.Usage().Keys; !maps.Equal(got, wantUsage) {
+		t.Errorf("Usage().Keys = %v, want %v", got, wantUsage)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterWith did not panic on a key used as an alias")
+		}
+	}()
+	RegisterWith[string](r, "uid")
+}
@@ -0,0 +1,29 @@
+package canonlog
+
+import (
+	"context"
+	"slices"
+)
+
+// Append adds values to the end of the slice stored for attr in the [Line]
+// attached to ctx, so that lists (retried hosts, validation failures) can be
+// accumulated without writing a merge function and wrapping every value in a
+// slice. If the context does not have a Line, Append silently does nothing.
+//
+// If attr has a merge function, such as a set-valued attribute created by
+// [RegisterSet], Append is equivalent to calling [Set] with the values as a
+// slice, and the merge function decides how they are combined.
+func Append[T any](ctx context.Context, attr Attr[[]T], values ...T) {
+	values = slices.Clone(values)
+	if attr.merge != nil {
+		Set(ctx, attr, values)
+		return
+	}
+	setWith(ctx, attr, values, mergeAppend[T])
+}
+
+// mergeAppend returns the concatenation of old and new, without modifying
+// the array backing old.
+func mergeAppend[T any](old, new []T) []T {
+	return append(slices.Clip(old), new...)
+}
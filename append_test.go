@@ -0,0 +1,58 @@
+package canonlog
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	r := testRegistry(t)
+	attrHosts := RegisterWith[[]string](r, "retried_hosts")
+
+	ctx := New(context.Background())
+	Append(ctx, attrHosts, "a.example.com")
+	Append(ctx, attrHosts, "b.example.com", "a.example.com")
+
+	got := Attrs(ctx)[0].Value.Any().([]string)
+	want := []string{"a.example.com", "b.example.com", "a.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("retried_hosts = %v, want %v", got, want)
+	}
+}
+
+func TestAppend_DoesNotAliasSetSlice(t *testing.T) {
+	r := testRegistry(t)
+	attrFailures := RegisterWith[[]string](r, "validation_failures")
+
+	// Leave spare capacity so that a naive append would write into in.
+	in := make([]string, 1, 4)
+	in[0] = "name"
+
+	ctx := New(context.Background())
+	Set(ctx, attrFailures, in)
+	Append(ctx, attrFailures, "email")
+
+	if got := in[:2][1]; got != "" {
+		t.Errorf("Append wrote %q into the caller's backing array", got)
+	}
+	got := Attrs(ctx)[0].Value.Any().([]string)
+	if want := []string{"name", "email"}; !slices.Equal(got, want) {
+		t.Errorf("validation_failures = %v, want %v", got, want)
+	}
+}
+
+func TestAppend_SetAttr(t *testing.T) {
+	r := testRegistry(t)
+	attrTables := RegisterSetWith[string](r, "tables_touched")
+
+	ctx := New(context.Background())
+	Append(ctx, attrTables, "users")
+	Append(ctx, attrTables, "orgs")
+	Append(ctx, attrTables, "users")
+
+	got := Attrs(ctx)[0].Value.Any().([]string)
+	if want := []string{"orgs", "users"}; !slices.Equal(got, want) {
+		t.Errorf("tables_touched = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Async logs the lines of the Emitters configured [WithAsync] from a
+// background goroutine, so that requests do not wait for slow log handlers,
+// such as ones writing to the network. Lines are logged in the order they
+// were emitted.
+//
+// Lines queued but not yet logged are lost if the process exits, so servers
+// should drain the queue with [Async.Close] during shutdown, within its
+// deadline:
+//
+//	async := canonlog.NewAsync(1024)
+//	handler := canonlog.Middleware(logger, canonlog.WithEmitterOptions(canonlog.WithAsync(async)))(mux)
+//	...
+//	srv.Shutdown(ctx)
+//	async.Close(ctx)
+//
+// An Async is safe for concurrent use.
+type Async struct {
+	lines   chan queuedLine
+	closing chan struct{} // closed by Close, to stop queueing lines
+	done    chan struct{} // closed once every queued line has been logged
+
+	// mu is held for reading while registering a line about to be queued
+	// in senders, and for writing to close closing, so that run can wait
+	// for the lines being queued once closing is closed. It is never held
+	// while waiting for room in the queue.
+	mu      sync.RWMutex
+	closed  bool
+	senders sync.WaitGroup
+}
+
+// queuedLine is a line queued in an [Async], or, if flushed is not nil, a
+// marker closed once the lines queued before it have been logged.
+type queuedLine struct {
+	ctx     context.Context
+	logger  *slog.Logger
+	level   slog.Level
+	attrs   []slog.Attr
+	flushed chan struct{}
+}
+
+// NewAsync creates an [Async] queueing up to n lines, and starts its
+// goroutine. Emitting a line blocks while the queue is full, so that lines
+// are not dropped when the log handler falls behind. It panics if n is
+// negative.
+func NewAsync(n int) *Async {
+	if n < 0 {
+		panic("canonlog: negative async queue size")
+	}
+	a := &Async{
+		lines:   make(chan queuedLine, n),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// WithAsync makes the [Emitter] log its lines through a, rather than from
+// the goroutine emitting them. Lines are sampled, and their attributes read,
+// when they are emitted; the log handler is given the emitting context
+// without its cancellation or its [Line], which may already have been reused
+// (see [NewPooled]). Once a is closed, lines are logged synchronously.
+func WithAsync(a *Async) EmitterOption {
+	return func(e *Emitter) {
+		e.async = a
+	}
+}
+
+// run logs the lines queued in a until it is closed, and then those still
+// queued.
+func (a *Async) run() {
+	defer close(a.done)
+	for {
+		select {
+		case l := <-a.lines:
+			l.log()
+		case <-a.closing:
+			a.senders.Wait()
+			for {
+				select {
+				case l := <-a.lines:
+					l.log()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// log logs l, or marks the lines queued before it as logged.
+func (l queuedLine) log() {
+	if l.flushed != nil {
+		close(l.flushed)
+		return
+	}
+	l.logger.LogAttrs(l.ctx, l.level, Message, l.attrs...)
+	linesEmitted.Add(1)
+}
+
+// enqueue queues l, waiting for room in the queue until ctx is done. It
+// reports false if a is closed, including while waiting.
+func (a *Async) enqueue(ctx context.Context, l queuedLine) (bool, error) {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return false, nil
+	}
+	a.senders.Add(1)
+	a.mu.RUnlock()
+	defer a.senders.Done()
+
+	select {
+	case a.lines <- l:
+		return true, nil
+	case <-a.closing:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// Flush waits for the lines queued in a to be logged, or for ctx to be
+// done, in which case it returns the context's error.
+func (a *Async) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	queued, err := a.enqueue(ctx, queuedLine{flushed: flushed})
+	if err != nil {
+		return err
+	}
+	if !queued {
+		flushed = a.done
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops a from accepting new lines, and waits for those already
+// queued to be logged, or for ctx to be done, in which case it returns the
+// context's error; the remaining lines are still logged in the background.
+// Lines emitted afterwards by Emitters configured [WithAsync] with a, or
+// waiting for room in its queue when it is closed, are logged
+// synchronously. Close may be called more than once.
+func (a *Async) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.closing)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
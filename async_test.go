@@ -0,0 +1,176 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedHandler is a [slog.Handler] that waits for gate to be closed before
+// passing records on to next.
+type gatedHandler struct {
+	slog.Handler
+	gate chan struct{}
+}
+
+func (h gatedHandler) Handle(ctx context.Context, r slog.Record) error {
+	<-h.gate
+	return h.Handler.Handle(ctx, r)
+}
+
+// lockedBuffer is a [bytes.Buffer] safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAsync(t *testing.T) {
+	r := testRegistry(t)
+	attrN := RegisterWith[int](r, "n")
+
+	var buf lockedBuffer
+	gate := make(chan struct{})
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	async := NewAsync(4)
+	e := NewEmitter(slog.New(gatedHandler{h, gate}), WithAsync(async))
+
+	for i := range 3 {
+		ctx, cancel := context.WithCancel(New(context.Background(), WithRegistry(r)))
+		Set(ctx, attrN, i)
+		e.Emit(ctx, slog.LevelInfo)
+		cancel() // lines are logged after the request finishes
+	}
+	if got := buf.String(); got != "" {
+		t.Errorf("lines logged before the handler was ready: %q", got)
+	}
+
+	// Flush gives up when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := async.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush with a blocked handler = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(gate)
+	if err := async.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush = %v", err)
+	}
+	want := "level=INFO msg=canonical-log-line n=0\n" +
+		"level=INFO msg=canonical-log-line n=1\n" +
+		"level=INFO msg=canonical-log-line n=2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output after Flush:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// Once closed, lines are logged synchronously.
+	if err := async.Close(context.Background()); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if err := async.Close(context.Background()); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	ctx = New(context.Background(), WithRegistry(r))
+	Set(ctx, attrN, 3)
+	e.Emit(ctx, slog.LevelInfo)
+	want += "level=INFO msg=canonical-log-line n=3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output after Close:\ngot:  %q\nwant: %q", got, want)
+	}
+	if err := async.Flush(context.Background()); err != nil {
+		t.Errorf("Flush after Close = %v", err)
+	}
+}
+
+func TestAsync_Close(t *testing.T) {
+	var buf lockedBuffer
+	async := NewAsync(16)
+	e := NewEmitter(slog.New(slog.NewTextHandler(&buf, nil)), WithAsync(async))
+	for range 10 {
+		e.Emit(New(context.Background()), slog.LevelInfo)
+	}
+
+	// Close drains the queue.
+	if err := async.Close(context.Background()); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if got := bytes.Count([]byte(buf.String()), []byte("\n")); got != 10 {
+		t.Errorf("logged %d lines, want 10", got)
+	}
+}
+
+func TestAsync_CloseBlocked(t *testing.T) {
+	gate := make(chan struct{})
+	defer close(gate)
+	async := NewAsync(1)
+	e := NewEmitter(slog.New(gatedHandler{slog.NewTextHandler(io.Discard, nil), gate}), WithAsync(async))
+
+	// The first line blocks the handler, the second fills the queue, and
+	// the third waits for room in it.
+	for range 2 {
+		e.Emit(New(context.Background()), slog.LevelInfo)
+	}
+	go e.Emit(New(context.Background()), slog.LevelInfo)
+	time.Sleep(10 * time.Millisecond) // let it block
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- async.Close(ctx) }()
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Close with a blocked handler = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return by its deadline")
+	}
+}
+
+// lineHandler is a [slog.Handler] that records the [Line] attached to the
+// context of each record.
+type lineHandler struct {
+	slog.Handler
+	lines []*Line
+}
+
+func (h *lineHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.lines = append(h.lines, FromContext(ctx))
+	return nil
+}
+
+func TestAsync_Pooled(t *testing.T) {
+	h := &lineHandler{Handler: slog.NewTextHandler(io.Discard, nil)}
+	async := NewAsync(1)
+	e := NewEmitter(slog.New(h), WithAsync(async))
+	e.Emit(NewPooled(context.Background()), slog.LevelInfo)
+	if err := async.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.lines) != 1 || h.lines[0] != nil {
+		t.Errorf("handler saw lines %v, want only a context without a line, which may have been reused", h.lines)
+	}
+}
@@ -0,0 +1,72 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+)
+
+// Average is the weighted average of the values recorded by an average
+// attribute.
+type Average[T Number] struct {
+	Sum    float64 // sum of the values, each multiplied by its weight
+	Weight float64 // sum of the weights
+}
+
+// RegisterAverageWith creates a new average attribute with the given key in
+// the specified registry. It panics if an attribute with the same key has
+// already been registered in that registry.
+//
+// Each call to [AddWeighted] records a value with a weight, and the
+// attribute is emitted as the weighted average of all values recorded, for
+// measurements that neither a sum nor the last value represent, such as
+// the average latency per item of batches of different sizes:
+//
+//	var AttrItemLatency = canonlog.RegisterAverage[time.Duration]("item_latency")
+//
+//	canonlog.AddWeighted(ctx, AttrItemLatency, elapsed/time.Duration(len(batch)), float64(len(batch)))
+//
+// The average of integer types, such as [time.Duration], is rounded to the
+// nearest integer. An attribute whose weights sum to zero is emitted as
+// zero.
+func RegisterAverageWith[T Number](r *Registry, key string, opts ...Option[Average[T]]) Attr[Average[T]] {
+	opts = append([]Option[Average[T]]{
+		WithMerge(mergeAverages[T]),
+		WithCommutativeMerge[Average[T]](),
+		WithValue(func(a Average[T]) slog.Value {
+			return slog.AnyValue(a.Value())
+		}),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterAverage creates a new average attribute with the given key using
+// [DefaultRegistry]. See [RegisterAverageWith] for details.
+func RegisterAverage[T Number](key string, opts ...Option[Average[T]]) Attr[Average[T]] {
+	return RegisterAverageWith(DefaultRegistry, key, opts...)
+}
+
+// AddWeighted records v with the given weight for the average attribute
+// attr in the [Line] attached to ctx. If the context does not have a Line,
+// AddWeighted silently does nothing.
+func AddWeighted[T Number](ctx context.Context, attr Attr[Average[T]], v T, weight float64) {
+	Set(ctx, attr, Average[T]{Sum: float64(v) * weight, Weight: weight})
+}
+
+// Value returns the weighted average held by a, or zero if its weights sum
+// to zero.
+func (a Average[T]) Value() T {
+	if a.Weight == 0 {
+		return 0
+	}
+	avg := a.Sum / a.Weight
+	half := 0.5
+	if T(half) == 0 { // an integer type
+		avg = math.Round(avg)
+	}
+	return T(avg)
+}
+
+func mergeAverages[T Number](old, new Average[T]) Average[T] {
+	return Average[T]{Sum: old.Sum + new.Sum, Weight: old.Weight + new.Weight}
+}
@@ -0,0 +1,39 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRegisterAverage(t *testing.T) {
+	r := testRegistry(t)
+	attrLatency := RegisterAverageWith[time.Duration](r, "item_latency")
+	attrRatio := RegisterAverageWith[float64](r, "hit_ratio")
+	attrNone := RegisterAverageWith[int](r, "none")
+
+	ctx := New(context.Background())
+	AddWeighted(ctx, attrLatency, 10*time.Millisecond, 3) // a batch of three
+	AddWeighted(ctx, attrLatency, 2*time.Millisecond, 1)
+	AddWeighted(ctx, attrRatio, 1.0, 1)
+	AddWeighted(ctx, attrRatio, 0.25, 3)
+	AddWeighted(ctx, attrNone, 5, 0)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line item_latency=8ms hit_ratio=0.4375 none=0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestAverage_Value(t *testing.T) {
+	if got := (Average[int]{Sum: 5, Weight: 2}).Value(); got != 3 {
+		t.Errorf("Value of 2.5 as an int = %d, want 3", got)
+	}
+	if got := (Average[float32]{Sum: 5, Weight: 2}).Value(); got != 2.5 {
+		t.Errorf("Value of 2.5 as a float32 = %v, want 2.5", got)
+	}
+}
@@ -0,0 +1,58 @@
+package canonlog
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// buildRegistry holds the attributes recorded by [SetBuildInfoWith], kept out
+// of [DefaultRegistry] for the same reason as those of [Middleware].
+var buildRegistry = NewRegistry()
+
+var (
+	attrGoVersion   = RegisterWith[string](buildRegistry, "go_version")
+	attrVCSRevision = RegisterWith[string](buildRegistry, "vcs_revision")
+	attrVCSTime     = RegisterWith[string](buildRegistry, "vcs_time")
+	attrDirty       = RegisterWith[bool](buildRegistry, "dirty")
+)
+
+// readBuildInfo reads the build information of the running binary once.
+var readBuildInfo = sync.OnceValues(debug.ReadBuildInfo)
+
+// SetBuildInfoWith includes information about how the running binary was
+// built in every line associated with r, as static attributes (see
+// [SetGlobalWith]), so that regressions can be correlated with deploys from
+// canonical lines alone. The attributes are go_version, and if the binary
+// was built from a version control checkout, vcs_revision, vcs_time, and
+// dirty, which is true if the checkout had uncommitted changes.
+//
+// The build information is read with [debug.ReadBuildInfo] the first time
+// it is needed. If it is not available, SetBuildInfoWith does nothing.
+func SetBuildInfoWith(r *Registry) {
+	if info, ok := readBuildInfo(); ok {
+		setBuildInfo(r, info)
+	}
+}
+
+// SetBuildInfo includes build information in every line associated with
+// [DefaultRegistry]. See [SetBuildInfoWith] for details.
+func SetBuildInfo() {
+	SetBuildInfoWith(DefaultRegistry)
+}
+
+// setBuildInfo is the implementation of [SetBuildInfoWith].
+func setBuildInfo(r *Registry, info *debug.BuildInfo) {
+	if info.GoVersion != "" {
+		SetGlobalWith(r, attrGoVersion, info.GoVersion)
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			SetGlobalWith(r, attrVCSRevision, s.Value)
+		case "vcs.time":
+			SetGlobalWith(r, attrVCSTime, s.Value)
+		case "vcs.modified":
+			SetGlobalWith(r, attrDirty, s.Value == "true")
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"testing"
+)
+
+func TestSetBuildInfo(t *testing.T) {
+	r := testRegistry(t)
+	setBuildInfo(r, &debug.BuildInfo{
+		GoVersion: "go1.25.3",
+		Settings: []debug.BuildSetting{
+			{Key: "-compiler", Value: "gc"},
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "3fde539"},
+			{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	})
+
+	var buf bytes.Buffer
+	Emit(New(context.Background(), WithRegistry(r)), testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line go_version=go1.25.3 vcs_revision=3fde539 vcs_time=2026-10-01T12:00:00Z dirty=true\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestSetBuildInfoWith(t *testing.T) {
+	r := testRegistry(t)
+	SetBuildInfoWith(r)
+
+	// Test binaries always have build information, with at least the Go
+	// version.
+	attrs := Attrs(New(context.Background(), WithRegistry(r)))
+	if len(attrs) == 0 || attrs[0].Key != "go_version" {
+		t.Errorf("attrs = %v, want go_version first", attrs)
+	}
+}
@@ -0,0 +1,56 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// cacheRegistry holds the attribute recorded by [CacheHit] and [CacheMiss],
+// kept out of [DefaultRegistry] for the same reason as those of
+// [Middleware].
+var cacheRegistry = NewRegistry()
+
+// attrCache records cache lookups by cache name.
+var attrCache = RegisterWith(cacheRegistry, "cache",
+	WithMerge(MergeMap(func(old, new cacheCounts) cacheCounts {
+		return cacheCounts{hits: old.hits + new.hits, misses: old.misses + new.misses}
+	})),
+	WithCommutativeMerge[map[string]cacheCounts](),
+	WithValue(cacheValue),
+)
+
+// cacheCounts counts the lookups in a named cache.
+type cacheCounts struct {
+	hits, misses int
+}
+
+// cacheValue converts cache counts to a group with a member per cache,
+// sorted by name.
+func cacheValue(m map[string]cacheCounts) slog.Value {
+	caches := make([]slog.Attr, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		c := m[name]
+		caches = append(caches, slog.Group(name,
+			slog.Int("hits", c.hits),
+			slog.Int("misses", c.misses),
+		))
+	}
+	return slog.GroupValue(caches...)
+}
+
+// CacheHit records a hit in the cache with the given name in the [Line]
+// attached to ctx. Hits and misses are emitted in a "cache" group, with a
+// member per cache name, as in cache.sessions.hits=3 cache.sessions.misses=1,
+// so that cache effectiveness is recorded the same way by every service. If
+// the context does not have a Line, CacheHit silently does nothing.
+func CacheHit(ctx context.Context, name string) {
+	SetKey(ctx, attrCache, name, cacheCounts{hits: 1})
+}
+
+// CacheMiss records a miss in the cache with the given name in the [Line]
+// attached to ctx. See [CacheHit] for details.
+func CacheMiss(ctx context.Context, name string) {
+	SetKey(ctx, attrCache, name, cacheCounts{misses: 1})
+}
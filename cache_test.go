@@ -0,0 +1,25 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestCacheHitMiss(t *testing.T) {
+	ctx := New(context.Background())
+	CacheHit(ctx, "sessions")
+	CacheMiss(ctx, "users")
+	CacheHit(ctx, "sessions")
+	CacheMiss(ctx, "sessions")
+	CacheHit(ctx, "sessions")
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line cache.sessions.hits=3 cache.sessions.misses=1 cache.users.hits=0 cache.users.misses=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
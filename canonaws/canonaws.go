@@ -0,0 +1,126 @@
+// Package canonaws records the AWS operations made with aws-sdk-go-v2 in
+// the current canonical log line, so that the cost of each request's cloud
+// dependencies shows up in its line.
+//
+// Add the middleware to the API options of the SDK's configuration, or of a
+// single client:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx)
+//	...
+//	cfg.APIOptions = append(cfg.APIOptions, canonaws.AddMiddleware)
+//
+// Operations are recorded in the [canonlog.Line] attached to the context
+// they are called with.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// the AWS SDK do not depend on it.
+package canonaws
+
+import (
+	"context"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package.
+var (
+	// AttrCalls counts the operations called, by service and operation,
+	// as in aws_calls.DynamoDB.GetItem=2.
+	AttrCalls = canonlog.RegisterMapWith[int64](registry, "aws_calls")
+
+	// AttrTime is the total time spent in operations, including retries.
+	AttrTime = canonlog.RegisterCounterWith[time.Duration](registry, "aws_time")
+
+	// AttrRetries counts the attempts made after the first, and
+	// AttrThrottles the attempts that failed because they were
+	// throttled.
+	AttrRetries   = canonlog.RegisterCounterWith[int64](registry, "aws_retries")
+	AttrThrottles = canonlog.RegisterCounterWith[int64](registry, "aws_throttles")
+
+	// AttrBytesSent and AttrBytesReceived are the sizes of the bodies of
+	// the HTTP requests sent and responses received, over all attempts.
+	// Bodies of unknown size, such as some streamed uploads, are not
+	// counted.
+	AttrBytesSent     = canonlog.RegisterCounterWith[int64](registry, "aws_bytes_sent")
+	AttrBytesReceived = canonlog.RegisterCounterWith[int64](registry, "aws_bytes_received")
+)
+
+// throttles reports whether an error was caused by throttling.
+var throttles = retry.IsErrorThrottles(retry.DefaultThrottles)
+
+// AddMiddleware adds the middleware recording operations to stack. It has
+// the signature of the functions in [aws.Config.APIOptions].
+func AddMiddleware(stack *middleware.Stack) error {
+	if err := stack.Initialize.Add(operation, middleware.After); err != nil {
+		return err
+	}
+	// Runs once per attempt, unlike operation.
+	if err := stack.Finalize.Insert(attempt, (&retry.Attempt{}).ID(), middleware.After); err != nil {
+		if err := stack.Finalize.Add(attempt, middleware.After); err != nil {
+			return err
+		}
+	}
+	return stack.Deserialize.Add(transfer, middleware.After)
+}
+
+// attemptsKey is the context key under which the number of attempts made
+// for an operation is stored.
+type attemptsKey struct{}
+
+var operation = middleware.InitializeMiddlewareFunc("canonaws.Operation", func(
+	ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+) (middleware.InitializeOutput, middleware.Metadata, error) {
+	if canonlog.FromContext(ctx) == nil {
+		return next.HandleInitialize(ctx, in)
+	}
+	start := time.Now()
+	ctx = context.WithValue(ctx, attemptsKey{}, new(int))
+	name := awsmiddleware.GetServiceID(ctx) + "." + awsmiddleware.GetOperationName(ctx)
+	canonlog.SetKey(ctx, AttrCalls, name, 1)
+
+	out, md, err := next.HandleInitialize(ctx, in)
+	canonlog.Add(ctx, AttrTime, time.Since(start))
+	return out, md, err
+})
+
+var attempt = middleware.FinalizeMiddlewareFunc("canonaws.Attempt", func(
+	ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	attempts, ok := ctx.Value(attemptsKey{}).(*int)
+	if !ok {
+		return next.HandleFinalize(ctx, in)
+	}
+	if *attempts++; *attempts > 1 {
+		canonlog.Add(ctx, AttrRetries, 1)
+	}
+
+	out, md, err := next.HandleFinalize(ctx, in)
+	if err != nil && throttles.IsErrorThrottle(err) == aws.TrueTernary {
+		canonlog.Add(ctx, AttrThrottles, 1)
+	}
+	return out, md, err
+})
+
+var transfer = middleware.DeserializeMiddlewareFunc("canonaws.Transfer", func(
+	ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler,
+) (middleware.DeserializeOutput, middleware.Metadata, error) {
+	if req, ok := in.Request.(*smithyhttp.Request); ok && req.ContentLength > 0 {
+		canonlog.Add(ctx, AttrBytesSent, req.ContentLength)
+	}
+	out, md, err := next.HandleDeserialize(ctx, in)
+	if resp, ok := out.RawResponse.(*smithyhttp.Response); ok && resp.ContentLength > 0 {
+		canonlog.Add(ctx, AttrBytesReceived, resp.ContentLength)
+	}
+	return out, md, err
+})
@@ -0,0 +1,105 @@
+package canonaws
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const throttleBody = `{"__type":"com.amazonaws.dynamodb.v20120810#ThrottlingException","message":"slow down"}`
+
+func TestMiddleware(t *testing.T) {
+	// The server throttles every other request.
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		requests++
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		if requests%2 == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, throttleBody)
+			return
+		}
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  aws.AnonymousCredentials{},
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+		APIOptions: []func(*middleware.Stack) error{AddMiddleware},
+	})
+
+	ctx := canonlog.New(context.Background())
+	key := map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "1"}}
+	for range 2 {
+		if _, err := client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("users"), Key: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String("users"), Key: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := canonlog.Attrs(ctx)
+	keys := make([]string, len(attrs))
+	values := make(map[string]slog.Value)
+	for i, a := range attrs {
+		keys[i] = a.Key
+		values[a.Key] = a.Value
+	}
+	wantKeys := []string{"aws_calls", "aws_bytes_sent", "aws_bytes_received", "aws_throttles", "aws_retries", "aws_time"}
+	if !slices.Equal(keys, wantKeys) {
+		t.Fatalf("attribute keys = %q, want %q", keys, wantKeys)
+	}
+
+	calls := slog.GroupValue(slog.Int64("DynamoDB.DeleteItem", 1), slog.Int64("DynamoDB.GetItem", 2))
+	if got := values["aws_calls"]; !got.Equal(calls) {
+		t.Errorf("aws_calls = %v, want %v", got, calls)
+	}
+	for key, want := range map[string]int64{"aws_throttles": 3, "aws_retries": 3, "aws_bytes_received": int64(3*len(throttleBody) + 3*len("{}"))} {
+		if got := values[key].Int64(); got != want {
+			t.Errorf("%s = %d, want %d", key, got, want)
+		}
+	}
+	if got := values["aws_bytes_sent"].Int64(); got <= 0 {
+		t.Errorf("aws_bytes_sent = %d, want > 0", got)
+	}
+	if got := values["aws_time"].Duration(); got <= 0 {
+		t.Errorf("aws_time = %v, want > 0", got)
+	}
+}
+
+func TestMiddleware_NoLine(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  aws.AnonymousCredentials{},
+		APIOptions:   []func(*middleware.Stack) error{AddMiddleware},
+	})
+	_, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
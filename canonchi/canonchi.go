@@ -0,0 +1,40 @@
+// Package canonchi records the route patterns of requests routed by chi in
+// the canonical log lines of [canonlog.Middleware].
+//
+// Install the middleware on the chi router, so that the pattern of the
+// matched route, such as "/users/{id}", is recorded under the "http_route"
+// key:
+//
+//	r := chi.NewRouter()
+//	r.Use(canonchi.Middleware(logger))
+//
+// It is a separate module from canonlog, so that programs that do not use
+// chi do not depend on it.
+package canonchi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware returns [canonlog.Middleware] configured with opts and with
+// [RoutePattern] given to [canonlog.WithRoutePattern]. It must be installed
+// with the Use method of a chi router, or of one of its sub-routers, for the
+// route pattern to be found.
+func Middleware(logger *slog.Logger, opts ...canonlog.MiddlewareOption) func(http.Handler) http.Handler {
+	opts = append(opts[:len(opts):len(opts)], canonlog.WithRoutePattern(RoutePattern))
+	return canonlog.Middleware(logger, opts...)
+}
+
+// RoutePattern returns the pattern of the chi route that served r, such as
+// "/users/{id}", or "" if r was not routed by chi.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
@@ -0,0 +1,52 @@
+package canonchi
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	r := chi.NewRouter()
+	r.Use(Middleware(logger))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/orgs/{org}", func(r chi.Router) {
+		r.Get("/members/{member}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	tests := []struct {
+		path string
+		want string // log output, up to the status
+	}{
+		{"/users/123", "level=INFO msg=canonical-log-line http_method=GET http_path=/users/123 http_route=/users/{id} http_status=200"},
+		{"/orgs/acme/members/ann", "level=INFO msg=canonical-log-line http_method=GET http_path=/orgs/acme/members/ann http_route=/orgs/{org}/members/{member} http_status=200"},
+		{"/missing", "level=INFO msg=canonical-log-line http_method=GET http_path=/missing http_status=404"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("GET %s: log output:\ngot:  %q\nwant: %q", tt.path, got, tt.want)
+		}
+	}
+
+	if got := RoutePattern(httptest.NewRequest("GET", "/", nil)); got != "" {
+		t.Errorf("RoutePattern of unrouted request = %q, want empty", got)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/andrew-d/canonlog/canonchi
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	github.com/go-chi/chi/v5 v5.3.1
+)
+
+replace github.com/andrew-d/canonlog => ../
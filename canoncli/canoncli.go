@@ -0,0 +1,138 @@
+// Package canoncli emits a canonical log line for each invocation of a
+// command-line tool, recording the command, selected flags, exit code and
+// duration, so that a fleet of internal tools can be observed like a
+// service:
+//
+//	func main() {
+//		verbose := flag.Bool("v", false, "verbose output")
+//		flag.Parse()
+//		os.Exit(canoncli.Run(context.Background(), "deployctl", func(ctx context.Context) int {
+//			canoncli.SetFlags(ctx, flag.CommandLine, "v", "env")
+//			...
+//			return 0
+//		}))
+//	}
+//
+// Commands built with cobra can use the canoncobra package, a separate
+// module, instead.
+package canoncli
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package.
+var (
+	// AttrCommand is the command that was run, such as "deployctl
+	// rollback". It is set by [Run], and may be set again once the
+	// command is known more precisely.
+	AttrCommand = canonlog.RegisterWith[string](registry, "command")
+
+	// AttrFlags records the values of the flags given on the command
+	// line, as a group keyed by flag name. Only flags set with
+	// [SetFlag] or [SetFlags] are recorded.
+	AttrFlags = canonlog.RegisterWith(registry, "flags",
+		canonlog.WithMerge(canonlog.MergeMap(func(old, new string) string { return new })),
+		canonlog.WithValue(flagsValue),
+	)
+
+	AttrExitCode = canonlog.RegisterWith[int](registry, "exit_code")
+	AttrDuration = canonlog.RegisterWith[time.Duration](registry, "duration")
+)
+
+// flagsValue converts flags to a group with members sorted by flag name.
+func flagsValue(flags map[string]string) slog.Value {
+	attrs := make([]slog.Attr, 0, len(flags))
+	for _, name := range slices.Sorted(maps.Keys(flags)) {
+		attrs = append(attrs, slog.String(name, flags[name]))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Option configures [Run].
+type Option func(*config)
+
+// config holds the configuration of [Run].
+type config struct {
+	emitter *canonlog.Emitter
+}
+
+// WithEmitter makes [Run] emit its line with e, instead of an
+// [canonlog.Emitter] logging to [slog.Default].
+func WithEmitter(e *canonlog.Emitter) Option {
+	return func(cfg *config) {
+		cfg.emitter = e
+	}
+}
+
+// Run runs fn as the command with the given name, with a context carrying
+// a new [canonlog.Line], and returns the exit code returned by fn, typically
+// to be passed to [os.Exit]. Once fn returns, the line is emitted with the
+// command, exit code and duration, at [slog.LevelInfo] if the exit code is
+// zero and at [slog.LevelError] otherwise. If fn panics, the line is
+// emitted with exit code 2, the exit code of a Go program that panics, and
+// the panic is then resumed.
+func Run(ctx context.Context, command string, fn func(ctx context.Context) int, opts ...Option) (code int) {
+	cfg := config{emitter: canonlog.NewEmitter(nil)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	start := time.Now()
+	ctx = canonlog.New(ctx)
+	canonlog.Set(ctx, AttrCommand, command)
+
+	defer func() {
+		p := recover()
+		if p != nil {
+			code = 2
+		}
+		canonlog.Set(ctx, AttrExitCode, code)
+		canonlog.Set(ctx, AttrDuration, time.Since(start))
+
+		level := slog.LevelInfo
+		if code != 0 {
+			level = slog.LevelError
+		}
+		cfg.emitter.Emit(ctx, level)
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// SetFlag records the value of the flag with the given name in the line
+// attached to ctx. If the context does not have a [canonlog.Line], SetFlag
+// silently does nothing.
+func SetFlag(ctx context.Context, name, value string) {
+	canonlog.SetKey(ctx, AttrFlags, name, value)
+}
+
+// SetFlags records the values of the flags in fs with the given names that
+// were set on the command line. Only the listed flags are recorded, so that
+// flags carrying secrets, such as tokens, are not.
+func SetFlags(ctx context.Context, fs *flag.FlagSet, names ...string) {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if allowed[f.Name] {
+			SetFlag(ctx, f.Name, f.Value.String())
+		}
+	})
+}
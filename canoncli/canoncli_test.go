@@ -0,0 +1,70 @@
+package canoncli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestRun(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fs := flag.NewFlagSet("deployctl", flag.ContinueOnError)
+		fs.String("env", "staging", "")
+		fs.Bool("v", false, "")
+		fs.String("token", "", "")
+		fs.Int("replicas", 1, "")
+		if err := fs.Parse([]string{"-env=prod", "-v", "-token=s3cr3t"}); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		code := Run(context.Background(), "deployctl", func(ctx context.Context) int {
+			SetFlags(ctx, fs, "env", "v", "replicas")
+			canonlog.Set(ctx, AttrCommand, "deployctl rollback")
+			time.Sleep(3 * time.Second)
+			return 3
+		}, WithEmitter(canonlog.NewEmitter(testLogger(&buf))))
+		if code != 3 {
+			t.Errorf("Run returned %d, want 3", code)
+		}
+
+		want := "level=ERROR msg=canonical-log-line command=\"deployctl rollback\" flags.env=prod flags.v=true exit_code=3 duration=3s\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestRun_Panic(t *testing.T) {
+	var buf bytes.Buffer
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the command's panic", p)
+		}
+		if got := buf.String(); !bytes.Contains(buf.Bytes(), []byte(" exit_code=2 ")) {
+			t.Errorf("log output = %q, want exit_code=2", got)
+		}
+	}()
+	Run(context.Background(), "deployctl", func(ctx context.Context) int {
+		panic("boom")
+	}, WithEmitter(canonlog.NewEmitter(testLogger(&buf))))
+}
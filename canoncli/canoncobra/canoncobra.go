@@ -0,0 +1,44 @@
+// Package canoncobra emits a canonical log line for each invocation of a
+// command-line tool built with cobra; see the canoncli package for details.
+//
+//	func main() {
+//		os.Exit(canoncobra.Execute(context.Background(), rootCmd, []string{"env", "dry-run"}))
+//	}
+//
+// It is a separate module from canonlog, so that programs that do not use
+// cobra do not depend on it.
+package canoncobra
+
+import (
+	"context"
+	"slices"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canoncli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Execute executes root with [cobra.Command.ExecuteContextC] as a
+// [canoncli.Run] command, and returns the exit code: 0 if the command
+// succeeded, and 1 if it returned an error. The line records the full path
+// of the command that ran, such as "deployctl rollback", and the values of
+// the flags listed in flags that were set on the command line. Only the
+// listed flags are recorded, so that flags carrying secrets are not.
+func Execute(ctx context.Context, root *cobra.Command, flags []string, opts ...canoncli.Option) int {
+	return canoncli.Run(ctx, root.Name(), func(ctx context.Context) int {
+		cmd, err := root.ExecuteContextC(ctx)
+		if cmd != nil {
+			canonlog.Set(ctx, canoncli.AttrCommand, cmd.CommandPath())
+			cmd.Flags().Visit(func(f *pflag.Flag) {
+				if slices.Contains(flags, f.Name) {
+					canoncli.SetFlag(ctx, f.Name, f.Value.String())
+				}
+			})
+		}
+		if err != nil {
+			return 1
+		}
+		return 0
+	}, opts...)
+}
@@ -0,0 +1,55 @@
+package canoncobra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canoncli"
+	"github.com/spf13/cobra"
+)
+
+func TestExecute(t *testing.T) {
+	root := &cobra.Command{Use: "deployctl"}
+	root.PersistentFlags().String("env", "staging", "")
+	root.PersistentFlags().String("token", "", "")
+	rollback := &cobra.Command{
+		Use: "rollback",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if canonlog.FromContext(cmd.Context()) == nil {
+				t.Error("command context does not have a Line")
+			}
+			return errors.New("no previous release")
+		},
+	}
+	rollback.Flags().Bool("dry-run", false, "")
+	root.AddCommand(rollback)
+	root.SetArgs([]string{"rollback", "--env=prod", "--token=s3cr3t", "--dry-run"})
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == "duration") {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	code := Execute(context.Background(), root, []string{"env", "dry-run"},
+		canoncli.WithEmitter(canonlog.NewEmitter(logger)))
+	if code != 1 {
+		t.Errorf("Execute returned %d, want 1", code)
+	}
+
+	want := "level=ERROR msg=canonical-log-line command=\"deployctl rollback\" flags.dry-run=true flags.env=prod exit_code=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/andrew-d/canonlog/canoncli/canoncobra
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	github.com/spf13/cobra v1.9.1
+	github.com/spf13/pflag v1.0.6
+)
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
+
+replace github.com/andrew-d/canonlog => ../../
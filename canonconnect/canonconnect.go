@@ -0,0 +1,158 @@
+// Package canonconnect emits canonical log lines for connect-go services.
+//
+// Install the interceptor on each handler so that every RPC gets its own
+// [canonlog.Line], emitted when the RPC completes:
+//
+//	path, handler := greetv1connect.NewGreetServiceHandler(svc,
+//		connect.WithInterceptors(canonconnect.NewInterceptor(logger)),
+//	)
+//
+// It is a separate module from canonlog, so that programs that do not use
+// connect-go do not depend on it.
+package canonconnect
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/andrew-d/canonlog"
+	"google.golang.org/protobuf/proto"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package. Sizes are those of the messages as
+// protocol buffers, and are not recorded for other messages.
+var (
+	AttrProcedure = canonlog.RegisterWith[string](registry, "rpc_procedure")
+	AttrProtocol  = canonlog.RegisterWith[string](registry, "rpc_protocol")
+	AttrCode      = canonlog.RegisterWith[string](registry, "rpc_code")
+	AttrDuration  = canonlog.RegisterWith[time.Duration](registry, "duration")
+
+	AttrMsgsSent      = canonlog.RegisterCounterWith[int64](registry, "rpc_msgs_sent")
+	AttrMsgsReceived  = canonlog.RegisterCounterWith[int64](registry, "rpc_msgs_received")
+	AttrBytesSent     = canonlog.RegisterCounterWith[int64](registry, "rpc_bytes_sent")
+	AttrBytesReceived = canonlog.RegisterCounterWith[int64](registry, "rpc_bytes_received")
+)
+
+// NewInterceptor returns a connect-go interceptor that attaches a new
+// [canonlog.Line] to the context of every RPC handled, unary or streaming,
+// records the RPC's procedure, protocol ("connect", "grpc" or "grpcweb"),
+// status code, the number and sizes of the messages received and sent, and
+// its duration, and emits the line to logger once the handler returns. RPCs
+// failing with a code indicating a server problem, such as internal or
+// unavailable, are emitted at [slog.LevelError], and all others at
+// [slog.LevelInfo]. The code of successful RPCs is recorded as "ok".
+//
+// The interceptor has no effect on clients.
+//
+// If logger is nil, [slog.Default] is used.
+func NewInterceptor(logger *slog.Logger) connect.Interceptor {
+	return &interceptor{logger: logger}
+}
+
+type interceptor struct {
+	logger *slog.Logger
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (resp connect.AnyResponse, err error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		start := time.Now()
+		ctx = begin(ctx, req.Spec(), req.Peer())
+		received(ctx, req.Any())
+		defer func() { i.finish(ctx, start, err) }()
+
+		resp, err = next(ctx, req)
+		if err == nil {
+			sent(ctx, resp.Any())
+		}
+		return resp, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) (err error) {
+		start := time.Now()
+		ctx = begin(ctx, conn.Spec(), conn.Peer())
+		defer func() { i.finish(ctx, start, err) }()
+
+		return next(ctx, &handlerConn{StreamingHandlerConn: conn, ctx: ctx})
+	}
+}
+
+// begin attaches a new Line to ctx for an RPC.
+func begin(ctx context.Context, spec connect.Spec, peer connect.Peer) context.Context {
+	ctx = canonlog.New(ctx)
+	canonlog.Set(ctx, AttrProcedure, spec.Procedure)
+	canonlog.Set(ctx, AttrProtocol, peer.Protocol)
+	return ctx
+}
+
+// received records a message received by the handler.
+func received(ctx context.Context, msg any) {
+	canonlog.Add(ctx, AttrMsgsReceived, 1)
+	if pm, ok := msg.(proto.Message); ok {
+		canonlog.Add(ctx, AttrBytesReceived, int64(proto.Size(pm)))
+	}
+}
+
+// sent records a message sent by the handler.
+func sent(ctx context.Context, msg any) {
+	canonlog.Add(ctx, AttrMsgsSent, 1)
+	if pm, ok := msg.(proto.Message); ok {
+		canonlog.Add(ctx, AttrBytesSent, int64(proto.Size(pm)))
+	}
+}
+
+// finish records the outcome of an RPC and emits its line.
+func (i *interceptor) finish(ctx context.Context, start time.Time, err error) {
+	code := "ok"
+	level := slog.LevelInfo
+	if err != nil {
+		c := connect.CodeOf(err)
+		code = c.String()
+		switch c {
+		case connect.CodeUnknown, connect.CodeDeadlineExceeded, connect.CodeUnimplemented,
+			connect.CodeInternal, connect.CodeUnavailable, connect.CodeDataLoss:
+			level = slog.LevelError
+		}
+	}
+	canonlog.Set(ctx, AttrCode, code)
+	canonlog.Set(ctx, AttrDuration, time.Since(start))
+	canonlog.Emit(ctx, i.logger, level)
+}
+
+// handlerConn wraps a [connect.StreamingHandlerConn] to count the messages
+// passing through it.
+type handlerConn struct {
+	connect.StreamingHandlerConn
+	ctx context.Context
+}
+
+func (c *handlerConn) Receive(msg any) error {
+	err := c.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		received(c.ctx, msg)
+	}
+	return err
+}
+
+func (c *handlerConn) Send(msg any) error {
+	err := c.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		sent(c.ctx, msg)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package canonconnect
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/andrew-d/canonlog"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var attrUser = canonlog.Register[string]("user")
+
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// testServer serves a unary procedure /test.Svc/Greet, which fails for
+// the name "fail", and a server-streaming procedure /test.Svc/Count.
+func testServer(t *testing.T, logger *slog.Logger) *httptest.Server {
+	opts := connect.WithInterceptors(NewInterceptor(logger))
+	mux := http.NewServeMux()
+	mux.Handle("/test.Svc/Greet", connect.NewUnaryHandler("/test.Svc/Greet",
+		func(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+			name := req.Msg.GetValue()
+			canonlog.Set(ctx, attrUser, name)
+			if name == "fail" {
+				return nil, connect.NewError(connect.CodeInternal, errors.New("boom"))
+			}
+			return connect.NewResponse(wrapperspb.String("hello " + name)), nil
+		}, opts))
+	mux.Handle("/test.Svc/Count", connect.NewServerStreamHandler("/test.Svc/Count",
+		func(ctx context.Context, req *connect.Request[wrapperspb.Int32Value], stream *connect.ServerStream[wrapperspb.Int32Value]) error {
+			for i := range req.Msg.GetValue() {
+				if err := stream.Send(wrapperspb.Int32(i + 1)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, opts))
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	srv := testServer(t, testLogger(&buf))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		opts []connect.ClientOption
+		req  string
+		want string // log output, without the duration
+	}{
+		{"connect", nil, "ann", "level=INFO msg=canonical-log-line rpc_procedure=/test.Svc/Greet rpc_protocol=connect rpc_msgs_received=1 rpc_bytes_received=5 user=ann rpc_msgs_sent=1 rpc_bytes_sent=11 rpc_code=ok"},
+		{"grpc", []connect.ClientOption{connect.WithGRPC()}, "ann", "level=INFO msg=canonical-log-line rpc_procedure=/test.Svc/Greet rpc_protocol=grpc rpc_msgs_received=1 rpc_bytes_received=5 user=ann rpc_msgs_sent=1 rpc_bytes_sent=11 rpc_code=ok"},
+		{"grpcweb", []connect.ClientOption{connect.WithGRPCWeb()}, "fail", "level=ERROR msg=canonical-log-line rpc_procedure=/test.Svc/Greet rpc_protocol=grpcweb rpc_msgs_received=1 rpc_bytes_received=6 user=fail rpc_code=internal"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		// The interceptor must have no effect on clients.
+		opts := append(tt.opts, connect.WithInterceptors(NewInterceptor(testLogger(&buf))))
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](srv.Client(), srv.URL+"/test.Svc/Greet", opts...)
+		client.CallUnary(ctx, connect.NewRequest(wrapperspb.String(tt.req)))
+
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("%s: log output:\ngot:  %q\nwant: %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInterceptor_Streaming(t *testing.T) {
+	var buf bytes.Buffer
+	srv := testServer(t, testLogger(&buf))
+
+	client := connect.NewClient[wrapperspb.Int32Value, wrapperspb.Int32Value](srv.Client(), srv.URL+"/test.Svc/Count")
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(wrapperspb.Int32(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for stream.Receive() {
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "level=INFO msg=canonical-log-line rpc_procedure=/test.Svc/Count rpc_protocol=connect rpc_msgs_received=1 rpc_bytes_received=2 rpc_msgs_sent=3 rpc_bytes_sent=6 rpc_code=ok"
+	if got, _, _ := strings.Cut(buf.String(), " duration="); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/andrew-d/canonlog/canonconnect
+
+go 1.25.3
+
+require (
+	connectrpc.com/connect v1.19.1
+	github.com/andrew-d/canonlog v0.0.0
+	google.golang.org/protobuf v1.36.11
+)
+
+replace github.com/andrew-d/canonlog => ../
@@ -0,0 +1,86 @@
+// Package canonecho emits canonical log lines for servers built with Echo.
+//
+// Install the middleware so that every request gets its own
+// [canonlog.Line], emitted when the request completes:
+//
+//	e := echo.New()
+//	e.Use(canonecho.Middleware(logger))
+//
+// Handlers find the line in the context of the request,
+// c.Request().Context().
+//
+// It is a separate module from canonlog, so that programs that do not use
+// Echo do not depend on it.
+package canonecho
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/labstack/echo/v4"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package, with the same keys as those recorded
+// by [canonlog.Middleware].
+var (
+	AttrMethod    = canonlog.RegisterWith[string](registry, "http_method")
+	AttrPath      = canonlog.RegisterWith[string](registry, "http_path")
+	AttrRoute     = canonlog.RegisterWith[string](registry, "http_route")
+	AttrStatus    = canonlog.RegisterWith[int](registry, "http_status")
+	AttrDuration  = canonlog.RegisterWith[time.Duration](registry, "duration")
+	AttrRespBytes = canonlog.RegisterWith[int64](registry, "resp_bytes")
+)
+
+// Middleware returns Echo middleware that attaches a new [canonlog.Line] to
+// the context of every request, records the request's method, path, route
+// pattern (such as "/users/:id"), response status, duration and response
+// size, and emits the line to logger once the next handler returns.
+// Responses with a 5xx status are emitted at [slog.LevelError], and all
+// others at [slog.LevelInfo].
+//
+// If the handler returns an error without having written a response, the
+// error is passed to the server's error handler, as by [echo.Context.Error],
+// so that the status it responds with is recorded, and the middleware
+// returns nil in its place.
+//
+// If logger is nil, [slog.Default] is used.
+func Middleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+			ctx := canonlog.New(req.Context())
+			c.SetRequest(req.WithContext(ctx))
+			canonlog.Set(ctx, AttrMethod, req.Method)
+			canonlog.Set(ctx, AttrPath, req.URL.Path)
+
+			err := next(c)
+			if err != nil && !c.Response().Committed {
+				c.Error(err)
+				err = nil
+			}
+
+			if route := c.Path(); route != "" {
+				canonlog.Set(ctx, AttrRoute, route)
+			}
+			status := c.Response().Status
+			canonlog.Set(ctx, AttrStatus, status)
+			canonlog.Set(ctx, AttrDuration, time.Since(start))
+			canonlog.Set(ctx, AttrRespBytes, c.Response().Size)
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			canonlog.Emit(ctx, logger, level)
+			return err
+		}
+	}
+}
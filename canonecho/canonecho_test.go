@@ -0,0 +1,71 @@
+package canonecho
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/labstack/echo/v4"
+)
+
+var attrUser = canonlog.Register[string]("user")
+
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Use(Middleware(testLogger(&buf)))
+	e.GET("/users/:id", func(c echo.Context) error {
+		canonlog.Set(c.Request().Context(), attrUser, c.Param("id"))
+		return c.String(http.StatusOK, "hello")
+	})
+	e.GET("/forbidden", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden)
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return errors.New("boom")
+	})
+
+	tests := []struct {
+		path string
+		want string // log output, without the duration and size
+	}{
+		{"/users/123", "level=INFO msg=canonical-log-line http_method=GET http_path=/users/123 user=123 http_route=/users/:id http_status=200"},
+		{"/forbidden", "level=INFO msg=canonical-log-line http_method=GET http_path=/forbidden http_route=/forbidden http_status=403"},
+		{"/fail", "level=ERROR msg=canonical-log-line http_method=GET http_path=/fail http_route=/fail http_status=500"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("GET %s: log output:\ngot:  %q\nwant: %q", tt.path, got, tt.want)
+		}
+		if want := "http_status=" + strconv.Itoa(rec.Code); !strings.Contains(got, want) {
+			t.Errorf("GET %s: log output %q does not record the status sent, %d", tt.path, got, rec.Code)
+		}
+	}
+
+	buf.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/123", nil))
+	if got := buf.String(); !strings.HasSuffix(got, " resp_bytes=5\n") {
+		t.Errorf("log output = %q, want resp_bytes=5", got)
+	}
+}
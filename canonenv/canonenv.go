@@ -0,0 +1,95 @@
+// Package canonenv provides [canonlog.Enricher] implementations that add
+// information about the environment a process runs in to canonical log
+// lines, such as its Kubernetes pod, container, or cloud instance, so that
+// every service includes the same infrastructure context:
+//
+//	env := []canonlog.Enricher{canonenv.Kubernetes(), canonenv.ContainerID()}
+//	if ec2, err := canonenv.EC2(ctx); err == nil {
+//		env = append(env, ec2)
+//	}
+//	emitter := canonlog.NewEmitter(logger, canonlog.WithEnricher(env...))
+//
+// The information is looked up once, when an enricher is created, so the
+// enrichers are cheap to run for every line.
+package canonenv
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"regexp"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// KubernetesEnv maps the environment variables read by [Kubernetes] to the
+// keys of the attributes they are recorded under, within the "k8s" group.
+// The variables are conventionally populated with the downward API:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.name
+var KubernetesEnv = []struct{ Var, Key string }{
+	{"POD_NAME", "pod"},
+	{"POD_NAMESPACE", "namespace"},
+	{"POD_IP", "pod_ip"},
+	{"NODE_NAME", "node"},
+}
+
+// Kubernetes returns an enricher that contributes a "k8s" group with the
+// values of the environment variables listed in [KubernetesEnv] that are
+// set when it is created. If none are set, the enricher contributes
+// nothing.
+func Kubernetes() canonlog.Enricher {
+	var attrs []any
+	for _, env := range KubernetesEnv {
+		if v := os.Getenv(env.Var); v != "" {
+			attrs = append(attrs, slog.String(env.Key, v))
+		}
+	}
+	if attrs == nil {
+		return canonlog.StaticEnricher()
+	}
+	return canonlog.StaticEnricher(slog.Group("k8s", attrs...))
+}
+
+// containerFiles are the files searched by ContainerID, in order.
+var containerFiles = []string{"/proc/self/cgroup", "/proc/self/mountinfo"}
+
+// containerIDPattern matches a container ID in a cgroup path or mount
+// point, such as "/docker/<id>" or "/containers/<id>/hostname".
+var containerIDPattern = regexp.MustCompile(`(?:^|[/-])([0-9a-f]{64})(?:\.scope|/|$)`)
+
+// ContainerID returns an enricher that contributes a "container_id"
+// attribute with the ID of the container the process runs in, as found in
+// its cgroup and mount information when the enricher is created. If the ID
+// cannot be found, for example because the process does not run in a
+// container or not on Linux, the enricher contributes nothing.
+func ContainerID() canonlog.Enricher {
+	for _, name := range containerFiles {
+		if id := findContainerID(name); id != "" {
+			return canonlog.StaticEnricher(slog.String("container_id", id))
+		}
+	}
+	return canonlog.StaticEnricher()
+}
+
+// findContainerID returns the first container ID found in the named file,
+// or "" if there is none.
+func findContainerID(name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if m := containerIDPattern.FindStringSubmatch(sc.Text()); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
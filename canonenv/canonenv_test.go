@@ -0,0 +1,97 @@
+package canonenv
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// render returns the attributes contributed by e in text format.
+func render(e canonlog.Enricher) string {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "", e.Enrich(context.Background())...)
+	return buf.String()
+}
+
+func TestKubernetes(t *testing.T) {
+	t.Setenv("POD_NAME", "web-7d9f")
+	t.Setenv("POD_NAMESPACE", "shop")
+	t.Setenv("POD_IP", "")
+	t.Setenv("NODE_NAME", "node-3")
+
+	want := "k8s.pod=web-7d9f k8s.namespace=shop k8s.node=node-3\n"
+	if got := render(Kubernetes()); got != want {
+		t.Errorf("attrs = %q, want %q", got, want)
+	}
+}
+
+func TestKubernetes_NotSet(t *testing.T) {
+	for _, env := range KubernetesEnv {
+		t.Setenv(env.Var, "")
+	}
+	if got := render(Kubernetes()); got != "\n" {
+		t.Errorf("attrs = %q, want none", got)
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	const id = "3f4e2b5c1d0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c7b6a5f4e"
+	tests := []struct {
+		name     string
+		cgroup   string
+		mounts   string
+		wantAttr string
+	}{
+		{
+			name:     "cgroup v1",
+			cgroup:   "12:pids:/docker/" + id + "\n0::/\n",
+			wantAttr: "container_id=" + id + "\n",
+		},
+		{
+			name:     "systemd scope",
+			cgroup:   "0::/system.slice/docker-" + id + ".scope\n",
+			wantAttr: "container_id=" + id + "\n",
+		},
+		{
+			name:     "cgroup v2 mountinfo",
+			cgroup:   "0::/\n",
+			mounts:   "651 640 254:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw - ext4 /dev/vda1 rw\n",
+			wantAttr: "container_id=" + id + "\n",
+		},
+		{
+			name:     "not a container",
+			cgroup:   "0::/user.slice/user-1000.slice/session-2.scope\n",
+			wantAttr: "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cgroup := filepath.Join(dir, "cgroup")
+			mounts := filepath.Join(dir, "mountinfo")
+			os.WriteFile(cgroup, []byte(tt.cgroup), 0o644)
+			os.WriteFile(mounts, []byte(tt.mounts), 0o644)
+
+			old := containerFiles
+			containerFiles = []string{cgroup, mounts}
+			t.Cleanup(func() { containerFiles = old })
+
+			if got := render(ContainerID()); got != tt.wantAttr {
+				t.Errorf("attrs = %q, want %q", got, tt.wantAttr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package canonenv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// Instance metadata service endpoints, variables for testing.
+var (
+	ec2Endpoint = "http://169.254.169.254"
+	gceEndpoint = "http://metadata.google.internal"
+)
+
+// EC2 returns an enricher that contributes a "cloud" group describing the
+// EC2 instance the process runs on: provider=aws, instance_id, instance_type,
+// region and zone. The information is fetched from the instance metadata
+// service (IMDSv2) when EC2 is called, and an error is returned if that
+// fails, such as when not running on EC2; ctx should have a short timeout
+// for that case.
+func EC2(ctx context.Context) (canonlog.Enricher, error) {
+	token, err := fetch(ctx, http.MethodPut, ec2Endpoint+"/latest/api/token",
+		"X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if err != nil {
+		return nil, err
+	}
+	get := func(p string) (string, error) {
+		return fetch(ctx, http.MethodGet, ec2Endpoint+"/latest/meta-data/"+p,
+			"X-aws-ec2-metadata-token", token)
+	}
+
+	attrs := []any{slog.String("provider", "aws")}
+	for _, f := range []struct{ key, path string }{
+		{"instance_id", "instance-id"},
+		{"instance_type", "instance-type"},
+		{"region", "placement/region"},
+		{"zone", "placement/availability-zone"},
+	} {
+		v, err := get(f.path)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.String(f.key, v))
+	}
+	return canonlog.StaticEnricher(slog.Group("cloud", attrs...)), nil
+}
+
+// GCE returns an enricher that contributes a "cloud" group describing the
+// Compute Engine instance the process runs on: provider=gcp, instance_id,
+// instance_type, region and zone. The information is fetched from the
+// metadata server when GCE is called, and an error is returned if that
+// fails, such as when not running on Google Cloud; ctx should have a short
+// timeout for that case.
+func GCE(ctx context.Context) (canonlog.Enricher, error) {
+	get := func(p string) (string, error) {
+		return fetch(ctx, http.MethodGet, gceEndpoint+"/computeMetadata/v1/instance/"+p,
+			"Metadata-Flavor", "Google")
+	}
+
+	id, err := get("id")
+	if err != nil {
+		return nil, err
+	}
+	// The zone and machine type are returned as resource names, such as
+	// "projects/123/zones/us-central1-a".
+	zone, err := get("zone")
+	if err != nil {
+		return nil, err
+	}
+	machineType, err := get("machine-type")
+	if err != nil {
+		return nil, err
+	}
+	zone = path.Base(zone)
+	region := zone
+	if i := strings.LastIndexByte(zone, '-'); i >= 0 {
+		region = zone[:i]
+	}
+
+	return canonlog.StaticEnricher(slog.Group("cloud",
+		slog.String("provider", "gcp"),
+		slog.String("instance_id", id),
+		slog.String("instance_type", path.Base(machineType)),
+		slog.String("region", region),
+		slog.String("zone", zone),
+	)), nil
+}
+
+// fetch makes a request to a metadata service with the given header and
+// returns the response body.
+func fetch(ctx context.Context, method, url, header, value string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(header, value)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("canonenv: %s %s: %s", method, url, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
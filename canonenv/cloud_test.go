@@ -0,0 +1,76 @@
+package canonenv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// metadataServer starts a metadata server that responds to each request
+// with the body for its method and path in routes, if the request has the
+// header given for that route, and points *endpoint at it.
+func metadataServer(t *testing.T, endpoint *string, routes map[string]struct{ header, body string }) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get(route.header) == "" {
+			http.Error(w, "missing "+route.header, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(route.body))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := *endpoint
+	*endpoint = srv.URL
+	t.Cleanup(func() { *endpoint = old })
+}
+
+func TestEC2(t *testing.T) {
+	const token = "X-aws-ec2-metadata-token"
+	metadataServer(t, &ec2Endpoint, map[string]struct{ header, body string }{
+		"PUT /latest/api/token":                             {"X-aws-ec2-metadata-token-ttl-seconds", "AQAEAE=="},
+		"GET /latest/meta-data/instance-id":                 {token, "i-0abc123"},
+		"GET /latest/meta-data/instance-type":               {token, "m7g.large"},
+		"GET /latest/meta-data/placement/region":            {token, "us-east-1"},
+		"GET /latest/meta-data/placement/availability-zone": {token, "us-east-1b"},
+	})
+
+	e, err := EC2(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "cloud.provider=aws cloud.instance_id=i-0abc123 cloud.instance_type=m7g.large cloud.region=us-east-1 cloud.zone=us-east-1b\n"
+	if got := render(e); got != want {
+		t.Errorf("attrs = %q, want %q", got, want)
+	}
+}
+
+func TestEC2_Unavailable(t *testing.T) {
+	metadataServer(t, &ec2Endpoint, nil)
+	if _, err := EC2(context.Background()); err == nil {
+		t.Error("EC2 succeeded without a metadata service")
+	}
+}
+
+func TestGCE(t *testing.T) {
+	const flavor = "Metadata-Flavor"
+	metadataServer(t, &gceEndpoint, map[string]struct{ header, body string }{
+		"GET /computeMetadata/v1/instance/id":           {flavor, "4520031799277581759"},
+		"GET /computeMetadata/v1/instance/zone":         {flavor, "projects/123/zones/us-central1-a"},
+		"GET /computeMetadata/v1/instance/machine-type": {flavor, "projects/123/machineTypes/e2-medium"},
+	})
+
+	e, err := GCE(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "cloud.provider=gcp cloud.instance_id=4520031799277581759 cloud.instance_type=e2-medium cloud.region=us-central1 cloud.zone=us-central1-a\n"
+	if got := render(e); got != want {
+		t.Errorf("attrs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+// Package canonfiber emits canonical log lines for servers built with Fiber.
+//
+// Install the middleware so that every request gets its own
+// [canonlog.Line], emitted when the request completes:
+//
+//	app := fiber.New()
+//	app.Use(canonfiber.Middleware(logger))
+//
+// Fiber is not built on net/http, so handlers find the line in the context
+// returned by c.UserContext().
+//
+// It is a separate module from canonlog, so that programs that do not use
+// Fiber do not depend on it.
+package canonfiber
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/gofiber/fiber/v2"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package, with the same keys as those recorded
+// by [canonlog.Middleware].
+var (
+	AttrMethod    = canonlog.RegisterWith[string](registry, "http_method")
+	AttrPath      = canonlog.RegisterWith[string](registry, "http_path")
+	AttrRoute     = canonlog.RegisterWith[string](registry, "http_route")
+	AttrStatus    = canonlog.RegisterWith[int](registry, "http_status")
+	AttrDuration  = canonlog.RegisterWith[time.Duration](registry, "duration")
+	AttrRespBytes = canonlog.RegisterWith[int64](registry, "resp_bytes")
+)
+
+// Middleware returns a Fiber handler that attaches a new [canonlog.Line] to
+// the user context of every request, records the request's method, path,
+// route pattern (such as "/users/:id"), response status, duration and
+// response size, and emits the line to logger once the next handlers
+// return. Responses with a 5xx status are emitted at [slog.LevelError], and
+// all others at [slog.LevelInfo].
+//
+// If the handlers return an error, the status recorded is that of a
+// [*fiber.Error], or 500 for other errors, as sent by Fiber's default error
+// handler.
+//
+// If logger is nil, [slog.Default] is used.
+func Middleware(logger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		ctx := canonlog.New(c.UserContext())
+		c.SetUserContext(ctx)
+		// Fiber reuses the memory of the strings it returns once the
+		// request completes, so they must be copied.
+		canonlog.Set(ctx, AttrMethod, strings.Clone(c.Method()))
+		canonlog.Set(ctx, AttrPath, strings.Clone(c.Path()))
+
+		self := c.Route()
+		err := c.Next()
+
+		// If no later route matched, c.Route is still the one this
+		// middleware was installed with, which is not an endpoint.
+		if route := c.Route(); route != nil && route != self {
+			canonlog.Set(ctx, AttrRoute, strings.Clone(route.Path))
+		}
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		canonlog.Set(ctx, AttrStatus, status)
+		canonlog.Set(ctx, AttrDuration, time.Since(start))
+		canonlog.Set(ctx, AttrRespBytes, int64(len(c.Response().Body())))
+
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		canonlog.Emit(ctx, logger, level)
+		return err
+	}
+}
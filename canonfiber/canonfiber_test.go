@@ -0,0 +1,77 @@
+package canonfiber
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/gofiber/fiber/v2"
+)
+
+var attrUser = canonlog.Register[string]("user")
+
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	app := fiber.New()
+	app.Use(Middleware(testLogger(&buf)))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		canonlog.Set(c.UserContext(), attrUser, c.Params("id"))
+		return c.SendString("hello")
+	})
+	app.Get("/forbidden", func(c *fiber.Ctx) error {
+		return fiber.ErrForbidden
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return errors.New("boom")
+	})
+
+	tests := []struct {
+		path string
+		want string // log output, without the duration and size
+	}{
+		{"/users/123", "level=INFO msg=canonical-log-line http_method=GET http_path=/users/123 user=123 http_route=/users/:id http_status=200"},
+		{"/forbidden", "level=INFO msg=canonical-log-line http_method=GET http_path=/forbidden http_route=/forbidden http_status=403"},
+		{"/fail", "level=ERROR msg=canonical-log-line http_method=GET http_path=/fail http_route=/fail http_status=500"},
+		{"/missing", "level=INFO msg=canonical-log-line http_method=GET http_path=/missing http_status=404"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("GET %s: log output:\ngot:  %q\nwant: %q", tt.path, got, tt.want)
+		}
+		if want := "http_status=" + strconv.Itoa(resp.StatusCode); !strings.Contains(got, want) {
+			t.Errorf("GET %s: log output %q does not record the status sent, %d", tt.path, got, resp.StatusCode)
+		}
+	}
+
+	buf.Reset()
+	app.Test(httptest.NewRequest("GET", "/users/123", nil))
+	if got := buf.String(); !strings.HasSuffix(got, " resp_bytes=5\n") {
+		t.Errorf("log output = %q, want resp_bytes=5", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Package canongin emits canonical log lines for servers built with gin.
+//
+// Install the middleware so that every request gets its own
+// [canonlog.Line], emitted when the request completes:
+//
+//	r := gin.New()
+//	r.Use(canongin.Middleware(logger))
+//
+// Handlers find the line in the context of the request, c.Request.Context().
+//
+// It is a separate module from canonlog, so that programs that do not use
+// gin do not depend on it.
+package canongin
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/gin-gonic/gin"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package, with the same keys as those recorded
+// by [canonlog.Middleware].
+var (
+	AttrMethod    = canonlog.RegisterWith[string](registry, "http_method")
+	AttrPath      = canonlog.RegisterWith[string](registry, "http_path")
+	AttrRoute     = canonlog.RegisterWith[string](registry, "http_route")
+	AttrStatus    = canonlog.RegisterWith[int](registry, "http_status")
+	AttrDuration  = canonlog.RegisterWith[time.Duration](registry, "duration")
+	AttrRespBytes = canonlog.RegisterWith[int64](registry, "resp_bytes")
+)
+
+// Middleware returns gin middleware that attaches a new [canonlog.Line] to
+// the context of every request, records the request's method, path, route
+// pattern (such as "/users/:id"), response status, duration and response
+// size, and emits the line to logger once the remaining handlers return.
+// Responses with a 5xx status are emitted at [slog.LevelError], and all
+// others at [slog.LevelInfo].
+//
+// If logger is nil, [slog.Default] is used.
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx := canonlog.New(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		canonlog.Set(ctx, AttrMethod, c.Request.Method)
+		canonlog.Set(ctx, AttrPath, c.Request.URL.Path)
+
+		defer func() {
+			if route := c.FullPath(); route != "" {
+				canonlog.Set(ctx, AttrRoute, route)
+			}
+			status := c.Writer.Status()
+			canonlog.Set(ctx, AttrStatus, status)
+			canonlog.Set(ctx, AttrDuration, time.Since(start))
+			canonlog.Set(ctx, AttrRespBytes, int64(max(c.Writer.Size(), 0)))
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			canonlog.Emit(ctx, logger, level)
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package canongin
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/gin-gonic/gin"
+)
+
+var attrUser = canonlog.Register[string]("user")
+
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(Middleware(testLogger(&buf)))
+	r.GET("/users/:id", func(c *gin.Context) {
+		canonlog.Set(c.Request.Context(), attrUser, c.Param("id"))
+		c.String(http.StatusOK, "hello")
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusBadGateway)
+	})
+
+	tests := []struct {
+		path string
+		want string // log output, without the duration and size
+	}{
+		{"/users/123", "level=INFO msg=canonical-log-line http_method=GET http_path=/users/123 user=123 http_route=/users/:id http_status=200"},
+		{"/fail", "level=ERROR msg=canonical-log-line http_method=GET http_path=/fail http_route=/fail http_status=502"},
+		{"/missing", "level=INFO msg=canonical-log-line http_method=GET http_path=/missing http_status=404"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("GET %s: log output:\ngot:  %q\nwant: %q", tt.path, got, tt.want)
+		}
+	}
+
+	buf.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/123", nil))
+	if got := buf.String(); !strings.HasSuffix(got, " resp_bytes=5\n") {
+		t.Errorf("log output = %q, want resp_bytes=5", got)
+	}
+}
@@ -0,0 +1,146 @@
+// Package canongrpc emits canonical log lines for gRPC servers.
+//
+// Install both interceptors so that every RPC gets its own
+// [canonlog.Line], emitted when the RPC completes:
+//
+//	srv := grpc.NewServer(
+//		grpc.UnaryInterceptor(canongrpc.UnaryServerInterceptor(logger)),
+//		grpc.StreamInterceptor(canongrpc.StreamServerInterceptor(logger)),
+//	)
+//
+// It is a separate module from canonlog, so that programs that do not use
+// gRPC do not depend on it.
+package canongrpc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+func counter(key string) canonlog.Attr[int64] {
+	return canonlog.RegisterWith(registry, key,
+		canonlog.WithMerge(func(old, new int64) int64 { return old + new }),
+		canonlog.WithCommutativeMerge[int64](),
+	)
+}
+
+// Attributes recorded by this package.
+var (
+	AttrMethod   = canonlog.RegisterWith[string](registry, "grpc_method")
+	AttrCode     = canonlog.RegisterWith[string](registry, "grpc_code")
+	AttrDuration = canonlog.RegisterWith[time.Duration](registry, "duration")
+
+	// Recorded for streaming RPCs only. Sizes are those of the messages
+	// as protocol buffers, and are not recorded for other messages.
+	AttrMsgsSent      = counter("grpc_msgs_sent")
+	AttrMsgsReceived  = counter("grpc_msgs_received")
+	AttrBytesSent     = counter("grpc_bytes_sent")
+	AttrBytesReceived = counter("grpc_bytes_received")
+
+	// AttrFirstMsgLatency is the time from the start of a streaming RPC
+	// until the server sent its first message.
+	AttrFirstMsgLatency = canonlog.RegisterWith[time.Duration](registry, "grpc_first_msg_latency")
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor that attaches a new
+// [canonlog.Line] to the context of every unary RPC, records the RPC's
+// method, status code and duration, and emits the line to logger once the
+// handler returns. RPCs failing with a code indicating a server problem,
+// such as Internal or Unavailable, are emitted at [slog.LevelError], and all
+// others at [slog.LevelInfo].
+//
+// If logger is nil, [slog.Default] is used.
+func UnaryServerInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		start := time.Now()
+		ctx = canonlog.New(ctx)
+		canonlog.Set(ctx, AttrMethod, info.FullMethod)
+		defer func() { finish(ctx, logger, start, err) }()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor that attaches a new
+// [canonlog.Line] to the context of every streaming RPC, and emits it to
+// logger when the stream closes. In addition to what
+// [UnaryServerInterceptor] records, it counts the messages sent and
+// received and their sizes, and the latency of the first message sent.
+//
+// If logger is nil, [slog.Default] is used.
+func StreamServerInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		ctx := canonlog.New(ss.Context())
+		canonlog.Set(ctx, AttrMethod, info.FullMethod)
+		defer func() { finish(ctx, logger, start, err) }()
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx, start: start})
+	}
+}
+
+// finish records the outcome of an RPC and emits its line.
+func finish(ctx context.Context, logger *slog.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	canonlog.Set(ctx, AttrCode, code.String())
+	canonlog.Set(ctx, AttrDuration, time.Since(start))
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		level = slog.LevelError
+	}
+	canonlog.Emit(ctx, logger, level)
+}
+
+// serverStream wraps a [grpc.ServerStream] to carry the RPC's Line in its
+// context and count the messages passing through it.
+type serverStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	start time.Time
+	first sync.Once
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.first.Do(func() {
+			canonlog.Set(s.ctx, AttrFirstMsgLatency, time.Since(s.start))
+		})
+		canonlog.Set(s.ctx, AttrMsgsSent, 1)
+		if pm, ok := m.(proto.Message); ok {
+			canonlog.Set(s.ctx, AttrBytesSent, int64(proto.Size(pm)))
+		}
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		canonlog.Set(s.ctx, AttrMsgsReceived, 1)
+		if pm, ok := m.(proto.Message); ok {
+			canonlog.Set(s.ctx, AttrBytesReceived, int64(proto.Size(pm)))
+		}
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package canongrpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+var attrUser = canonlog.RegisterWith[string](canonlog.NewRegistry(), "user")
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := func(ctx context.Context, req any) (any, error) {
+			time.Sleep(15 * time.Millisecond)
+			canonlog.Set(ctx, attrUser, "usr_123")
+			return nil, status.Error(codes.Internal, "boom")
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: "/users.v1.Users/Get"}
+		UnaryServerInterceptor(testLogger(&buf))(context.Background(), nil, info, handler)
+
+		want := "level=ERROR msg=canonical-log-line grpc_method=/users.v1.Users/Get user=usr_123 grpc_code=Internal duration=15ms\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+// fakeStream is a grpc.ServerStream that receives the messages in recv.
+type fakeStream struct {
+	grpc.ServerStream
+	recv []string
+}
+
+func (s *fakeStream) Context() context.Context { return context.Background() }
+func (s *fakeStream) SendMsg(m any) error      { return nil }
+
+func (s *fakeStream) RecvMsg(m any) error {
+	if len(s.recv) == 0 {
+		return io.EOF
+	}
+	m.(*wrapperspb.StringValue).Value, s.recv = s.recv[0], s.recv[1:]
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := func(srv any, ss grpc.ServerStream) error {
+			for {
+				var msg wrapperspb.StringValue
+				if err := ss.RecvMsg(&msg); err == io.EOF {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+				ss.SendMsg(wrapperspb.String("echo: " + msg.Value))
+			}
+			return nil
+		}
+		ss := &fakeStream{recv: []string{"hello", "world!"}}
+		info := &grpc.StreamServerInfo{FullMethod: "/echo.v1.Echo/Chat"}
+		StreamServerInterceptor(testLogger(&buf))(nil, ss, info, handler)
+
+		want := "level=INFO msg=canonical-log-line grpc_method=/echo.v1.Echo/Chat" +
+			" grpc_msgs_received=2 grpc_bytes_received=15 grpc_first_msg_latency=10ms" +
+			" grpc_msgs_sent=2 grpc_bytes_sent=27 grpc_code=OK duration=20ms\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+// Package canonjob emits a canonical log line for each run of a scheduled
+// job, such as a cron job or a periodic background task:
+//
+//	c.AddFunc("@daily", func() {
+//		canonjob.Run(ctx, "nightly_report", func(ctx context.Context) error {
+//			for _, account := range accounts {
+//				if err := report(ctx, account); err != nil {
+//					canonjob.Failed(ctx, 1)
+//					continue
+//				}
+//				canonjob.Processed(ctx, 1)
+//			}
+//			return nil
+//		})
+//	})
+//
+// Each line records the job name, duration, the number of items processed
+// and failed as counted by the job, and the outcome, including any panic.
+package canonjob
+
+import (
+	"context"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+func counter(key string) canonlog.Attr[int64] {
+	return canonlog.RegisterWith(registry, key,
+		canonlog.WithMerge(func(old, new int64) int64 { return old + new }),
+		canonlog.WithCommutativeMerge[int64](),
+	)
+}
+
+// Attributes recorded by this package.
+var (
+	// AttrProcessed is the number of items the job processed
+	// successfully, as counted with [Processed].
+	AttrProcessed = counter("items_processed")
+
+	// AttrFailed is the number of items the job failed to process, as
+	// counted with [Failed].
+	AttrFailed = counter("items_failed")
+)
+
+// Option configures [Run].
+type Option func(*config)
+
+// config holds the configuration of [Run].
+type config struct {
+	emitter *canonlog.Emitter
+}
+
+// WithEmitter makes [Run] emit its line with e, instead of an
+// [canonlog.Emitter] logging to [slog.Default].
+func WithEmitter(e *canonlog.Emitter) Option {
+	return func(cfg *config) {
+		cfg.emitter = e
+	}
+}
+
+// Run runs fn as a run of the job with the given name, with a context
+// carrying a new [canonlog.Line] that is emitted once fn returns, and
+// returns fn's error. The line is that of [canonlog.Emitter.Task], with the
+// job name recorded under the "task" key, and includes the items counted
+// by fn with [Processed] and [Failed]. Items are only recorded if counted.
+func Run(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...Option) error {
+	cfg := config{emitter: canonlog.NewEmitter(nil)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.emitter.Task(ctx, name, fn)
+}
+
+// Processed counts n items as processed successfully by the job whose run
+// the context belongs to. If the context does not have a [canonlog.Line],
+// Processed silently does nothing.
+func Processed(ctx context.Context, n int) {
+	canonlog.Set(ctx, AttrProcessed, int64(n))
+}
+
+// Failed counts n items as failed by the job whose run the context belongs
+// to. See [Processed] for details.
+func Failed(ctx context.Context, n int) {
+	canonlog.Set(ctx, AttrFailed, int64(n))
+}
@@ -0,0 +1,54 @@
+package canonjob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestRun(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		e := canonlog.NewEmitter(testLogger(&buf))
+
+		errPartial := errors.New("2 accounts failed")
+		err := Run(context.Background(), "nightly_report", func(ctx context.Context) error {
+			for i := range 5 {
+				time.Sleep(time.Second)
+				if i%2 == 1 {
+					Failed(ctx, 1)
+				} else {
+					Processed(ctx, 1)
+				}
+			}
+			return errPartial
+		}, WithEmitter(e))
+		if err != errPartial {
+			t.Errorf("Run returned %v, want %v", err, errPartial)
+		}
+
+		want := "level=ERROR msg=canonical-log-line task=nightly_report items_processed=3 items_failed=2 duration=5s outcome=error error=\"2 accounts failed\"\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
@@ -26,15 +26,36 @@ package canonlog
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Registry tracks registered attribute keys to prevent duplicates.
 // Use [NewRegistry] to create a new instance, or use [DefaultRegistry]
 // for the default global registry.
 type Registry struct {
-	mu   sync.Mutex
-	keys map[string]bool
+	mu            sync.Mutex
+	keys          map[string]bool
+	setters       map[string]func(context.Context, any) bool // Attr.setAny by key
+	onSet         atomic.Pointer[[]SetHook]                  // read without mu
+	schemaVersion string
+	schemaKey     string // see SetSchemaVersionKey; SchemaVersionKey if empty
+	ordering      Ordering
+	static        []staticValue // replaced, never modified, when changed
+	onEmit        []EmitHook    // likewise
+	onLogged      []LoggedHook  // likewise
+	registered    uint64        // number of attributes registered
+	maxBytes      int
+	tokenizer     Tokenizer
+	enums         map[string][]string // allowed values by key, see Enums
+	recorder      *Recorder
+	aliases       map[string]string // alias -> key, see Aliases
+
+	// lines and usage hold the statistics returned by Usage.
+	lines atomic.Int64
+	usage sync.Map // string -> *atomic.Int64
 }
 
 // NewRegistry creates a new [Registry].
@@ -51,12 +72,43 @@ var DefaultRegistry = NewRegistry()
 // Attr is a type-safe handle for a registered attribute.
 // It is created by [Register] and used with [Set] to store values.
 type Attr[T any] struct {
-	key     string
-	merge   func(old, new T) T
-	toValue func(T) slog.Value
+	key          string // name, qualified by group if any
+	name         string
+	group        string
+	seq          uint64 // registration order within the registry
+	priority     int
+	merge        func(old, new T) T
+	commutative  bool
+	toValue      func(T) slog.Value
+	unit         string // of the emitted value, see WithDurationAs
+	validate     func(T) error
+	visibility   Visibility
+	encryptor    Encryptor
+	tokenize     bool
+	clamp        func(any) (any, bool) // see WithClamp and WithEnumFallback
+	enumFallback string                // see WithEnumFallback
+	aliases      []string              // see WithAlias
+	members      []string              // keys of group members, see RegisterGroupWith
+
+	// convert calls toValue with a value of type T held in an any, and
+	// tokenizes and encrypts the result if the attribute is registered
+	// WithTokenize and WithEncrypt. It is created
+	// once at registration, rather than on every Set, and is nil if there
+	// is nothing to do.
+	convert func(any) slog.Value
+
+	// newAdder, if not nil, creates the accumulator of the attribute in
+	// place of an [accumulator] using merge; see [RegisterCounterWith].
+	newAdder func() adder[T]
+
+	// setAny calls Set with a value of type T held in an any, for
+	// operations such as Join that handle values of many attributes. It
+	// reports whether v had type T.
+	setAny func(ctx context.Context, v any) bool
 }
 
-// Key returns the attribute's key name.
+// Key returns the attribute's key name. For an attribute registered
+// [WithGroup], the key is qualified by the group name, as in "db.queries".
 func (a Attr[T]) Key() string {
 	return a.key
 }
@@ -70,9 +122,32 @@ type Option[T any] func(*Attr[T])
 //
 // If no merge function is set, the default behavior is to overwrite
 // the existing value with the new value.
+//
+// WithMerge replaces any previously set merge function, along with its
+// [WithCommutativeMerge] declaration.
 func WithMerge[T any](fn func(old, new T) T) Option[T] {
 	return func(a *Attr[T]) {
 		a.merge = fn
+		a.commutative = false
+		a.newAdder = nil
+	}
+}
+
+// WithCommutativeMerge declares that the attribute's merge function (set
+// with [WithMerge]) is commutative and associative: merging values in any
+// order and with any grouping gives the same result, as with sums, maximums
+// or set unions.
+//
+// Concurrent Sets of such an attribute are accumulated in several
+// independently locked shards that are only combined when the line is read,
+// so that goroutines updating the same attribute rarely contend with each
+// other. Declaring a merge function that does not have these properties
+// makes the emitted value depend on scheduling. WithCommutativeMerge has no
+// effect on an attribute without a merge function, and must be given after
+// the corresponding [WithMerge].
+func WithCommutativeMerge[T any]() Option[T] {
+	return func(a *Attr[T]) {
+		a.commutative = true
 	}
 }
 
@@ -93,27 +168,89 @@ func WithValue[T any](fn func(T) slog.Value) Option[T] {
 	}
 }
 
+// WithLogValuer makes the attribute convert its values to an [slog.Value]
+// with the LogValue method of *T, for types whose [slog.LogValuer]
+// implementation has a pointer receiver. Values of types that implement
+// LogValuer themselves, like those converted by [WithValue], are resolved
+// automatically, when the line's attributes are read.
+//
+// Example:
+//
+//	func (m *Money) LogValue() slog.Value { ... }
+//
+//	var AttrTotal = canonlog.Register("total", canonlog.WithLogValuer[Money]())
+func WithLogValuer[T any, PT interface {
+	*T
+	slog.LogValuer
+}]() Option[T] {
+	return WithValue(func(v T) slog.Value {
+		return slog.AnyValue(PT(&v)).Resolve()
+	})
+}
+
 // RegisterWith creates a new attribute with the given key in the specified
 // registry. It panics if an attribute with the same key has already been
 // registered in that registry.
 //
 // Use [Register] for the common case of registering with [DefaultRegistry].
 func RegisterWith[T any](r *Registry, key string, opts ...Option[T]) Attr[T] {
+	attr := Attr[T]{name: key}
+	for _, opt := range opts {
+		opt(&attr)
+	}
+	attr.key = attr.name // possibly changed by options like WithUnitSuffix
+	if attr.group != "" {
+		attr.key = attr.group + "." + attr.name
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if r.keys == nil {
 		r.keys = make(map[string]bool)
 	}
-	if r.keys[key] {
-		panic("canonlog: duplicate attribute key: " + key)
+	// Check every key before reserving any, so that a failed
+	// registration leaves r unchanged.
+	reserved := append([]string{attr.key}, attr.aliases...)
+	for _, m := range attr.members {
+		reserved = append(reserved, attr.key+"."+m)
 	}
-	r.keys[key] = true
-
-	attr := Attr[T]{key: key}
-	for _, opt := range opts {
-		opt(&attr)
+	for i, key := range reserved {
+		if r.keys[key] || slices.Contains(reserved[:i], key) {
+			panic("canonlog: duplicate attribute key: " + key)
+		}
+	}
+	for _, key := range reserved {
+		r.keys[key] = true
+	}
+	for _, alias := range attr.aliases {
+		if r.aliases == nil {
+			r.aliases = make(map[string]string)
+		}
+		r.aliases[alias] = attr.key
+	}
+	r.registered++
+	attr.seq = r.registered
+	if toValue := attr.toValue; toValue != nil {
+		attr.convert = func(v any) slog.Value { return toValue(v.(T)) }
+	}
+	if attr.tokenize {
+		attr.convert = tokenizing(r, attr.key, attr.convert)
+	}
+	if attr.encryptor != nil {
+		attr.convert = encrypting(attr.key, attr.convert, attr.encryptor)
+	}
+	attr.setAny = func(ctx context.Context, v any) bool {
+		t, ok := v.(T)
+		if ok {
+			Set(ctx, attr, t)
+		}
+		return ok
+	}
+	if r.setters == nil {
+		r.setters = make(map[string]func(context.Context, any) bool)
 	}
+	r.setters[attr.key] = attr.setAny
 	return attr
 }
 
@@ -127,31 +264,170 @@ func Register[T any](key string, opts ...Option[T]) Attr[T] {
 	return RegisterWith(DefaultRegistry, key, opts...)
 }
 
-// storedValue holds a raw value and an optional converter function.
+// storedValue holds a raw value and an optional converter function, along
+// with the name and group of the attribute it was set for.
 type storedValue struct {
-	raw     any
-	convert func(any) slog.Value
+	raw        any
+	convert    func(any) slog.Value
+	name       string
+	group      string
+	seq        uint64
+	priority   int
+	setAny     func(context.Context, any) bool
+	visibility Visibility
+	clamp      func(any) (any, bool)
+	aliases    []string
+	size       int    // approximate bytes held, if the line has a size limit
+	inserted   uint64 // position in the order keys were first set
+}
+
+// charged returns the number of bytes sv counts towards the size limit of
+// its line, including those held by an accumulator.
+func (sv storedValue) charged() int {
+	if a, ok := sv.raw.(interface{ charged() int }); ok {
+		return a.charged()
+	}
+	return sv.size
+}
+
+// value returns the value held by sv, combining the shards of an accumulator
+// and evaluating a value set with [SetLazy].
+func (sv storedValue) value() any {
+	switch raw := sv.raw.(type) {
+	case accumulated:
+		return raw.load()
+	case lazy:
+		return raw.eval()
+	}
+	return sv.raw
+}
+
+// newStoredValue returns a storedValue holding raw for attr.
+func newStoredValue[T any](attr Attr[T], raw any) storedValue {
+	return storedValue{
+		raw:      raw,
+		convert:  attr.convert,
+		name:     attr.name,
+		group:    attr.group,
+		seq:      attr.seq,
+		priority: attr.priority,
+		setAny:   attr.setAny,
+
+		visibility: attr.visibility,
+		clamp:      attr.clamp,
+		aliases:    attr.aliases,
+	}
 }
 
 // Line accumulates attributes for a single canonical log line.
 // It is safe for concurrent use.
 type Line struct {
-	mu     sync.Mutex
-	values map[string]storedValue
-	order  []string // maintains insertion order for consistent output
+	registry *Registry
+
+	// shards holds the line's values, spread over several maps by key so
+	// that goroutines setting different attributes do not contend on a
+	// single lock. inserted counts the keys inserted, to record the order
+	// in which they were first set.
+	shards   [lineShards]lineShard
+	inserted atomic.Uint64
+
+	// accums holds the accumulator (also stored in shards) for each
+	// attribute with a commutative merge function, so that Set can find it
+	// without taking any lock.
+	accums sync.Map // string -> *accumulator[T]
+
+	// enriched holds the attributes contributed by enrichers when the
+	// line was created; see WithLineEnricher.
+	enriched  []slog.Attr
+	enrichers []Enricher // set only while New runs
+
+	// onEmit holds the hooks given to WithEmitHook.
+	onEmit []EmitHook
+
+	// parent is the Line in the context passed to New, set only while
+	// its options are applied.
+	parent *Line
+
+	// frozen is set once the line has been emitted, and lateSets counts
+	// the attempts to set attributes on it since; see lateSet.
+	frozen   atomic.Bool
+	lateSets atomic.Int64
+
+	// maxBytes is the size limit of the line, bytes the approximate size
+	// of its values, and cappedSets the number of values dropped for
+	// exceeding it; see Registry.SetMaxBytes.
+	maxBytes   int
+	bytes      atomic.Int64
+	cappedSets atomic.Int64
+
+	// now is the clock of the line, or nil if it records no times, and
+	// start and end the times it was created and first emitted; see
+	// WithAutoDuration and WithTimestamps. end is guarded by the locks of
+	// all shards.
+	now             func() time.Time
+	start, end      time.Time
+	durationKey     string
+	timestamps      bool
+	timestampLayout string
+
+	// callers holds the location of the last Set of each attribute, in
+	// ModeDebug; see Dump.
+	callers sync.Map // string -> string
+
+	// pooled is set for lines created by NewPooled, which are released
+	// once emitted.
+	pooled bool
+
+	// recorder records the line, under the ID recorderID, if its registry
+	// has one; see Registry.SetRecorder.
+	recorder   *Recorder
+	recorderID uint64
 }
 
 // ctxKey is the context key for storing the Line.
 type ctxKey struct{}
 
+// LineOption configures a [Line] created by [New].
+type LineOption func(*Line)
+
+// WithRegistry associates the new Line with r instead of [DefaultRegistry].
+// The registry provides line-wide settings, such as the schema version set
+// with [Registry.SetSchemaVersion]; attributes registered in any registry
+// can still be set on the line.
+func WithRegistry(r *Registry) LineOption {
+	return func(l *Line) {
+		l.registry = r
+	}
+}
+
 // New creates a new [Line] and returns a context containing it.
 //
 // Use [Set] to add attributes to the line, and [Attrs] to retrieve them.
-func New(ctx context.Context) context.Context {
-	line := &Line{
-		values: make(map[string]storedValue),
+func New(ctx context.Context, opts ...LineOption) context.Context {
+	return newLine(ctx, new(Line), opts)
+}
+
+// newLine initializes line, which must be empty, with opts, and returns a
+// context derived from ctx containing it.
+func newLine(ctx context.Context, line *Line, opts []LineOption) context.Context {
+	line.registry = DefaultRegistry
+	line.parent = FromContext(ctx)
+	for _, opt := range opts {
+		opt(line)
+	}
+	line.parent = nil
+	line.startClock()
+	line.maxBytes = line.registry.MaxBytes()
+	line.account()
+	if rec := line.registry.getRecorder(); rec != nil {
+		rec.track(line)
 	}
-	return context.WithValue(ctx, ctxKey{}, line)
+	ctx = context.WithValue(ctx, ctxKey{}, line)
+	for _, e := range line.enrichers {
+		line.enriched = append(line.enriched, e.Enrich(ctx)...)
+	}
+	line.enrichers = nil
+	return ctx
 }
 
 // FromContext retrieves a [Line] from the provided [context.Context], or nil
@@ -164,70 +440,244 @@ func FromContext(ctx context.Context) *Line {
 }
 
 // Set stores a value for the given attribute in the [Line] attached to ctx.
-// If the context does not have a Line ([New] was not called), Set silently
-// does nothing.
+// If the context does not have a Line ([New] was not called), Set does
+// nothing, other than reporting the mistake in [ModeDebug] and [ModeStrict].
+// Neither does it once the Line has been emitted, other than recording the
+// mistake as described for [LateSetsKey] and [Mode].
 //
 // If the attribute was already set and has a merge function, the merge
 // function is called to combine the old and new values. Otherwise, the
-// new value overwrites the old value.
+// new value overwrites the old value. Values rejected by the attribute's
+// validator (see [WithValidator]) are dropped. Use [TrySet] to find out
+// whether a value was stored.
 func Set[T any](ctx context.Context, attr Attr[T], value T) {
-	l := FromContext(ctx)
-	if l == nil {
+	set(ctx, FromContext(ctx), attr, value)
+}
+
+// set is the implementation of [Set] and [SetOn], storing value for attr in
+// l. ctx is passed to set hooks, and may be nil if l is not attached to a
+// context; see runSetHooks.
+func set[T any](ctx context.Context, l *Line, attr Attr[T], value T) {
+	if attr.commutative && attr.merge != nil {
+		accumulate(ctx, l, attr, value)
 		return
 	}
+	setOn(ctx, l, attr, value, attr.merge)
+}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// setWith is like [Set], but uses merge in place of the attribute's own
+// merge function.
+func setWith[T any](ctx context.Context, attr Attr[T], value T, merge func(old, new T) T) {
+	setOn(ctx, FromContext(ctx), attr, value, merge)
+}
+
+// setOn is like set, but uses merge in place of the attribute's own merge
+// function, and never accumulates.
+func setOn[T any](ctx context.Context, l *Line, attr Attr[T], value T, merge func(old, new T) T) {
+	if l == nil {
+		noLine(attr.key)
+		return
+	}
+	if l.lateSet(attr.key) {
+		return
+	}
+	if !attr.valid(value) {
+		return
+	}
+	if !store(l, attr, value, merge) {
+		return
+	}
+	l.recordCaller(attr.key)
+	runSetHooks(ctx, l, attr.key, value)
+}
 
+// store stores value for attr in l, merging it with any existing value with
+// merge, and reports whether it did so within l's size limit.
+func store[T any](l *Line, attr Attr[T], value T, merge func(old, new T) T) bool {
 	key := attr.key
-	if existing, exists := l.values[key]; exists && attr.merge != nil {
-		if oldVal, ok := existing.raw.(T); ok {
-			value = attr.merge(oldVal, value)
+	s := l.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.get(key)
+	if exists {
+		if oldVal, ok := existing.raw.(T); ok && merge != nil {
+			value = merge(oldVal, value)
 		}
 	}
-
-	// Track insertion order for new keys
-	if _, exists := l.values[key]; !exists {
-		l.order = append(l.order, key)
+	size := sizeOf(key, value, l.maxBytes)
+	if !l.reserve(existing.charged(), size) {
+		return false
 	}
 
-	// Create converter function if attr has custom toValue
-	var convert func(any) slog.Value
-	if attr.toValue != nil {
-		convert = func(v any) slog.Value { return attr.toValue(v.(T)) }
+	if _, ok := existing.raw.(accumulated); ok {
+		// Overwriting an accumulator (only possible with an Attr of the
+		// same key from another registry); stop using it.
+		l.accums.Delete(key)
 	}
 
-	l.values[key] = storedValue{raw: value, convert: convert}
+	sv := newStoredValue(attr, value)
+	sv.size = size
+	l.put(s, key, sv)
+	return true
 }
 
 // Attrs returns all set attributes as [slog.Attr] values.
 //
-// Attributes are returned in the order they were first set. If the context
-// does not have a [Line], nil is returned.
+// Attributes are returned in the order configured for the line's registry
+// with [Registry.SetOrdering], by default the order in which they were first
+// set, preceded by the schema version of the registry if one is set and
+// followed by any attributes from enrichers given to [WithLineEnricher] and
+// the registry's static attributes (see [SetGlobalWith]). Values that
+// implement [slog.LogValuer] are resolved, except those set with [SetLazy].
+// Values are converted from a copy of the line's values, so that converters
+// given to [WithValue] do not delay concurrent calls to [Set].
+// NaN and infinite floats and negative durations, which many JSON pipelines
+// reject, are replaced with finite sentinel values, and the line is marked
+// with [SanitizedKey]; values set with SetLazy are not checked.
+// If the context does not have a [Line], or the line has no attributes, nil
+// is returned.
 func Attrs(ctx context.Context) []slog.Attr {
-	l := FromContext(ctx)
+	return AppendAttrs(ctx, nil)
+}
+
+// AppendAttrs appends the attributes returned by [Attrs] to dst and returns
+// the extended slice, so that a caller emitting many lines can reuse a
+// scratch slice rather than allocate a new one each time. With the default
+// [OrderInsertion] ordering, AppendAttrs does not allocate if dst has
+// enough capacity, unless the line has more than 16 attributes, attributes
+// registered [WithGroup] or values that must be boxed to be stored in a
+// [slog.Value].
+func AppendAttrs(ctx context.Context, dst []slog.Attr) []slog.Attr {
+	return FromContext(ctx).AppendAttrs(dst)
+}
+
+// AppendAttrs is like the function [AppendAttrs], for a Line held directly.
+// It returns dst unchanged if l is nil.
+func (l *Line) AppendAttrs(dst []slog.Attr) []slog.Attr {
+	return l.appendAttrs(dst, 0)
+}
+
+// appendAttrs is the implementation of [AppendAttrs], only appending
+// attributes whose visibility is in visible.
+func (l *Line) appendAttrs(dst []slog.Attr, visible visibilitySet) []slog.Attr {
 	if l == nil {
-		return nil
+		return dst
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	// The line's values are copied with it locked, and converted once it is
+	// unlocked, so that slow converters (see WithValue) and LogValuers
+	// cannot stall concurrent Sets. The copies are never modified: a Set
+	// replaces the storedValue of its key rather than changing it,
+	// accumulators are read atomically, and lazy values are evaluated at
+	// most once.
+	var buf [16]entry
+	l.lockAll()
+	n := l.len()
+	schemaKey, schemaVersion := l.registry.schema()
+	statics := l.registry.statics()
+	late, capped := l.lateSets.Load(), l.cappedSets.Load()
+	start, end, emitted := l.readClock()
+	if n == 0 && len(l.enriched) == 0 && schemaVersion == "" && len(statics) == 0 &&
+		late == 0 && capped == 0 && start.IsZero() {
+		l.unlockAll()
+		return dst
+	}
+	entries := l.appendOrdered(buf[:0])
+	l.unlockAll()
 
-	if len(l.values) == 0 {
-		return nil
+	b := attrsBuilder{result: slices.Grow(dst, n+1)}
+	if schemaVersion != "" {
+		b.result = append(b.result, slog.String(schemaKey, schemaVersion))
 	}
 
-	result := make([]slog.Attr, 0, len(l.order))
-	for _, key := range l.order {
-		if sv, exists := l.values[key]; exists {
-			var slogVal slog.Value
-			if sv.convert != nil {
-				slogVal = sv.convert(sv.raw)
-			} else {
-				slogVal = slog.AnyValue(sv.raw)
+	var sanitized bool // see SanitizedKey
+	for _, e := range entries {
+		if !visible.has(e.sv.visibility) {
+			continue
+		}
+		if lv, ok := e.sv.raw.(lazy); ok {
+			// Left for the handler to resolve, if the line is written.
+			b.add(e.key, e.sv.group, e.sv.name, slog.AnyValue(lv))
+			b.addAliases(e.sv.aliases, slog.AnyValue(lv))
+			continue
+		}
+		raw := e.sv.value()
+		if e.sv.clamp != nil {
+			var clamped bool
+			raw, clamped = e.sv.clamp(raw)
+			sanitized = sanitized || clamped
+		}
+
+		var slogVal slog.Value
+		if e.sv.convert != nil {
+			slogVal = e.sv.convert(raw)
+		} else {
+			slogVal = slog.AnyValue(raw)
+			// A LogValuer chooses its own representation, so
+			// is not serialized by reflection.
+			if slogVal.Kind() != slog.KindLogValuer {
+				if size, ok := checkLargeValue(raw); ok {
+					b.large = append(b.large, reportLargeValue(e.key, size))
+				}
 			}
-			result = append(result, slog.Attr{Key: key, Value: slogVal})
 		}
+		slogVal, replaced := sanitize(slogVal.Resolve())
+		sanitized = sanitized || replaced
+		b.add(e.key, e.sv.group, e.sv.name, slogVal)
+		b.addAliases(e.sv.aliases, slogVal)
+	}
+	if !start.IsZero() {
+		l.addClockAttrs(&b, entries, start, end, emitted)
+	}
+	b.result = append(b.result, l.enriched...)
+	// Static attributes are overridden by values set on the line itself.
+	for _, st := range statics {
+		if !hasKey(entries, st.key) && visible.has(st.visibility) {
+			b.add(st.key, st.group, st.name, st.value)
+			b.addAliases(st.aliases, st.value)
+		}
+	}
+	if late > 0 {
+		b.result = append(b.result, slog.Int64(LateSetsKey, late))
+	}
+	if capped > 0 {
+		b.result = append(b.result, slog.Int64(CappedSetsKey, capped))
+	}
+	if sanitized {
+		b.result = append(b.result, slog.Bool(SanitizedKey, true))
+	}
+	if b.large != nil {
+		b.result = append(b.result, slog.Attr{Key: LargeValuesKey, Value: slog.GroupValue(b.large...)})
+	}
+	return b.result
+}
+
+// attrsBuilder builds the attributes returned by AppendAttrs.
+type attrsBuilder struct {
+	result []slog.Attr
+	groups map[string]int // group name -> index in result
+	large  []slog.Attr    // see LargeValuesKey
+}
+
+// add appends an attribute to b.result. Attributes registered WithGroup are
+// collected into a single group attribute, positioned where the first of
+// them was added.
+func (b *attrsBuilder) add(key, group, name string, value slog.Value) {
+	if group == "" {
+		b.result = append(b.result, slog.Attr{Key: key, Value: value})
+		return
+	}
+
+	if b.groups == nil {
+		b.groups = make(map[string]int)
+	}
+	i, ok := b.groups[group]
+	if !ok {
+		i = len(b.result)
+		b.groups[group] = i
+		b.result = append(b.result, slog.Attr{Key: group, Value: slog.GroupValue()})
 	}
-	return result
+	members := append(b.result[i].Value.Group(), slog.Attr{Key: name, Value: value})
+	b.result[i].Value = slog.GroupValue(members...)
 }
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // testRegistry returns a new registry for use in a single test.
@@ -308,3 +312,202 @@ func TestWithValueAndMerge(t *testing.T) {
 		t.Errorf("total value = %q, want %q", got, "$6")
 	}
 }
+
+type testSecret string
+
+func (testSecret) LogValue() slog.Value { return slog.StringValue("[redacted]") }
+
+type testMoney struct {
+	cents    int64
+	currency string
+}
+
+func (m *testMoney) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency))
+}
+
+func TestLogValuer(t *testing.T) {
+	r := testRegistry(t)
+	attrToken := RegisterWith[testSecret](r, "token")
+	attrTotal := RegisterWith(r, "total", WithLogValuer[testMoney]())
+	attrRefund := RegisterWith[testMoney](r, "refund")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrToken, "s3cret")
+	Set(ctx, attrTotal, testMoney{1250, "EUR"})
+	Set(ctx, attrRefund, testMoney{300, "EUR"})
+	SetMany(ctx, map[string]any{"api_key": testSecret("k3y")})
+
+	want := []slog.Attr{
+		slog.String("token", "[redacted]"),
+		slog.String("total", "12.50 EUR"),
+		// Without WithLogValuer, a pointer receiver is not found.
+		slog.Any("refund", testMoney{300, "EUR"}),
+		slog.String("api_key", "[redacted]"),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
+
+// BenchmarkSetParallelKeys measures Sets from many goroutines of attributes
+// without a commutative merge function, each goroutine setting its own key
+// as with fan-out handlers recording per-backend results.
+func BenchmarkSetParallelKeys(b *testing.B) {
+	r := NewRegistry()
+	attrs := make([]Attr[int], 32)
+	for i := range attrs {
+		attrs[i] = RegisterWith(r, fmt.Sprintf("backend_%d", i),
+			WithMerge(func(old, new int) int { return old + new }))
+	}
+
+	for _, goroutines := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("goroutines=%d", goroutines), func(b *testing.B) {
+			ctx := New(context.Background())
+			var next atomic.Int64
+			b.SetParallelism(goroutines)
+			b.RunParallel(func(pb *testing.PB) {
+				attr := attrs[int(next.Add(1)-1)%len(attrs)]
+				for pb.Next() {
+					Set(ctx, attr, 1)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkNewSetAttrs(b *testing.B) {
+	r := NewRegistry()
+	attrUser := RegisterWith[string](r, "user")
+	attrStatus := RegisterWith[int](r, "status")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		ctx := New(context.Background())
+		Set(ctx, attrUser, "usr_123")
+		Set(ctx, attrStatus, 200)
+		Set(ctx, attrQueries, 1)
+		Set(ctx, attrQueries, 1)
+		_ = Attrs(ctx)
+	}
+}
+
+func TestAppendAttrs(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrStatus := RegisterWith[int](r, "status")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrQueries, 2)
+	Set(ctx, attrStatus, 200)
+
+	prefix := slog.String("service", "checkout")
+	got := AppendAttrs(ctx, []slog.Attr{prefix})
+	want := []slog.Attr{prefix, slog.String("user", "usr_123"), slog.Int("queries", 2), slog.Int("status", 200)}
+	if !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("AppendAttrs = %v, want %v", got, want)
+	}
+
+	scratch := make([]slog.Attr, 0, 8)
+	allocs := testing.AllocsPerRun(100, func() {
+		scratch = AppendAttrs(ctx, scratch[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("AppendAttrs allocated %v times per call, want 0", allocs)
+	}
+}
+
+func TestAttrs_SlowConverterDoesNotBlockSet(t *testing.T) {
+	r := testRegistry(t)
+	entered, release := make(chan struct{}), make(chan struct{})
+	attrSlow := RegisterWith(r, "slow", WithValue(func(n int) slog.Value {
+		close(entered)
+		<-release
+		return slog.IntValue(n)
+	}))
+	attrOther := RegisterWith[int](r, "other")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrSlow, 1)
+
+	done := make(chan []slog.Attr)
+	go func() { done <- Attrs(ctx) }()
+	<-entered
+
+	set := make(chan struct{})
+	go func() {
+		Set(ctx, attrOther, 2)
+		close(set)
+	}()
+	select {
+	case <-set:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Set blocked while Attrs was converting a value")
+	}
+	close(release)
+
+	// The attributes are those of the line when Attrs was called.
+	if got := <-done; len(got) != 1 || got[0].Key != "slow" {
+		t.Errorf("Attrs = %v, want only slow", got)
+	}
+}
+
+func BenchmarkAppendAttrs(b *testing.B) {
+	r := NewRegistry()
+	attrUser := RegisterWith[string](r, "user")
+	attrStatus := RegisterWith[int](r, "status")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrStatus, 200)
+	Set(ctx, attrQueries, 4)
+
+	b.Run("Attrs", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = Attrs(ctx)
+		}
+	})
+	b.Run("AppendAttrs", func(b *testing.B) {
+		b.ReportAllocs()
+		var scratch []slog.Attr
+		for b.Loop() {
+			scratch = AppendAttrs(ctx, scratch[:0])
+		}
+	})
+}
+
+func TestSetWithValueAllocs(t *testing.T) {
+	r := NewRegistry()
+	attrPlain := RegisterWith[int](r, "plain")
+	attrConverted := RegisterWith(r, "converted",
+		WithValue(func(n int) slog.Value { return slog.StringValue(strconv.Itoa(n)) }),
+	)
+	ctx := New(context.Background(), WithRegistry(r))
+
+	// Setting an attribute with a converter must not allocate more than
+	// setting one without.
+	plain := testing.AllocsPerRun(100, func() { Set(ctx, attrPlain, 7) })
+	converted := testing.AllocsPerRun(100, func() { Set(ctx, attrConverted, 7) })
+	if converted != plain {
+		t.Errorf("Set allocated %v times per call with WithValue, %v without", converted, plain)
+	}
+
+	want := []slog.Attr{slog.Int("plain", 7), slog.String("converted", "7")}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
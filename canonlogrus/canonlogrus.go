@@ -0,0 +1,110 @@
+// Package canonlogrus emits canonical log lines with logrus, for services
+// that cannot move off it yet:
+//
+//	defer canonlogrus.Emit(ctx, logger, logrus.InfoLevel)
+//
+// It is a separate module from canonlog, so that programs that do not use
+// logrus do not depend on it.
+package canonlogrus
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/sirupsen/logrus"
+)
+
+// Fields returns the attributes of the [canonlog.Line] attached to ctx, as
+// returned by [canonlog.Attrs], as logrus fields. Members of groups are
+// flattened into keys qualified by the group's, as in "db.queries", since
+// logrus formatters do not handle nested fields. If the context does not
+// have a Line, Fields returns nil.
+func Fields(ctx context.Context) logrus.Fields {
+	attrs := canonlog.Attrs(ctx)
+	if attrs == nil {
+		return nil
+	}
+	fields := make(logrus.Fields, len(attrs))
+	addFields(fields, "", attrs)
+	return fields
+}
+
+// addFields adds attrs to fields, qualifying their keys with prefix and
+// flattening groups. Empty attributes are skipped, as by [slog.Handler]
+// implementations.
+func addFields(fields logrus.Fields, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			p := prefix
+			if a.Key != "" {
+				p += a.Key + "."
+			}
+			addFields(fields, p, a.Value.Group())
+			continue
+		}
+		fields[prefix+a.Key] = a.Value.Any()
+	}
+}
+
+// Emit emits the [canonlog.Line] attached to ctx to logger at the given
+// level, with the fields returned by [Fields] and the message
+// [canonlog.Message], as [canonlog.Emit] does for slog loggers, which it
+// uses; the line can no longer be modified afterwards.
+func Emit(ctx context.Context, logger logrus.FieldLogger, level logrus.Level) {
+	NewEmitter(logger, level).Emit(ctx, slog.LevelInfo)
+}
+
+// NewEmitter returns a [canonlog.Emitter] that emits lines to logger at the
+// given level, whatever level is passed to its methods, so that options
+// such as sampling can be used with logrus.
+func NewEmitter(logger logrus.FieldLogger, level logrus.Level, opts ...canonlog.EmitterOption) *canonlog.Emitter {
+	return canonlog.NewEmitter(slog.New(&handler{logger: logger, level: level}), opts...)
+}
+
+// handler is a [slog.Handler] writing records to a logrus logger at a fixed
+// level.
+type handler struct {
+	logger logrus.FieldLogger
+	level  logrus.Level
+	prefix string // qualifies the keys of attributes, see WithGroup
+}
+
+func (h *handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(logrus.Fields, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		addFields(fields, h.prefix, []slog.Attr{a})
+		return true
+	})
+	entry := h.logger.WithFields(fields)
+	if !r.Time.IsZero() {
+		entry = entry.WithTime(r.Time)
+	}
+	entry.Log(h.level, r.Message)
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(logrus.Fields, len(attrs))
+	addFields(fields, h.prefix, attrs)
+	h2 := *h
+	h2.logger = h.logger.WithFields(fields)
+	return &h2
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
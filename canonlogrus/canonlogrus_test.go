@@ -0,0 +1,90 @@
+package canonlogrus
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestEmit(t *testing.T) {
+	r := canonlog.NewRegistry()
+	attrUser := canonlog.RegisterWith[string](r, "user")
+	attrStatus := canonlog.RegisterWith[int](r, "status")
+	attrDBTime := canonlog.RegisterWith(r, "time", canonlog.WithGroup[time.Duration]("db"))
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	hook := test.NewLocal(logger)
+
+	ctx := canonlog.New(context.Background(), canonlog.WithRegistry(r))
+	canonlog.Set(ctx, attrUser, "alice")
+	canonlog.Set(ctx, attrStatus, 200)
+	canonlog.Set(ctx, attrDBTime, 12*time.Millisecond)
+
+	want := logrus.Fields{
+		"user":    "alice",
+		"status":  int64(200),
+		"db.time": 12 * time.Millisecond,
+	}
+	if got := Fields(ctx); !equalFields(got, want) {
+		t.Errorf("Fields = %v, want %v", got, want)
+	}
+
+	Emit(ctx, logger, logrus.WarnLevel)
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("no entry logged")
+	}
+	if entry.Level != logrus.WarnLevel || entry.Message != canonlog.Message {
+		t.Errorf("logged %v %q, want %v %q", entry.Level, entry.Message, logrus.WarnLevel, canonlog.Message)
+	}
+	if !equalFields(entry.Data, want) {
+		t.Errorf("logged fields = %v, want %v", entry.Data, want)
+	}
+
+	// The line is frozen once emitted.
+	canonlog.Set(ctx, attrUser, "bob")
+	if got := Fields(ctx)["user"]; got != "alice" {
+		t.Errorf("user after emitting = %v, want alice", got)
+	}
+}
+
+func TestNewEmitter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	hook := test.NewLocal(logger)
+
+	e := NewEmitter(logger, logrus.InfoLevel, canonlog.WithEnricher(
+		canonlog.StaticEnricher(slog.Group("host", slog.String("name", "a"))),
+	))
+	e.Emit(canonlog.New(context.Background()), slog.LevelError)
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("no entry logged")
+	}
+	if entry.Level != logrus.InfoLevel {
+		t.Errorf("logged at %v, want %v", entry.Level, logrus.InfoLevel)
+	}
+	if got := entry.Data["host.name"]; got != "a" {
+		t.Errorf("host.name = %v, want a", got)
+	}
+}
+
+func equalFields(got, want logrus.Fields) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for k, v := range want {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
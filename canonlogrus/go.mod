@@ -0,0 +1,15 @@
+module github.com/andrew-d/canonlog/canonlogrus
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require (
+	github.com/stretchr/testify v1.9.0 // indirect
+	golang.org/x/sys v0.9.0 // indirect
+)
+
+replace github.com/andrew-d/canonlog => ../
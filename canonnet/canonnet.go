@@ -0,0 +1,238 @@
+// Package canonnet records network-level activity in canonical log lines:
+// DNS lookups, dial attempts and the bytes sent and received over dialed
+// connections, which often explain slow requests that application-level
+// attributes do not.
+//
+// To account for the outbound HTTP requests made while serving a request,
+// use a [Dialer] in the client's transport, wrapped in a [Transport], and
+// make requests with the request's context:
+//
+//	client := &http.Client{
+//		Transport: &canonnet.Transport{
+//			Base: &http.Transport{
+//				DialContext: (&canonnet.Dialer{}).DialContext,
+//			},
+//		},
+//	}
+package canonnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+func register[T int | int64 | time.Duration](key string) canonlog.Attr[T] {
+	return canonlog.RegisterWith(registry, key,
+		canonlog.WithMerge(func(old, new T) T { return old + new }),
+		canonlog.WithCommutativeMerge[T](),
+	)
+}
+
+// Attributes recorded by this package. All of them are summed over the
+// lifetime of the line.
+var (
+	AttrDNSLookups = register[int]("net.dns_lookups")
+	AttrDNSErrors  = register[int]("net.dns_errors")
+	AttrDNSTime    = register[time.Duration]("net.dns_time")
+
+	AttrDials      = register[int]("net.dials")
+	AttrDialErrors = register[int]("net.dial_errors")
+	AttrDialTime   = register[time.Duration]("net.dial_time")
+
+	AttrBytesSent     = register[int64]("net.bytes_sent")
+	AttrBytesReceived = register[int64]("net.bytes_received")
+)
+
+// Resolver wraps a [net.Resolver] to record each lookup, its duration, and
+// whether it failed in the [canonlog.Line] attached to the lookup's context.
+type Resolver struct {
+	// Resolver performs the lookups. If nil, [net.DefaultResolver] is
+	// used.
+	Resolver *net.Resolver
+}
+
+func (r *Resolver) resolver() *net.Resolver {
+	if r == nil || r.Resolver == nil {
+		return net.DefaultResolver
+	}
+	return r.Resolver
+}
+
+// LookupHost looks up the given host, like [net.Resolver.LookupHost].
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	start := time.Now()
+	addrs, err := r.resolver().LookupHost(ctx, host)
+	recordLookup(ctx, start, err)
+	return addrs, err
+}
+
+// LookupNetIP looks up host, like [net.Resolver.LookupNetIP].
+func (r *Resolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	start := time.Now()
+	addrs, err := r.resolver().LookupNetIP(ctx, network, host)
+	recordLookup(ctx, start, err)
+	return addrs, err
+}
+
+// recordLookup records a lookup that started at start and returned err in
+// the line attached to ctx.
+func recordLookup(ctx context.Context, start time.Time, err error) {
+	canonlog.Set(ctx, AttrDNSLookups, 1)
+	canonlog.Set(ctx, AttrDNSTime, time.Since(start))
+	if err != nil {
+		canonlog.Set(ctx, AttrDNSErrors, 1)
+	}
+}
+
+// Dialer wraps a [net.Dialer] to record DNS lookups, dial attempts and the
+// traffic over each dialed connection in the [canonlog.Line] attached to the
+// dial's context.
+//
+// Host names are resolved with the embedded Dialer's Resolver (wrapped as a
+// [Resolver]) and each resolved address is dialed in turn until one
+// succeeds, so that every attempt is counted. Bytes sent and received are
+// recorded in the line of the context the connection was dialed with, until
+// the connection is reused by a request made through a [Transport], from
+// which on they are recorded in that request's line. Bytes are not recorded
+// once the line has been emitted, such as while a connection dialed for a
+// finished request sits in the pool of an [http.Transport].
+type Dialer struct {
+	net.Dialer
+}
+
+// DialContext connects to the address on the named network, like
+// [net.Dialer.DialContext].
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	addrs, err := d.resolve(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, addr := range addrs {
+		start := time.Now()
+		conn, err := d.Dialer.DialContext(ctx, network, addr)
+		canonlog.Set(ctx, AttrDials, 1)
+		canonlog.Set(ctx, AttrDialTime, time.Since(start))
+		if err == nil {
+			c := &countingConn{Conn: conn}
+			c.line.Store(canonlog.FromContext(ctx))
+			return c, nil
+		}
+		canonlog.Set(ctx, AttrDialErrors, 1)
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// resolve returns the addresses to dial for address. Only the host names of
+// IP networks are resolved; other addresses are returned unchanged.
+func (d *Dialer) resolve(ctx context.Context, network, address string) ([]string, error) {
+	var ipNetwork string
+	switch network {
+	case "tcp", "udp":
+		ipNetwork = "ip"
+	case "tcp4", "udp4":
+		ipNetwork = "ip4"
+	case "tcp6", "udp6":
+		ipNetwork = "ip6"
+	default:
+		return []string{address}, nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		return []string{address}, nil
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return []string{address}, nil
+	}
+
+	r := &Resolver{Resolver: d.Dialer.Resolver}
+	ips, err := r.LookupNetIP(ctx, ipNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.JoinHostPort(ip.Unmap().String(), port)
+	}
+	return addrs, nil
+}
+
+// Transport is an [http.RoundTripper] that records the bytes sent and
+// received over connections dialed by a [Dialer] in the line of the request
+// using them, rather than in that of the request they were dialed for, so
+// that requests reusing a pooled connection are accounted for. Connections
+// of HTTP/2 requests are shared by concurrent requests, whose bytes are
+// recorded in the line of whichever request most recently obtained the
+// connection.
+type Transport struct {
+	// Base sends the requests. If nil, [http.DefaultTransport] is used;
+	// its connections are not dialed by a Dialer, so it is only useful
+	// with a Base whose DialContext is that of a Dialer.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	line := canonlog.FromContext(req.Context())
+	if line == nil {
+		return base.RoundTrip(req)
+	}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if c, ok := info.Conn.(*countingConn); ok {
+				c.line.Store(line)
+			}
+		},
+	}
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	return base.RoundTrip(req.WithContext(ctx))
+}
+
+// countingConn is a [net.Conn] that records the bytes read and written in
+// line, if it has one that has not been emitted.
+type countingConn struct {
+	net.Conn
+	line atomic.Pointer[canonlog.Line]
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if l := c.line.Load(); n > 0 && l != nil && !l.Emitted() {
+		canonlog.SetOn(l, AttrBytesReceived, int64(n))
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if l := c.line.Load(); n > 0 && l != nil && !l.Emitted() {
+		canonlog.SetOn(l, AttrBytesSent, int64(n))
+	}
+	return n, err
+}
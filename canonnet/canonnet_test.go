@@ -0,0 +1,170 @@
+package canonnet
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// lineValues returns the attributes of the line in ctx, keyed by name.
+func lineValues(ctx context.Context) map[string]any {
+	m := make(map[string]any)
+	for _, a := range canonlog.Attrs(ctx) {
+		m[a.Key] = a.Value.Any()
+	}
+	return m
+}
+
+// echoListener returns the address of a TCP listener that echoes back
+// everything written to it.
+func echoListener(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestDialer(t *testing.T) {
+	addr := echoListener(t)
+	_, port, _ := net.SplitHostPort(addr)
+
+	tests := []struct {
+		name        string
+		address     string
+		wantLookups any
+	}{
+		{"ip", addr, nil},
+		{"hostname", net.JoinHostPort("localhost", port), int64(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := canonlog.New(context.Background())
+			var d Dialer
+			conn, err := d.DialContext(ctx, "tcp4", tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if _, err := conn.Write([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.ReadFull(conn, make([]byte, 5)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := lineValues(ctx)
+			want := map[string]any{
+				"net.dns_lookups":    tt.wantLookups,
+				"net.dials":          int64(1),
+				"net.bytes_sent":     int64(5),
+				"net.bytes_received": int64(5),
+			}
+			for key, w := range want {
+				if got[key] != w {
+					t.Errorf("%s = %v, want %v", key, got[key], w)
+				}
+			}
+			if _, ok := got["net.dial_errors"]; ok {
+				t.Errorf("net.dial_errors = %v, want unset", got["net.dial_errors"])
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: &Transport{
+			Base: &http.Transport{DialContext: (&Dialer{}).DialContext},
+		},
+	}
+	before := canonlog.LateSets()
+	for i, wantDials := range []any{int64(1), nil} {
+		ctx := canonlog.New(context.Background())
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		// The second request reuses the connection dialed for the
+		// first, once that request's line has been emitted.
+		got := lineValues(ctx)
+		canonlog.Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+		if got["net.dials"] != wantDials {
+			t.Errorf("request %d: net.dials = %v, want %v", i, got["net.dials"], wantDials)
+		}
+		for _, key := range []string{"net.bytes_sent", "net.bytes_received"} {
+			if n, _ := got[key].(int64); n <= 0 {
+				t.Errorf("request %d: %s = %v, want > 0", i, key, got[key])
+			}
+		}
+	}
+	if n := canonlog.LateSets() - before; n != 0 {
+		t.Errorf("LateSets increased by %d, want 0", n)
+	}
+}
+
+func TestDialer_Error(t *testing.T) {
+	// Find a port with nothing listening on it.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx := canonlog.New(context.Background())
+	var d Dialer
+	if _, err := d.DialContext(ctx, "tcp", addr); err == nil {
+		t.Fatal("DialContext succeeded, want error")
+	}
+
+	got := lineValues(ctx)
+	if got["net.dials"] != int64(1) || got["net.dial_errors"] != int64(1) {
+		t.Errorf("net.dials = %v, net.dial_errors = %v, want 1 and 1", got["net.dials"], got["net.dial_errors"])
+	}
+}
+
+func TestResolver_Error(t *testing.T) {
+	ctx := canonlog.New(context.Background())
+	var r Resolver
+	if _, err := r.LookupHost(ctx, "does-not-exist.invalid"); err == nil {
+		t.Fatal("LookupHost succeeded, want error")
+	}
+
+	got := lineValues(ctx)
+	if got["net.dns_lookups"] != int64(1) || got["net.dns_errors"] != int64(1) {
+		t.Errorf("net.dns_lookups = %v, net.dns_errors = %v, want 1 and 1", got["net.dns_lookups"], got["net.dns_errors"])
+	}
+}
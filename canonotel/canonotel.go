@@ -0,0 +1,106 @@
+// Package canonotel connects canonical log lines with OpenTelemetry.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// OpenTelemetry do not depend on it.
+package canonotel
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/andrew-d/canonlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BaggageGroup is the group under which [Baggage] records baggage entries.
+const BaggageGroup = "baggage"
+
+// Baggage returns an enricher that copies the members of the OpenTelemetry
+// baggage in the context with the given keys into a [BaggageGroup] group,
+// so that context provided upstream, such as the tenant or experiment a
+// request belongs to, is included in the canonical line of every service
+// that handles it. Only the listed keys are copied, since baggage is
+// controlled by callers.
+//
+// Give it to [canonlog.WithLineEnricher] to copy the baggage when a line is
+// created, after propagation middleware has extracted it into the context:
+//
+//	mw := canonlog.Middleware(logger, canonlog.WithLineOptions(
+//		canonlog.WithLineEnricher(canonotel.Baggage("tenant", "experiment")),
+//	))
+func Baggage(keys ...string) canonlog.Enricher {
+	return canonlog.EnricherFunc(func(ctx context.Context) []slog.Attr {
+		bag := baggage.FromContext(ctx)
+		if bag.Len() == 0 {
+			return nil
+		}
+
+		var attrs []any
+		for _, key := range keys {
+			if m := bag.Member(key); m.Key() != "" {
+				attrs = append(attrs, slog.String(key, m.Value()))
+			}
+		}
+		if attrs == nil {
+			return nil
+		}
+		return []slog.Attr{slog.Group(BaggageGroup, attrs...)}
+	})
+}
+
+// TraceSampledKey is the key under which [TraceSampled] records whether the
+// request's trace is sampled.
+const TraceSampledKey = "trace_sampled"
+
+// TraceSampled returns an enricher that records, under [TraceSampledKey],
+// whether the OpenTelemetry span in the context belongs to a sampled trace,
+// so that lines whose trace was recorded can be found and joined with it.
+// Nothing is recorded if the context holds no valid span.
+//
+// Give it to [canonlog.WithEnricher], alongside [TraceSampler]:
+//
+//	mw := canonlog.Middleware(logger, canonlog.WithEmitterOptions(
+//		canonlog.WithSampler(canonotel.TraceSampler(nil)),
+//		canonlog.WithEnricher(canonotel.TraceSampled()),
+//	))
+func TraceSampled() canonlog.Enricher {
+	return canonlog.EnricherFunc(func(ctx context.Context) []slog.Attr {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return nil
+		}
+		return []slog.Attr{slog.Bool(TraceSampledKey, sc.IsSampled())}
+	})
+}
+
+// TraceSampleRule is the rule name of the sampling decisions made by
+// [TraceSampler] for lines whose trace is sampled.
+const TraceSampleRule = "trace"
+
+// TraceSampler returns a sampler that keeps every line whose OpenTelemetry
+// trace is sampled, so that each sampled trace has a canonical line to go
+// with it. The lines of other requests are sampled by fallback, or all kept
+// if it is nil.
+//
+// To keep logs and traces consistent, so that lines are emitted for exactly
+// the requests whose traces are recorded, give a fallback dropping every
+// line:
+//
+//	canonotel.TraceSampler(canonlog.RuleSampler(
+//		canonlog.SampleRule{Name: "untraced", Rate: 0},
+//	))
+//
+// Kept lines of sampled traces record a rate of 1, since the rate at which
+// traces are sampled is not known to the sampler.
+func TraceSampler(fallback canonlog.Sampler) canonlog.Sampler {
+	return canonlog.SamplerFunc(func(ctx context.Context, level slog.Level, attrs []slog.Attr) canonlog.SampleDecision {
+		if trace.SpanContextFromContext(ctx).IsSampled() {
+			return canonlog.SampleDecision{Keep: true, Rule: TraceSampleRule, Rate: 1}
+		}
+		if fallback == nil {
+			return canonlog.SampleDecision{Keep: true, Rate: 1}
+		}
+		return fallback.Sample(ctx, level, attrs)
+	})
+}
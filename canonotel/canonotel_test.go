@@ -0,0 +1,124 @@
+package canonotel
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestBaggage(t *testing.T) {
+	bag, err := baggage.Parse("tenant=acme,experiment=new-checkout,session=s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "allowlisted",
+			ctx:  baggage.ContextWithBaggage(context.Background(), bag),
+			want: "level=INFO msg=canonical-log-line baggage.tenant=acme baggage.experiment=new-checkout\n",
+		},
+		{
+			name: "no baggage",
+			ctx:  context.Background(),
+			want: "level=INFO msg=canonical-log-line\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := canonlog.New(tt.ctx, canonlog.WithLineEnricher(Baggage("tenant", "experiment", "missing")))
+
+			var buf bytes.Buffer
+			canonlog.Emit(ctx, testLogger(&buf), slog.LevelInfo)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("log output:\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// spanContext returns a context holding a remote span context, sampled or
+// not.
+func spanContext(sampled bool) context.Context {
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return trace.ContextWithSpanContext(context.Background(), sc)
+}
+
+func TestTraceSampling(t *testing.T) {
+	dropAll := canonlog.RuleSampler(canonlog.SampleRule{Name: "untraced", Rate: 0})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		fallback canonlog.Sampler
+		want     string
+	}{
+		{
+			name:     "sampled",
+			ctx:      spanContext(true),
+			fallback: dropAll,
+			want:     "level=INFO msg=canonical-log-line trace_sampled=true sample_rule=trace sample_rate=1\n",
+		},
+		{
+			name:     "not sampled",
+			ctx:      spanContext(false),
+			fallback: dropAll,
+			want:     "",
+		},
+		{
+			name: "not sampled, no fallback",
+			ctx:  spanContext(false),
+			want: "level=INFO msg=canonical-log-line trace_sampled=false sample_rule=\"\" sample_rate=1\n",
+		},
+		{
+			name: "no span",
+			ctx:  context.Background(),
+			want: "level=INFO msg=canonical-log-line sample_rule=\"\" sample_rate=1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := canonlog.NewEmitter(testLogger(&buf),
+				canonlog.WithSampler(TraceSampler(tt.fallback)),
+				canonlog.WithSampleAttrs(),
+				canonlog.WithEnricher(TraceSampled()),
+			)
+			e.Emit(canonlog.New(tt.ctx), slog.LevelInfo)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("log output:\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
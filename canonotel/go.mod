@@ -0,0 +1,13 @@
+module github.com/andrew-d/canonlog/canonotel
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	go.opentelemetry.io/otel v1.44.0
+	go.opentelemetry.io/otel/trace v1.44.0
+)
+
+require github.com/cespare/xxhash/v2 v2.3.0 // indirect
+
+replace github.com/andrew-d/canonlog => ../
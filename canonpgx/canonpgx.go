@@ -0,0 +1,88 @@
+// Package canonpgx records PostgreSQL queries made with pgx in canonical log
+// lines.
+//
+// Configure a [Tracer] as the connection's query tracer, and every query
+// made with a context carrying a [canonlog.Line] is recorded in it:
+//
+//	cfg, err := pgxpool.ParseConfig(dsn)
+//	...
+//	cfg.ConnConfig.Tracer = &canonpgx.Tracer{}
+//
+// pgx allows a single tracer per connection; to use Tracer alongside
+// another, such as one for distributed tracing, combine them with the
+// multitracer package.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// pgx do not depend on it.
+package canonpgx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/jackc/pgx/v5"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+func sum[T int | time.Duration](old, new T) T { return old + new }
+
+// Attributes recorded by this package.
+var (
+	// AttrQueries is the number of queries made.
+	AttrQueries = canonlog.RegisterWith(registry, "db_queries",
+		canonlog.WithMerge(sum[int]), canonlog.WithCommutativeMerge[int]())
+
+	// AttrErrors is the number of queries that failed. A query returning
+	// no rows where one was expected is not counted as failed.
+	AttrErrors = canonlog.RegisterWith(registry, "db_query_errors",
+		canonlog.WithMerge(sum[int]), canonlog.WithCommutativeMerge[int]())
+
+	// AttrTime is the total duration of all queries.
+	AttrTime = canonlog.RegisterWith(registry, "db_query_time",
+		canonlog.WithMerge(sum[time.Duration]), canonlog.WithCommutativeMerge[time.Duration]())
+
+	// AttrMaxTime is the duration of the slowest query.
+	AttrMaxTime = canonlog.RegisterWith(registry, "db_query_max_time",
+		canonlog.WithMerge(func(old, new time.Duration) time.Duration { return max(old, new) }),
+		canonlog.WithCommutativeMerge[time.Duration]())
+)
+
+// Tracer is a [pgx.QueryTracer] that records each query in the
+// [canonlog.Line] attached to the query's context. The zero value is ready
+// to use.
+type Tracer struct{}
+
+var _ pgx.QueryTracer = (*Tracer)(nil)
+
+// startKey is the context key for storing the start time of a query.
+type startKey struct{}
+
+// TraceQueryStart implements [pgx.QueryTracer].
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if canonlog.FromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, startKey{}, time.Now())
+}
+
+// TraceQueryEnd implements [pgx.QueryTracer].
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	d := time.Since(start)
+
+	canonlog.Set(ctx, AttrQueries, 1)
+	canonlog.Set(ctx, AttrTime, d)
+	canonlog.Set(ctx, AttrMaxTime, d)
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		canonlog.Set(ctx, AttrErrors, 1)
+	}
+}
@@ -0,0 +1,61 @@
+package canonpgx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/jackc/pgx/v5"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestTracer(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		tracer := &Tracer{}
+		ctx := canonlog.New(context.Background())
+
+		query := func(d time.Duration, err error) {
+			qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+			time.Sleep(d)
+			tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: err})
+		}
+		query(10*time.Millisecond, nil)
+		query(30*time.Millisecond, errors.New("deadlock detected"))
+		query(5*time.Millisecond, pgx.ErrNoRows)
+
+		var buf bytes.Buffer
+		canonlog.Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+		want := "level=INFO msg=canonical-log-line db_queries=3 db_query_time=45ms db_query_max_time=30ms db_query_errors=1\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestTracer_WithoutLine(t *testing.T) {
+	tracer := &Tracer{}
+	ctx := context.Background()
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{})
+	if qctx != ctx {
+		t.Error("TraceQueryStart changed a context without a Line")
+	}
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{}) // must not panic
+}
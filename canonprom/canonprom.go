@@ -0,0 +1,62 @@
+// Package canonprom exports canonlog's own metrics, as returned by
+// [canonlog.ReadMetrics], to Prometheus, so that operators can be alerted
+// when the pipeline producing canonical log lines is unhealthy:
+//
+//	prometheus.MustRegister(canonprom.NewCollector())
+//
+// It also records values from canonical lines in histograms with
+// [Histogram], with exemplars linking each observation to its line.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// Prometheus do not depend on it.
+package canonprom
+
+import (
+	"github.com/andrew-d/canonlog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	linesEmittedDesc = prometheus.NewDesc("canonlog_lines_emitted_total",
+		"Canonical log lines logged by emitters.", nil, nil)
+	linesSampledOutDesc = prometheus.NewDesc("canonlog_lines_sampled_out_total",
+		"Canonical log lines dropped by the sampler of an emitter.", nil, nil)
+	lateSetsDesc = prometheus.NewDesc("canonlog_late_sets_total",
+		"Attempts to set attributes on canonical log lines after they were emitted.", nil, nil)
+	cappedSetsDesc = prometheus.NewDesc("canonlog_capped_sets_total",
+		"Attribute values dropped for exceeding the size limit of their line.", nil, nil)
+	invalidValuesDesc = prometheus.NewDesc("canonlog_invalid_values_total",
+		"Attribute values rejected by the validator of their attribute.", nil, nil)
+)
+
+// NewCollector returns a [prometheus.Collector] exporting the metrics
+// returned by [canonlog.ReadMetrics] as counters.
+func NewCollector() prometheus.Collector {
+	return collector{}
+}
+
+type collector struct{}
+
+func (collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- linesEmittedDesc
+	ch <- linesSampledOutDesc
+	ch <- lateSetsDesc
+	ch <- cappedSetsDesc
+	ch <- invalidValuesDesc
+}
+
+func (collector) Collect(ch chan<- prometheus.Metric) {
+	m := canonlog.ReadMetrics()
+	for _, c := range []struct {
+		desc  *prometheus.Desc
+		value uint64
+	}{
+		{linesEmittedDesc, m.LinesEmitted},
+		{linesSampledOutDesc, m.LinesSampledOut},
+		{lateSetsDesc, m.LateSets},
+		{cappedSetsDesc, m.CappedSets},
+		{invalidValuesDesc, m.InvalidValues},
+	} {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(c.value))
+	}
+}
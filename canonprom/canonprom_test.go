@@ -0,0 +1,36 @@
+package canonprom
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := canonlog.New(context.Background())
+	canonlog.Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector())
+
+	want := `
+# HELP canonlog_lines_emitted_total Canonical log lines logged by emitters.
+# TYPE canonlog_lines_emitted_total counter
+canonlog_lines_emitted_total 1
+# HELP canonlog_late_sets_total Attempts to set attributes on canonical log lines after they were emitted.
+# TYPE canonlog_late_sets_total counter
+canonlog_late_sets_total 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"canonlog_lines_emitted_total", "canonlog_late_sets_total"); err != nil {
+		t.Error(err)
+	}
+	if n, err := testutil.GatherAndCount(reg); err != nil || n != 5 {
+		t.Errorf("gathered %d metrics (error %v), want 5", n, err)
+	}
+}
@@ -0,0 +1,115 @@
+package canonprom
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// exemplarKeys are the keys of the attributes that [Histogram] copies into
+// the exemplars of its observations, in order of preference.
+var exemplarKeys = []string{"trace_id", "request_id"}
+
+// Histogram returns a hook that observes, in h, the value of the attribute
+// with the given key of every line it is run for, so that the distribution
+// of a value recorded in canonical lines, such as request latency, is
+// available as a metric without recording it twice:
+//
+//	latency := prometheus.NewHistogram(prometheus.HistogramOpts{
+//		Name: "http_request_duration_seconds",
+//	})
+//	canonlog.DefaultRegistry.OnLogged(canonprom.Histogram(latency, "duration"))
+//
+// As a [canonlog.LoggedHook], it observes each line once, when it is first
+// logged, and not lines dropped by sampling, so that every exemplar points
+// at a line that was logged.
+//
+// Each observation carries an exemplar with the line's trace ID and request
+// ID, as recorded under the "trace_id" and "request_id" keys by
+// [canonlog.WithTraceparent] and [canonlog.WithRequestID], if it has them,
+// so that dashboards can jump from a latency bucket to the exact line; the
+// request ID is left out if it would exceed Prometheus' limit on the size of
+// exemplars. Exemplars are only attached if h implements
+// [prometheus.ExemplarObserver], as histograms do.
+//
+// The key of an attribute in a group, such as one registered
+// [canonlog.WithGroup], is qualified by the group's, as in "db.time".
+// Durations are observed in seconds, and numbers as they are; lines without
+// the attribute, or on which it holds another kind of value, are not
+// observed.
+func Histogram(h prometheus.Observer, key string) canonlog.LoggedHook {
+	return func(_ context.Context, _ slog.Level, attrs []slog.Attr) {
+		v, ok := lookup(attrs, key)
+		if !ok {
+			return
+		}
+		var f float64
+		switch v.Kind() {
+		case slog.KindDuration:
+			f = v.Duration().Seconds()
+		case slog.KindInt64:
+			f = float64(v.Int64())
+		case slog.KindUint64:
+			f = float64(v.Uint64())
+		case slog.KindFloat64:
+			f = v.Float64()
+		default:
+			return
+		}
+
+		eo, ok := h.(prometheus.ExemplarObserver)
+		if !ok {
+			h.Observe(f)
+			return
+		}
+		if labels := exemplar(attrs); labels != nil {
+			eo.ObserveWithExemplar(f, labels)
+		} else {
+			h.Observe(f)
+		}
+	}
+}
+
+// lookup returns the resolved value of the attribute of attrs with the
+// given key, qualified by the keys of the groups it is in.
+func lookup(attrs []slog.Attr, key string) (slog.Value, bool) {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.Resolve(), true
+		}
+		if rest, ok := strings.CutPrefix(key, a.Key+"."); ok {
+			if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+				return lookup(v.Group(), rest)
+			}
+		}
+	}
+	return slog.Value{}, false
+}
+
+// exemplar returns the labels of the exemplar of an observation from a line
+// with the given attributes, or nil if it has none of exemplarKeys.
+func exemplar(attrs []slog.Attr) prometheus.Labels {
+	var labels prometheus.Labels
+	var runes int
+	for _, key := range exemplarKeys {
+		v, ok := lookup(attrs, key)
+		if !ok {
+			continue
+		}
+		s := v.String()
+		n := utf8.RuneCountInString(key) + utf8.RuneCountInString(s)
+		if runes+n > prometheus.ExemplarMaxRunes || !utf8.ValidString(s) {
+			continue
+		}
+		if labels == nil {
+			labels = make(prometheus.Labels, len(exemplarKeys))
+		}
+		labels[key] = s
+		runes += n
+	}
+	return labels
+}
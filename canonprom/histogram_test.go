@@ -0,0 +1,94 @@
+package canonprom
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestHistogram(t *testing.T) {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "test_duration_seconds",
+		Buckets: []float64{0.1, 1},
+	})
+	hook := Histogram(h, "db.time")
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	for _, attrs := range [][]slog.Attr{
+		{
+			slog.Group("db", slog.Duration("time", 50*time.Millisecond)),
+			slog.String("trace_id", traceID),
+			slog.String("request_id", strings.Repeat("x", 100)), // too long
+		},
+		{slog.Group("db", slog.Duration("time", 2*time.Second))},
+		{slog.Group("db", slog.String("time", "slow"))}, // not observed
+		{slog.String("user", "usr_123")},                // not observed
+	} {
+		hook(context.Background(), slog.LevelInfo, attrs)
+	}
+
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	hist := m.GetHistogram()
+	if got := hist.GetSampleCount(); got != 2 {
+		t.Errorf("sample count = %d, want 2", got)
+	}
+	if got, want := hist.GetSampleSum(), 2.05; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("sample sum = %v, want %v", got, want)
+	}
+	ex := hist.GetBucket()[0].GetExemplar()
+	if ex == nil {
+		t.Fatal("first bucket has no exemplar")
+	}
+	if labels := ex.GetLabel(); len(labels) != 1 || labels[0].GetName() != "trace_id" || labels[0].GetValue() != traceID {
+		t.Errorf("exemplar labels = %v, want trace_id=%s", labels, traceID)
+	}
+	if ex := hist.GetBucket()[1].GetExemplar(); ex != nil {
+		t.Errorf("second bucket has exemplar %v, want none", ex)
+	}
+}
+
+func TestHistogram_OnLogged(t *testing.T) {
+	discard := slog.New(slog.DiscardHandler)
+	drop := canonlog.WithSampler(canonlog.SamplerFunc(func(context.Context, slog.Level, []slog.Attr) canonlog.SampleDecision {
+		return canonlog.SampleDecision{}
+	}))
+	tests := []struct {
+		name      string
+		emitter   *canonlog.Emitter
+		wantCount uint64
+	}{
+		{"kept", canonlog.NewEmitter(discard), 1},
+		{"sampled_out", canonlog.NewEmitter(discard, drop), 0},
+		{"tee", canonlog.NewEmitter(discard, canonlog.WithTee(canonlog.NewEmitter(discard))), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_latency_seconds"})
+			r := canonlog.NewRegistry()
+			attrLatency := canonlog.RegisterWith[time.Duration](r, "latency")
+			r.OnLogged(Histogram(h, "latency"))
+
+			ctx := canonlog.New(context.Background(), canonlog.WithRegistry(r))
+			canonlog.Set(ctx, attrLatency, 250*time.Millisecond)
+			tt.emitter.Emit(ctx, slog.LevelInfo)
+			tt.emitter.Emit(ctx, slog.LevelInfo) // emitted again: not observed again
+
+			var m dto.Metric
+			if err := h.Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.GetHistogram().GetSampleCount(); got != tt.wantCount {
+				t.Errorf("sample count = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Package canonasynq provides asynq server middleware that emits a
+// canonical log line for every task it processes:
+//
+//	mux := asynq.NewServeMux()
+//	mux.Use(canonasynq.Middleware())
+//	mux.HandleFunc("email:welcome", sendWelcomeEmail)
+//
+// Each line records the task's type, its queue and which attempt it is, as
+// described for [canonqueue.Wrap]. asynq does not expose when a task was
+// enqueued to its handlers, so no message age is recorded.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// asynq do not depend on it.
+package canonasynq
+
+import (
+	"context"
+
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/hibiken/asynq"
+)
+
+// Describe describes the task t, being processed with the context ctx
+// given to its handler.
+func Describe(ctx context.Context, t *asynq.Task) canonqueue.Info {
+	info := canonqueue.Info{Type: t.Type()}
+	info.Queue, _ = asynq.GetQueueName(ctx)
+	if retried, ok := asynq.GetRetryCount(ctx); ok {
+		info.Attempt = retried + 1
+	}
+	return info
+}
+
+// delivery is a task together with its description, which [Describe] can
+// only produce from the handler's context.
+type delivery struct {
+	task *asynq.Task
+	info canonqueue.Info
+}
+
+// Middleware returns asynq middleware that processes each task with a
+// context carrying a new [canonlog.Line], as described for
+// [canonqueue.Wrap].
+func Middleware(opts ...canonqueue.Option) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		handle := canonqueue.Wrap(
+			func(d delivery) canonqueue.Info { return d.info },
+			func(ctx context.Context, d delivery) error { return next.ProcessTask(ctx, d.task) },
+			opts...,
+		)
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			return handle(ctx, delivery{task: t, info: Describe(ctx, t)})
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package canonasynq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/hibiken/asynq"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == "duration") {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	h := Middleware(canonqueue.WithEmitter(canonlog.NewEmitter(logger)))(
+		asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			if canonlog.FromContext(ctx) == nil {
+				t.Error("handler context does not have a Line")
+			}
+			return errors.New("smtp unavailable")
+		}),
+	)
+
+	// Outside of an asynq server the task's queue and retry count are not
+	// in the context, so only its type is recorded.
+	h.ProcessTask(context.Background(), asynq.NewTask("email:welcome", nil))
+
+	want := "level=ERROR msg=canonical-log-line msg_type=email:welcome outcome=error error=\"smtp unavailable\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+// Package canonkafka adapts canonqueue to Kafka consumers using franz-go.
+//
+//	handle := canonkafka.Wrap(processOrder)
+//	for {
+//		fetches := client.PollFetches(ctx)
+//		...
+//		fetches.EachRecord(func(r *kgo.Record) {
+//			handle(ctx, r)
+//		})
+//	}
+//
+// The queue is recorded as the record's topic, and the message age is
+// measured from the record's timestamp. Kafka does not track deliveries, so
+// no attempt number is recorded.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// Kafka do not depend on franz-go.
+package canonkafka
+
+import (
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Describe describes a Kafka record, for use with [canonqueue.Wrap].
+func Describe(r *kgo.Record) canonqueue.Info {
+	return canonqueue.Info{Queue: r.Topic, EnqueuedAt: r.Timestamp}
+}
+
+// Wrap is like [canonqueue.Wrap] for Kafka records.
+func Wrap(handler canonqueue.Handler[*kgo.Record], opts ...canonqueue.Option) canonqueue.Handler[*kgo.Record] {
+	return canonqueue.Wrap(Describe, handler, opts...)
+}
@@ -0,0 +1,42 @@
+package canonkafka
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestWrap(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+
+		handle := Wrap(func(ctx context.Context, r *kgo.Record) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		}, canonqueue.WithEmitter(canonlog.NewEmitter(logger)))
+
+		r := &kgo.Record{Topic: "orders", Timestamp: time.Now()}
+		time.Sleep(3 * time.Second)
+		handle(context.Background(), r)
+
+		want := "level=INFO msg=canonical-log-line queue=orders msg_age=3s duration=5ms outcome=success\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
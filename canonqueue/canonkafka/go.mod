@@ -0,0 +1,17 @@
+module github.com/andrew-d/canonlog/canonqueue/canonkafka
+
+go 1.25.3
+
+require github.com/andrew-d/canonlog v0.0.0
+
+require (
+	github.com/twmb/franz-go v1.17.0
+	github.com/twmb/franz-go/pkg/kmsg v1.8.0 // indirect
+)
+
+require (
+	github.com/klauspost/compress v1.17.8 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+)
+
+replace github.com/andrew-d/canonlog => ../../
@@ -0,0 +1,123 @@
+// Package canonqueue emits canonical log lines for message queue consumers,
+// the same way [canonlog.Middleware] does for HTTP requests.
+//
+// Wrap a message handler so that every message it processes gets its own
+// [canonlog.Line], recording the queue, the age of the message, which
+// delivery attempt it is, and how long processing took:
+//
+//	handle := canonqueue.Wrap(describe, func(ctx context.Context, msg Job) error {
+//		...
+//	})
+//
+// Adapters for specific queue clients, which know how to describe their
+// messages, are provided by the canonkafka and canonsqs packages, and
+// middleware for the asynq and River job queues by the canonasynq and
+// canonriver packages. Each is a separate module.
+package canonqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package.
+var (
+	AttrQueue    = canonlog.RegisterWith[string](registry, "queue")
+	AttrType     = canonlog.RegisterWith[string](registry, "msg_type")
+	AttrAge      = canonlog.RegisterWith[time.Duration](registry, "msg_age")
+	AttrAttempt  = canonlog.RegisterWith[int](registry, "attempt")
+	AttrDuration = canonlog.RegisterWith[time.Duration](registry, "duration")
+)
+
+// Info describes a message, as returned by the describe function given to
+// [Wrap]. Zero fields are not recorded.
+type Info struct {
+	// Queue is the name of the queue, topic or subscription the message
+	// was received from.
+	Queue string
+
+	// Type is the type of the message, if the queue distinguishes types
+	// of message, such as the kind of job for a job queue.
+	Type string
+
+	// EnqueuedAt is when the message was sent, if known. The age of the
+	// message when processing started is recorded under the "msg_age"
+	// key.
+	EnqueuedAt time.Time
+
+	// Attempt is the number of times the message has been delivered,
+	// including this delivery, if known.
+	Attempt int
+}
+
+// Handler processes a message of type M.
+type Handler[M any] func(ctx context.Context, msg M) error
+
+// Option configures a handler returned by [Wrap].
+type Option func(*config)
+
+// config holds the configuration of a handler returned by [Wrap].
+type config struct {
+	emitter *canonlog.Emitter
+}
+
+// WithEmitter makes the handler emit its lines with e, instead of an
+// [canonlog.Emitter] logging to [slog.Default].
+func WithEmitter(e *canonlog.Emitter) Option {
+	return func(cfg *config) {
+		cfg.emitter = e
+	}
+}
+
+// Wrap returns a handler that processes each message with handler, with a
+// context carrying a new [canonlog.Line] that is emitted once handler
+// returns. The line records the queue name, message type, message age and
+// attempt number from describe, and the processing duration, with the level
+// and outcome derived from handler's error as described for
+// [canonlog.Emitter.EmitOnReturn]. If handler panics, the line is emitted
+// with the panic as its error and the panic is then resumed.
+func Wrap[M any](describe func(M) Info, handler Handler[M], opts ...Option) Handler[M] {
+	cfg := config{emitter: canonlog.NewEmitter(nil)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(ctx context.Context, msg M) (err error) {
+		start := time.Now()
+		ctx = canonlog.New(ctx)
+
+		info := describe(msg)
+		if info.Queue != "" {
+			canonlog.Set(ctx, AttrQueue, info.Queue)
+		}
+		if info.Type != "" {
+			canonlog.Set(ctx, AttrType, info.Type)
+		}
+		if !info.EnqueuedAt.IsZero() {
+			canonlog.Set(ctx, AttrAge, start.Sub(info.EnqueuedAt))
+		}
+		if info.Attempt > 0 {
+			canonlog.Set(ctx, AttrAttempt, info.Attempt)
+		}
+
+		defer func() {
+			canonlog.Set(ctx, AttrDuration, time.Since(start))
+			if p := recover(); p != nil {
+				err := fmt.Errorf("panic: %v", p)
+				cfg.emitter.EmitOnReturn(ctx, &err)()
+				panic(p)
+			}
+			cfg.emitter.EmitOnReturn(ctx, &err)()
+		}()
+
+		return handler(ctx, msg)
+	}
+}
@@ -0,0 +1,81 @@
+package canonqueue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+type job struct {
+	id       string
+	sentAt   time.Time
+	attempts int
+}
+
+func describe(j job) Info {
+	return Info{Queue: "emails", Type: "welcome", EnqueuedAt: j.sentAt, Attempt: j.attempts}
+}
+
+var attrJobID = canonlog.RegisterWith[string](canonlog.NewRegistry(), "job_id")
+
+func TestWrap(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		handle := Wrap(describe, func(ctx context.Context, j job) error {
+			canonlog.Set(ctx, attrJobID, j.id)
+			time.Sleep(250 * time.Millisecond)
+			if j.attempts < 3 {
+				return errors.New("smtp unavailable")
+			}
+			return nil
+		}, WithEmitter(canonlog.NewEmitter(testLogger(&buf))))
+
+		sentAt := time.Now()
+		time.Sleep(2 * time.Second)
+		handle(context.Background(), job{id: "job_1", sentAt: sentAt, attempts: 2})
+		handle(context.Background(), job{id: "job_2", attempts: 3})
+
+		want := "level=ERROR msg=canonical-log-line queue=emails msg_type=welcome msg_age=2s attempt=2 job_id=job_1 duration=250ms outcome=error error=\"smtp unavailable\"\n" +
+			"level=INFO msg=canonical-log-line queue=emails msg_type=welcome attempt=3 job_id=job_2 duration=250ms outcome=success\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestWrap_Panic(t *testing.T) {
+	var buf bytes.Buffer
+	handle := Wrap(describe, func(ctx context.Context, j job) error {
+		panic("nil template")
+	}, WithEmitter(canonlog.NewEmitter(testLogger(&buf))))
+
+	defer func() {
+		if p := recover(); p != "nil template" {
+			t.Errorf("recovered %v, want the handler's panic", p)
+		}
+		if got := buf.String(); !strings.Contains(got, `outcome=error error="panic: nil template"`) {
+			t.Errorf("log output = %q, want panic recorded as error", got)
+		}
+	}()
+	handle(context.Background(), job{id: "job_3"})
+}
@@ -0,0 +1,68 @@
+// Package canonriver provides River worker middleware that emits a
+// canonical log line for every job it works:
+//
+//	client, err := river.NewClient(riverpgxv5.New(pool), &river.Config{
+//		Middleware: []rivertype.Middleware{canonriver.NewMiddleware()},
+//		...
+//	})
+//
+// Each line records the job's kind, its queue, how long after it was
+// scheduled it started, and which attempt it is, as described for
+// [canonqueue.Wrap].
+//
+// It is a separate module from canonlog, so that programs that do not use
+// River do not depend on it.
+package canonriver
+
+import (
+	"context"
+
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/riverqueue/river/rivertype"
+)
+
+// Describe describes a River job, for use with [canonqueue.Wrap]. Its age
+// is measured from when it was scheduled to run, rather than when it was
+// inserted, so that jobs scheduled for the future do not appear stale.
+func Describe(job *rivertype.JobRow) canonqueue.Info {
+	return canonqueue.Info{
+		Queue:      job.Queue,
+		Type:       job.Kind,
+		EnqueuedAt: job.ScheduledAt,
+		Attempt:    job.Attempt,
+	}
+}
+
+// delivery is a job together with the function that works it.
+type delivery struct {
+	job     *rivertype.JobRow
+	doInner func(context.Context) error
+}
+
+// Middleware is a [rivertype.WorkerMiddleware] that works each job with a
+// context carrying a new [canonlog.Line], as described for
+// [canonqueue.Wrap]. Create one with [NewMiddleware].
+type Middleware struct {
+	handle canonqueue.Handler[delivery]
+}
+
+var _ rivertype.WorkerMiddleware = (*Middleware)(nil)
+
+// NewMiddleware returns a new [Middleware] configured with opts.
+func NewMiddleware(opts ...canonqueue.Option) *Middleware {
+	return &Middleware{
+		handle: canonqueue.Wrap(
+			func(d delivery) canonqueue.Info { return Describe(d.job) },
+			func(ctx context.Context, d delivery) error { return d.doInner(ctx) },
+			opts...,
+		),
+	}
+}
+
+// IsMiddleware implements [rivertype.Middleware].
+func (m *Middleware) IsMiddleware() bool { return true }
+
+// Work implements [rivertype.WorkerMiddleware].
+func (m *Middleware) Work(ctx context.Context, job *rivertype.JobRow, doInner func(context.Context) error) error {
+	return m.handle(ctx, delivery{job: job, doInner: doInner})
+}
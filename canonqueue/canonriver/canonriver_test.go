@@ -0,0 +1,49 @@
+package canonriver
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/riverqueue/river/rivertype"
+)
+
+func TestMiddleware(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+		m := NewMiddleware(canonqueue.WithEmitter(canonlog.NewEmitter(logger)))
+
+		job := &rivertype.JobRow{
+			Kind:        "sort_items",
+			Queue:       "default",
+			Attempt:     2,
+			ScheduledAt: time.Now(),
+		}
+		time.Sleep(time.Second)
+		m.Work(context.Background(), job, func(ctx context.Context) error {
+			if canonlog.FromContext(ctx) == nil {
+				t.Error("worker context does not have a Line")
+			}
+			time.Sleep(40 * time.Millisecond)
+			return nil
+		})
+
+		want := "level=INFO msg=canonical-log-line queue=default msg_type=sort_items msg_age=1s attempt=2 duration=40ms outcome=success\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
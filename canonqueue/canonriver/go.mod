@@ -0,0 +1,10 @@
+module github.com/andrew-d/canonlog/canonqueue/canonriver
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	github.com/riverqueue/river/rivertype v0.39.0
+)
+
+replace github.com/andrew-d/canonlog => ../../
@@ -0,0 +1,54 @@
+// Package canonsqs adapts canonqueue to Amazon SQS consumers using the AWS
+// SDK for Go v2.
+//
+// The message's age and attempt number are only known if the
+// SentTimestamp and ApproximateReceiveCount system attributes were
+// requested when receiving it:
+//
+//	out, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+//		QueueUrl: &queueURL,
+//		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+//			types.MessageSystemAttributeNameSentTimestamp,
+//			types.MessageSystemAttributeNameApproximateReceiveCount,
+//		},
+//	})
+//	...
+//	handle := canonsqs.Wrap("emails", processEmail)
+//	for _, msg := range out.Messages {
+//		err := handle(ctx, msg)
+//		...
+//	}
+//
+// It is a separate module from canonlog, so that programs that do not use
+// SQS do not depend on the AWS SDK.
+package canonsqs
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Describe returns a function describing messages received from the queue
+// with the given name, for use with [canonqueue.Wrap].
+func Describe(queue string) func(types.Message) canonqueue.Info {
+	return func(msg types.Message) canonqueue.Info {
+		info := canonqueue.Info{Queue: queue}
+		attrs := msg.Attributes
+		if ms, err := strconv.ParseInt(attrs[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+			info.EnqueuedAt = time.UnixMilli(ms)
+		}
+		if n, err := strconv.Atoi(attrs[string(types.MessageSystemAttributeNameApproximateReceiveCount)]); err == nil {
+			info.Attempt = n
+		}
+		return info
+	}
+}
+
+// Wrap is like [canonqueue.Wrap] for messages received from the SQS queue
+// with the given name.
+func Wrap(queue string, handler canonqueue.Handler[types.Message], opts ...canonqueue.Option) canonqueue.Handler[types.Message] {
+	return canonqueue.Wrap(Describe(queue), handler, opts...)
+}
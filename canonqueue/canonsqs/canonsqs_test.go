@@ -0,0 +1,38 @@
+package canonsqs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrew-d/canonlog/canonqueue"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  canonqueue.Info
+	}{
+		{
+			name: "system attributes",
+			attrs: map[string]string{
+				"SentTimestamp":           "1760000000123",
+				"ApproximateReceiveCount": "3",
+			},
+			want: canonqueue.Info{Queue: "emails", EnqueuedAt: time.UnixMilli(1760000000123), Attempt: 3},
+		},
+		{
+			name: "not requested",
+			want: canonqueue.Info{Queue: "emails"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Describe("emails")(types.Message{Attributes: tt.attrs})
+			if got != tt.want {
+				t.Errorf("Describe = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/andrew-d/canonlog/canonqueue/canonsqs
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.42.21
+)
+
+require github.com/aws/smithy-go v1.24.0 // indirect
+
+replace github.com/andrew-d/canonlog => ../../
@@ -0,0 +1,108 @@
+// Package canontwirp emits canonical log lines for Twirp services.
+//
+// Install the server hooks so that every request gets its own
+// [canonlog.Line], emitted when the response has been sent:
+//
+//	handler := haberdasher.NewHaberdasherServer(svc,
+//		twirp.WithServerHooks(canontwirp.ServerHooks(logger)),
+//	)
+//
+// Use [twirp.ChainHooks] to install them alongside other hooks.
+//
+// It is a separate module from canonlog, so that programs that do not use
+// Twirp do not depend on it.
+package canontwirp
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/twitchtv/twirp"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+// Attributes recorded by this package.
+var (
+	AttrService    = canonlog.RegisterWith[string](registry, "twirp_service")
+	AttrMethod     = canonlog.RegisterWith[string](registry, "twirp_method")
+	AttrCode       = canonlog.RegisterWith[string](registry, "twirp_code")
+	AttrHTTPStatus = canonlog.RegisterWith[int](registry, "http_status")
+	AttrDuration   = canonlog.RegisterWith[time.Duration](registry, "duration")
+)
+
+// stateKey is the context key under which the [state] of a request is
+// stored.
+type stateKey struct{}
+
+// state holds what the hooks record about a request until it is emitted.
+type state struct {
+	start time.Time
+	code  twirp.ErrorCode // if the request failed
+}
+
+// ServerHooks returns Twirp server hooks that attach a new [canonlog.Line]
+// to the context of every request, record the request's service (qualified
+// by its package, as in "example.Haberdasher"), method, error code ("ok"
+// for successful requests), HTTP status and duration, and emit the line to
+// logger once the response has been sent. Requests failing with a code
+// indicating a server problem, such as internal or unavailable, are emitted
+// at [slog.LevelError], and all others at [slog.LevelInfo].
+//
+// If logger is nil, [slog.Default] is used.
+func ServerHooks(logger *slog.Logger) *twirp.ServerHooks {
+	return &twirp.ServerHooks{
+		RequestReceived: func(ctx context.Context) (context.Context, error) {
+			ctx = context.WithValue(canonlog.New(ctx), stateKey{}, &state{start: time.Now()})
+			service, _ := twirp.ServiceName(ctx)
+			if pkg, ok := twirp.PackageName(ctx); ok && pkg != "" {
+				service = pkg + "." + service
+			}
+			canonlog.Set(ctx, AttrService, service)
+			return ctx, nil
+		},
+		RequestRouted: func(ctx context.Context) (context.Context, error) {
+			if method, ok := twirp.MethodName(ctx); ok {
+				canonlog.Set(ctx, AttrMethod, method)
+			}
+			return ctx, nil
+		},
+		Error: func(ctx context.Context, err twirp.Error) context.Context {
+			if st, ok := ctx.Value(stateKey{}).(*state); ok {
+				st.code = err.Code()
+			}
+			return ctx
+		},
+		ResponseSent: func(ctx context.Context) {
+			st, ok := ctx.Value(stateKey{}).(*state)
+			if !ok {
+				return // not received by these hooks
+			}
+
+			code := "ok"
+			level := slog.LevelInfo
+			if st.code != twirp.NoError {
+				code = string(st.code)
+				switch st.code {
+				case twirp.Unknown, twirp.DeadlineExceeded, twirp.Unimplemented,
+					twirp.Internal, twirp.Unavailable, twirp.DataLoss:
+					level = slog.LevelError
+				}
+			}
+			canonlog.Set(ctx, AttrCode, code)
+			if s, ok := twirp.StatusCode(ctx); ok {
+				if status, err := strconv.Atoi(s); err == nil {
+					canonlog.Set(ctx, AttrHTTPStatus, status)
+				}
+			}
+			canonlog.Set(ctx, AttrDuration, time.Since(st.start))
+			canonlog.Emit(ctx, logger, level)
+		},
+	}
+}
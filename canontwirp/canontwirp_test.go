@@ -0,0 +1,71 @@
+package canontwirp
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+var attrUser = canonlog.Register[string]("user")
+
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+// serve calls hooks as a generated Twirp server does for a request to the
+// MakeHat method of the example.Haberdasher service, with the handler
+// returning err.
+func serve(hooks *twirp.ServerHooks, err twirp.Error) {
+	ctx := context.Background()
+	ctx = ctxsetters.WithPackageName(ctx, "example")
+	ctx = ctxsetters.WithServiceName(ctx, "Haberdasher")
+	ctx, _ = hooks.RequestReceived(ctx)
+	ctx = ctxsetters.WithMethodName(ctx, "MakeHat")
+	ctx, _ = hooks.RequestRouted(ctx)
+
+	canonlog.Set(ctx, attrUser, "ann") // in the handler
+	if err != nil {
+		ctx = ctxsetters.WithStatusCode(ctx, twirp.ServerHTTPStatusFromErrorCode(err.Code()))
+		ctx = hooks.Error(ctx, err)
+	} else {
+		ctx = ctxsetters.WithStatusCode(ctx, 200)
+		if hooks.ResponsePrepared != nil {
+			ctx = hooks.ResponsePrepared(ctx)
+		}
+	}
+	hooks.ResponseSent(ctx)
+}
+
+func TestServerHooks(t *testing.T) {
+	tests := []struct {
+		name string
+		err  twirp.Error
+		want string // log output, without the duration
+	}{
+		{"ok", nil, "level=INFO msg=canonical-log-line twirp_service=example.Haberdasher twirp_method=MakeHat user=ann twirp_code=ok http_status=200"},
+		{"invalid", twirp.InvalidArgumentError("size", "must be positive"), "level=INFO msg=canonical-log-line twirp_service=example.Haberdasher twirp_method=MakeHat user=ann twirp_code=invalid_argument http_status=400"},
+		{"internal", twirp.InternalError("boom"), "level=ERROR msg=canonical-log-line twirp_service=example.Haberdasher twirp_method=MakeHat user=ann twirp_code=internal http_status=500"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		serve(ServerHooks(testLogger(&buf)), tt.err)
+
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("%s: log output:\ngot:  %q\nwant: %q", tt.name, got, tt.want)
+		}
+	}
+}
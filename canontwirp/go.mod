@@ -0,0 +1,12 @@
+module github.com/andrew-d/canonlog/canontwirp
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	github.com/twitchtv/twirp v8.1.3+incompatible
+)
+
+require github.com/pkg/errors v0.9.1 // indirect
+
+replace github.com/andrew-d/canonlog => ../
@@ -0,0 +1,130 @@
+// Package canontx records database transaction health in canonical log
+// lines.
+//
+// Transactions begun with [Begin] or run with [Do] record, in the [canonlog.Line]
+// attached to their context, the number of transactions, their total and
+// maximum duration, and the number that were rolled back:
+//
+//	err := canontx.Do(ctx, db, nil, func(tx *sql.Tx) error {
+//		_, err := tx.ExecContext(ctx, "UPDATE accounts SET balance = balance - 1 WHERE id = $1", id)
+//		return err
+//	})
+package canontx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// registry holds the attributes recorded by this package, separately from
+// [canonlog.DefaultRegistry] so that they cannot collide with keys
+// registered by users of the package.
+var registry = canonlog.NewRegistry()
+
+func sum[T int | time.Duration](old, new T) T { return old + new }
+
+// Attributes recorded by this package.
+var (
+	// AttrCount is the number of transactions begun.
+	AttrCount = canonlog.RegisterWith(registry, "db_tx_count",
+		canonlog.WithMerge(sum[int]), canonlog.WithCommutativeMerge[int]())
+
+	// AttrRollbacks is the number of transactions that were rolled back,
+	// including those whose commit failed.
+	AttrRollbacks = canonlog.RegisterWith(registry, "db_tx_rollbacks",
+		canonlog.WithMerge(sum[int]), canonlog.WithCommutativeMerge[int]())
+
+	// AttrTime is the total duration of all finished transactions, from
+	// Begin to Commit or Rollback.
+	AttrTime = canonlog.RegisterWith(registry, "db_tx_time",
+		canonlog.WithMerge(sum[time.Duration]), canonlog.WithCommutativeMerge[time.Duration]())
+
+	// AttrMaxTime is the duration of the longest finished transaction.
+	AttrMaxTime = canonlog.RegisterWith(registry, "db_tx_max_time",
+		canonlog.WithMerge(func(old, new time.Duration) time.Duration { return max(old, new) }),
+		canonlog.WithCommutativeMerge[time.Duration]())
+)
+
+// Beginner begins transactions. It is implemented by [*sql.DB] and
+// [*sql.Conn].
+type Beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Tx is an [sql.Tx] whose Commit and Rollback record the transaction in the
+// canonical log line of the context it was begun with.
+type Tx struct {
+	*sql.Tx
+
+	ctx   context.Context
+	start time.Time
+	once  sync.Once
+}
+
+// Begin begins a transaction on db, like [sql.DB.BeginTx], and records it in
+// the [canonlog.Line] attached to ctx. The transaction is recorded as
+// finished by the first call to [Tx.Commit] or [Tx.Rollback].
+func Begin(ctx context.Context, db Beginner, opts *sql.TxOptions) (*Tx, error) {
+	start := time.Now()
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	canonlog.Set(ctx, AttrCount, 1)
+	return &Tx{Tx: tx, ctx: ctx, start: start}, nil
+}
+
+// Commit commits the transaction. If the commit fails, the transaction is
+// recorded as rolled back.
+func (tx *Tx) Commit() error {
+	err := tx.Tx.Commit()
+	tx.finish(err != nil)
+	return err
+}
+
+// Rollback aborts the transaction. Calling Rollback after the transaction
+// has been committed, as with a deferred Rollback, does not record anything.
+func (tx *Tx) Rollback() error {
+	err := tx.Tx.Rollback()
+	tx.finish(true)
+	return err
+}
+
+// finish records the transaction's duration and whether it was rolled back,
+// the first time it is called.
+func (tx *Tx) finish(rolledBack bool) {
+	tx.once.Do(func() {
+		d := time.Since(tx.start)
+		canonlog.Set(tx.ctx, AttrTime, d)
+		canonlog.Set(tx.ctx, AttrMaxTime, d)
+		if rolledBack {
+			canonlog.Set(tx.ctx, AttrRollbacks, 1)
+		}
+	})
+}
+
+// Do runs fn in a transaction begun with [Begin]. The transaction is
+// committed if fn returns nil, and rolled back if fn returns an error or
+// panics. The error from fn, or else from Commit, is returned.
+func Do(ctx context.Context, db Beginner, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := Begin(ctx, db, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx.Tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
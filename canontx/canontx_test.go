@@ -0,0 +1,156 @@
+package canontx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+)
+
+// fakeDriver is a database/sql driver supporting only transactions, whose
+// commits fail if failCommit is set.
+type fakeDriver struct {
+	failCommit bool
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{c.d}, nil }
+
+type fakeTx struct{ d *fakeDriver }
+
+func (tx *fakeTx) Commit() error {
+	if tx.d.failCommit {
+		return errors.New("commit failed")
+	}
+	return nil
+}
+func (tx *fakeTx) Rollback() error { return nil }
+
+func openDB(t *testing.T, d *fakeDriver) *sql.DB {
+	db := sql.OpenDB(connector{d})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type connector struct{ d *fakeDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+// lineValues returns the attributes of the line in ctx, keyed by name.
+func lineValues(ctx context.Context) map[string]any {
+	m := make(map[string]any)
+	for _, a := range canonlog.Attrs(ctx) {
+		m[a.Key] = a.Value.Any()
+	}
+	return m
+}
+
+func TestBegin(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		db := openDB(t, &fakeDriver{})
+		ctx := canonlog.New(context.Background())
+
+		// Committed, with a deferred Rollback that must not count.
+		func() {
+			tx, err := Begin(ctx, db, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			time.Sleep(10 * time.Millisecond)
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+
+		// Rolled back.
+		tx, err := Begin(ctx, db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		tx.Rollback()
+
+		got := lineValues(ctx)
+		want := map[string]any{
+			"db_tx_count":     int64(2),
+			"db_tx_rollbacks": int64(1),
+			"db_tx_time":      40 * time.Millisecond,
+			"db_tx_max_time":  30 * time.Millisecond,
+		}
+		for key, w := range want {
+			if got[key] != w {
+				t.Errorf("%s = %v, want %v", key, got[key], w)
+			}
+		}
+	})
+}
+
+func TestDo(t *testing.T) {
+	errFn := errors.New("fn failed")
+
+	tests := []struct {
+		name          string
+		failCommit    bool
+		fnErr         error
+		wantErr       bool
+		wantRollbacks any
+	}{
+		{name: "commit"},
+		{name: "fn_error", fnErr: errFn, wantErr: true, wantRollbacks: int64(1)},
+		{name: "commit_error", failCommit: true, wantErr: true, wantRollbacks: int64(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openDB(t, &fakeDriver{failCommit: tt.failCommit})
+			ctx := canonlog.New(context.Background())
+
+			err := Do(ctx, db, nil, func(tx *sql.Tx) error { return tt.fnErr })
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.fnErr != nil && !errors.Is(err, tt.fnErr) {
+				t.Errorf("Do() error = %v, want %v", err, tt.fnErr)
+			}
+
+			got := lineValues(ctx)
+			if got["db_tx_count"] != int64(1) {
+				t.Errorf("db_tx_count = %v, want 1", got["db_tx_count"])
+			}
+			if got["db_tx_rollbacks"] != tt.wantRollbacks {
+				t.Errorf("db_tx_rollbacks = %v, want %v", got["db_tx_rollbacks"], tt.wantRollbacks)
+			}
+		})
+	}
+}
+
+func TestDo_Panic(t *testing.T) {
+	db := openDB(t, &fakeDriver{})
+	ctx := canonlog.New(context.Background())
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Do did not propagate panic")
+			}
+		}()
+		Do(ctx, db, nil, func(tx *sql.Tx) error { panic("boom") })
+	}()
+
+	if got := lineValues(ctx)["db_tx_rollbacks"]; got != int64(1) {
+		t.Errorf("db_tx_rollbacks = %v, want 1", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Package canonzap converts canonical log lines to zap fields, so that
+// services logging with zap can emit canonical lines natively:
+//
+//	defer func() {
+//		logger.Info(canonlog.Message, canonzap.Fields(ctx)...)
+//	}()
+//
+// It is a separate module from canonlog, so that programs that do not use
+// zap do not depend on it.
+package canonzap
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/andrew-d/canonlog"
+	"go.uber.org/zap"
+)
+
+// Fields returns the attributes of the [canonlog.Line] attached to ctx, as
+// returned by [canonlog.Attrs], as zap fields. Values are given their zap
+// field type directly, with values of types that [slog.Value] does not
+// represent natively passed to [zap.Any] as set, so that zap's handling of
+// errors, ObjectMarshalers and other interfaces applies. Groups
+// become nested objects. If the context does not have a Line, Fields
+// returns nil.
+func Fields(ctx context.Context) []zap.Field {
+	attrs := canonlog.Attrs(ctx)
+	if attrs == nil {
+		return nil
+	}
+	return appendFields(make([]zap.Field, 0, len(attrs)), attrs)
+}
+
+// appendFields appends attrs to fields as zap fields, skipping empty
+// attributes as [slog.Handler] implementations do.
+func appendFields(fields []zap.Field, attrs []slog.Attr) []zap.Field {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+			// Inlined into the enclosing group, as by slog handlers.
+			fields = appendFields(fields, a.Value.Group())
+			continue
+		}
+		fields = append(fields, field(a))
+	}
+	return fields
+}
+
+// field returns a, whose value must be resolved, as a zap field.
+func field(a slog.Attr) zap.Field {
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return zap.String(a.Key, v.String())
+	case slog.KindInt64:
+		return zap.Int64(a.Key, v.Int64())
+	case slog.KindUint64:
+		return zap.Uint64(a.Key, v.Uint64())
+	case slog.KindFloat64:
+		return zap.Float64(a.Key, v.Float64())
+	case slog.KindBool:
+		return zap.Bool(a.Key, v.Bool())
+	case slog.KindDuration:
+		return zap.Duration(a.Key, v.Duration())
+	case slog.KindTime:
+		return zap.Time(a.Key, v.Time())
+	case slog.KindGroup:
+		members := v.Group()
+		return zap.Dict(a.Key, appendFields(make([]zap.Field, 0, len(members)), members)...)
+	}
+	return zap.Any(a.Key, v.Any())
+}
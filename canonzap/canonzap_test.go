@@ -0,0 +1,82 @@
+package canonzap
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFields(t *testing.T) {
+	r := canonlog.NewRegistry()
+	attrUser := canonlog.RegisterWith[string](r, "user")
+	attrStatus := canonlog.RegisterWith[int](r, "status")
+	attrCached := canonlog.RegisterWith[bool](r, "cached")
+	attrDBTime := canonlog.RegisterWith(r, "time", canonlog.WithGroup[time.Duration]("db"))
+	attrDBRows := canonlog.RegisterWith(r, "rows", canonlog.WithGroup[uint64]("db"))
+	attrErr := canonlog.RegisterWith[error](r, "error")
+
+	if got := Fields(context.Background()); got != nil {
+		t.Errorf("Fields without a line = %v, want nil", got)
+	}
+
+	ctx := canonlog.New(context.Background(), canonlog.WithRegistry(r),
+		canonlog.WithLineEnricher(canonlog.StaticEnricher(
+			slog.Group("", slog.String("host", "a")),
+		)),
+	)
+	canonlog.Set(ctx, attrUser, "alice")
+	canonlog.Set(ctx, attrStatus, 200)
+	canonlog.Set(ctx, attrCached, true)
+	canonlog.Set(ctx, attrDBTime, 12*time.Millisecond)
+	canonlog.Set(ctx, attrDBRows, 3)
+	canonlog.Set(ctx, attrErr, errors.New("boom"))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info(canonlog.Message, Fields(ctx)...)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(entries))
+	}
+	got := entries[0].ContextMap()
+	want := map[string]any{
+		"user":   "alice",
+		"status": int64(200),
+		"cached": true,
+		"db": map[string]any{
+			"time": 12 * time.Millisecond,
+			"rows": uint64(3),
+		},
+		"error": "boom",
+		"host":  "a",
+	}
+	if len(got) != len(want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if m, ok := v.(map[string]any); ok {
+			gm, _ := got[k].(map[string]any)
+			for mk, mv := range m {
+				if gm[mk] != mv {
+					t.Errorf("field %s.%s = %#v, want %#v", k, mk, gm[mk], mv)
+				}
+			}
+			continue
+		}
+		if got[k] != v {
+			t.Errorf("field %s = %#v, want %#v", k, got[k], v)
+		}
+	}
+	for _, f := range entries[0].Context {
+		if f.Key == "error" && f.Type != zapcore.ErrorType {
+			t.Errorf("error field has type %v, want ErrorType", f.Type)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+module github.com/andrew-d/canonlog/canonzap
+
+go 1.25.3
+
+require (
+	github.com/andrew-d/canonlog v0.0.0
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	github.com/stretchr/testify v1.9.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+)
+
+replace github.com/andrew-d/canonlog => ../
@@ -0,0 +1,116 @@
+package canonlog
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// attrClientIP records the client address derived with WithClientIP.
+var attrClientIP = RegisterWith[string](httpRegistry, "client_ip")
+
+// WithClientIP makes the middleware record the address of the client that
+// made each request under the "client_ip" key, taking into account the
+// proxies in front of the server, so that services do not each have to
+// implement this. trusted lists the networks of those proxies, such as load
+// balancers; only headers added by them are believed, since clients can set
+// any headers they like.
+//
+// If the request came directly from an untrusted address, that address is
+// the client's. Otherwise, the addresses of the hops recorded in the "for"
+// parameters of the Forwarded header, or if it has none, in the
+// X-Forwarded-For header, are examined from the nearest to the furthest, and
+// the first that is not trusted is the client's. If neither header records
+// any hops, a trusted proxy's X-Real-IP header is used if present. If every
+// hop is trusted, the furthest is the client.
+func WithClientIP(trusted ...netip.Prefix) MiddlewareOption {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, p := range trusted {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(cfg *middlewareConfig) {
+		cfg.onRequest = append(cfg.onRequest, func(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			if addr, ok := clientIP(r, isTrusted); ok {
+				Set(ctx, attrClientIP, addr.String())
+			}
+			return ctx
+		})
+	}
+}
+
+// clientIP returns the address of the client that made r.
+func clientIP(r *http.Request, isTrusted func(netip.Addr) bool) (netip.Addr, bool) {
+	client, ok := parseHostAddr(r.RemoteAddr)
+	if !ok || !isTrusted(client) {
+		return client, ok
+	}
+
+	hops := forwardedFor(r.Header)
+	if hops == nil {
+		if real, ok := parseHostAddr(strings.TrimSpace(r.Header.Get("X-Real-Ip"))); ok {
+			return real, true
+		}
+		return client, true
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHostAddr(hops[i])
+		if !ok {
+			// An obfuscated or malformed hop; the nearest proxy that
+			// reported it is the best that is known.
+			break
+		}
+		client = addr
+		if !isTrusted(addr) {
+			break
+		}
+	}
+	return client, true
+}
+
+// forwardedFor returns the addresses of the hops recorded in the "for"
+// parameters of the Forwarded header of h, or else in its X-Forwarded-For
+// header, from furthest to nearest. It returns nil if neither header
+// records any.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values("Forwarded") {
+		for elem := range strings.SplitSeq(v, ",") {
+			for pair := range strings.SplitSeq(elem, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(key, "for") {
+					hops = append(hops, strings.Trim(value, `"`))
+				}
+			}
+		}
+	}
+	if hops != nil {
+		return hops
+	}
+	for _, v := range h.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseHostAddr parses an IP address, optionally with a port and with IPv6
+// addresses optionally in brackets.
+func parseHostAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
@@ -0,0 +1,100 @@
+package canonlog
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8:ffff::/48"),
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:   "direct",
+			remote: "203.0.113.7:51234",
+			want:   "203.0.113.7",
+		},
+		{
+			name:    "untrusted peer headers ignored",
+			remote:  "203.0.113.7:51234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-Ip": "198.51.100.2"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "x-forwarded-for",
+			remote:  "10.0.0.2:443",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.99, 203.0.113.7, 10.1.2.3"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "all hops trusted",
+			remote:  "10.0.0.2:443",
+			headers: map[string]string{"X-Forwarded-For": "10.9.9.9, 10.1.2.3"},
+			want:    "10.9.9.9",
+		},
+		{
+			name:   "forwarded preferred",
+			remote: "[2001:db8:ffff::1]:443",
+			headers: map[string]string{
+				"Forwarded":       `for="[2001:db8:cafe::17]:4711";proto=https, for=10.1.2.3`,
+				"X-Forwarded-For": "198.51.100.1",
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:   "forwarded without for",
+			remote: "10.0.0.2:443",
+			headers: map[string]string{
+				"Forwarded":       "proto=https;host=example.com",
+				"X-Forwarded-For": "203.0.113.7",
+				"X-Real-Ip":       "198.51.100.2",
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name:    "obfuscated hop",
+			remote:  "10.0.0.2:443",
+			headers: map[string]string{"Forwarded": "for=_hidden, for=10.1.2.3"},
+			want:    "10.1.2.3",
+		},
+		{
+			name:    "x-real-ip",
+			remote:  "10.0.0.2:443",
+			headers: map[string]string{"X-Real-Ip": "203.0.113.7"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:   "ipv4-mapped peer",
+			remote: "[::ffff:203.0.113.7]:51234",
+			want:   "203.0.113.7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = lookup(FromContext(r.Context()), attrClientIP)
+			})
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			Middleware(slog.New(slog.DiscardHandler), WithClientIP(trusted...))(handler).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("client_ip = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
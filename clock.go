@@ -0,0 +1,119 @@
+package canonlog
+
+import (
+	"log/slog"
+	"time"
+)
+
+// WithClock makes the new [Line] read the current time from now rather than
+// [time.Now], for the times it records itself with [WithAutoDuration] and
+// [WithTimestamps], so that tests can control them.
+func WithClock(now func() time.Time) LineOption {
+	return func(l *Line) {
+		l.now = now
+	}
+}
+
+// WithAutoDuration makes the new [Line] record the time elapsed between its
+// creation and its emission under key, removing the need to record a start
+// time and set the duration by hand:
+//
+//	ctx = canonlog.New(ctx, canonlog.WithAutoDuration("duration"))
+//
+// Until the line is emitted, the time elapsed so far is reported. A value
+// set for key on the line takes precedence.
+func WithAutoDuration(key string) LineOption {
+	return func(l *Line) {
+		l.durationKey = key
+	}
+}
+
+// Keys of the attributes recorded by [WithTimestamps].
+const (
+	StartTimeKey = "start_time"
+	EndTimeKey   = "end_time"
+)
+
+// WithTimestamps makes the new [Line] record the time it was created under
+// [StartTimeKey], and the time it was first emitted under [EndTimeKey], for
+// analytics that bucket lines by time independently of the timestamp added
+// by the handler. The times are formatted with layout, as by
+// [time.Time.Format], or, if layout is empty, left for the handler to
+// format:
+//
+//	ctx = canonlog.New(ctx, canonlog.WithTimestamps(time.RFC3339Nano))
+//
+// The end time is only recorded once the line is emitted. Values set for
+// the keys on the line take precedence.
+func WithTimestamps(layout string) LineOption {
+	return func(l *Line) {
+		l.timestamps = true
+		l.timestampLayout = layout
+	}
+}
+
+// startClock records the creation time of l, if it records any times.
+func (l *Line) startClock() {
+	if l.durationKey == "" && !l.timestamps {
+		l.now = nil
+		return
+	}
+	if l.now == nil {
+		l.now = time.Now
+	}
+	l.start = l.now()
+}
+
+// stopClock records the emission time of l, if it records any times. The
+// shards of l must be locked with lockAll.
+func (l *Line) stopClock() {
+	if l.now != nil {
+		l.end = l.now()
+	}
+}
+
+// readClock returns the creation time of l, and its emission time or, if it
+// has not been emitted, the current time, along with whether it has been
+// emitted. The times are zero if l does not record any. The shards of l must
+// be locked with lockAll.
+func (l *Line) readClock() (start, end time.Time, emitted bool) {
+	if l.now == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	if !l.end.IsZero() {
+		return l.start, l.end, true
+	}
+	return l.start, l.now(), false
+}
+
+// addClockAttrs adds the attributes recording the times read by readClock
+// to b, other than those whose keys are set in entries, the values of l.
+func (l *Line) addClockAttrs(b *attrsBuilder, entries []entry, start, end time.Time, emitted bool) {
+	if l.timestamps && !hasKey(entries, StartTimeKey) {
+		b.add(StartTimeKey, "", StartTimeKey, l.timeValue(start))
+	}
+	if l.timestamps && emitted && !hasKey(entries, EndTimeKey) {
+		b.add(EndTimeKey, "", EndTimeKey, l.timeValue(end))
+	}
+	if l.durationKey != "" && !hasKey(entries, l.durationKey) {
+		b.add(l.durationKey, "", l.durationKey, slog.DurationValue(end.Sub(start)))
+	}
+}
+
+// timeValue returns t formatted as configured by WithTimestamps.
+func (l *Line) timeValue(t time.Time) slog.Value {
+	if l.timestampLayout == "" {
+		return slog.TimeValue(t)
+	}
+	return slog.StringValue(t.Format(l.timestampLayout))
+}
+
+// hasKey reports whether entries holds a value for key.
+func hasKey(entries []entry, key string) bool {
+	for i := range entries {
+		if entries[i].key == key {
+			return true
+		}
+	}
+	return false
+}
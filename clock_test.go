@@ -0,0 +1,114 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithAutoDuration(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	ctx := New(context.Background(), WithRegistry(r),
+		WithClock(clock), WithAutoDuration("duration"))
+	Set(ctx, attrUser, "alice")
+
+	now = now.Add(2 * time.Second)
+	want := []slog.Attr{slog.String("user", "alice"), slog.Duration("duration", 2*time.Second)}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs before emitting = %v, want %v", got, want)
+	}
+
+	now = now.Add(time.Second)
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	if got, want := buf.String(), "level=INFO msg=canonical-log-line user=alice duration=3s\n"; got != want {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+
+	// The duration stops at the first emission.
+	now = now.Add(time.Hour)
+	want = []slog.Attr{slog.String("user", "alice"), slog.Duration("duration", 3*time.Second)}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs after emitting = %v, want %v", got, want)
+	}
+}
+
+func TestWithAutoDuration_Synctest(t *testing.T) {
+	r := testRegistry(t)
+	attrDuration := RegisterWith[time.Duration](r, "duration")
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx := New(context.Background(), WithRegistry(r), WithAutoDuration("elapsed"))
+		time.Sleep(150 * time.Millisecond)
+		want := []slog.Attr{slog.Duration("elapsed", 150*time.Millisecond)}
+		if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+			t.Errorf("Attrs = %v, want %v", got, want)
+		}
+
+		// A value set explicitly takes precedence.
+		ctx = New(context.Background(), WithRegistry(r), WithAutoDuration("duration"))
+		Set(ctx, attrDuration, time.Minute)
+		want = []slog.Attr{slog.Duration("duration", time.Minute)}
+		if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+			t.Errorf("Attrs = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestWithTimestamps(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+
+	t.Run("layout", func(t *testing.T) {
+		now = start
+		ctx := New(context.Background(), WithRegistry(r), WithClock(clock),
+			WithTimestamps(time.RFC3339), WithAutoDuration("duration"))
+		Set(ctx, attrUser, "alice")
+
+		now = now.Add(time.Second)
+		want := []slog.Attr{
+			slog.String("user", "alice"),
+			slog.String(StartTimeKey, "2024-03-01T12:00:00Z"),
+			slog.Duration("duration", time.Second),
+		}
+		if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+			t.Errorf("Attrs before emitting = %v, want %v", got, want)
+		}
+
+		now = now.Add(time.Second)
+		var buf bytes.Buffer
+		Emit(ctx, testLogger(&buf), slog.LevelInfo)
+		want2 := "level=INFO msg=canonical-log-line user=alice start_time=2024-03-01T12:00:00Z " +
+			"end_time=2024-03-01T12:00:02Z duration=2s\n"
+		if got := buf.String(); got != want2 {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want2)
+		}
+	})
+
+	t.Run("time values", func(t *testing.T) {
+		now = start
+		ctx := New(context.Background(), WithRegistry(r), WithClock(clock), WithTimestamps(""))
+		now = now.Add(time.Minute)
+		Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+
+		want := []slog.Attr{
+			slog.Time(StartTimeKey, start),
+			slog.Time(EndTimeKey, start.Add(time.Minute)),
+		}
+		if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+			t.Errorf("Attrs = %v, want %v", got, want)
+		}
+	})
+}
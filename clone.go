@@ -0,0 +1,106 @@
+package canonlog
+
+import (
+	"context"
+	"slices"
+)
+
+// Clone returns a copy of ctx carrying a new [Line] that starts with a copy
+// of the attributes of the Line in ctx, so that a branched operation, such as
+// a hedged request, can diverge without its Sets affecting the original. Use
+// [MergeBack] to copy selected attributes back to the original afterwards.
+//
+// Values themselves are not deep-copied, so values of reference types such
+// as slices and maps must not be modified in place once set. If ctx does not
+// have a Line, Clone returns ctx unchanged.
+func Clone(ctx context.Context) context.Context {
+	l := FromContext(ctx)
+	if l == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, l.clone())
+}
+
+// clone returns a copy of l.
+func (l *Line) clone() *Line {
+	l.lockAll()
+	defer l.unlockAll()
+
+	c := &Line{
+		registry: l.registry,
+		enriched: l.enriched,
+		maxBytes: l.maxBytes,
+
+		now:         l.now,
+		start:       l.start,
+		durationKey: l.durationKey,
+
+		timestamps:      l.timestamps,
+		timestampLayout: l.timestampLayout,
+	}
+	for i := range l.shards {
+		entries := slices.Clone(l.shards[i].entries)
+		for j := range entries {
+			entries[j].sv = entries[j].sv.snapshot()
+		}
+		c.shards[i].entries = entries
+	}
+	c.inserted.Store(l.inserted.Load())
+	c.bytes.Store(l.bytes.Load())
+	return c
+}
+
+// snapshot returns a copy of sv that does not share an accumulator with it.
+// A Line holding the copy gets the accumulator's current value, and creates
+// its own accumulator on its next Set.
+func (sv storedValue) snapshot() storedValue {
+	if a, ok := sv.raw.(accumulated); ok {
+		sv.size = sv.charged()
+		sv.raw = a.load()
+	}
+	return sv
+}
+
+// MergeBack sets the value of attr in the [Line] attached to src, if it has
+// one, on the Line attached to dst, combining it with any existing value
+// using attr's merge function exactly as [Set] would. It is typically used
+// to copy selected attributes from a line created by [Clone] back to the
+// original line.
+func MergeBack[T any](dst, src context.Context, attr Attr[T]) {
+	if v, ok := lookup(FromContext(src), attr); ok {
+		Set(dst, attr, v)
+	}
+}
+
+// lookup returns the current value of attr in l, and whether it is set.
+func lookup[T any](l *Line, attr Attr[T]) (T, bool) {
+	var zero T
+	if l == nil {
+		return zero, false
+	}
+
+	s := l.shard(attr.key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sv, ok := s.get(attr.key)
+	if !ok {
+		return zero, false
+	}
+	v, ok := sv.value().(T)
+	return v, ok
+}
+
+// Detach returns a context that carries the same [Line] and other values as
+// ctx, but is never canceled and has no deadline, so that a fire-and-forget
+// goroutine spawned by a handler can keep setting attributes after the
+// request's context is canceled:
+//
+//	go audit(canonlog.Detach(ctx), event)
+//
+// Attributes set after the line has been emitted do not appear in it; pair
+// Detach with [Join] and a wait for the goroutine, or with [Task], if they
+// need to be logged.
+func Detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
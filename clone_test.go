@@ -0,0 +1,74 @@
+package canonlog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrCalls := RegisterWith(r, "calls",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+	attrHost := RegisterWith[string](r, "host")
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrCalls, 1)
+
+	hedged := Clone(ctx)
+	Set(hedged, attrCalls, 1)
+	Set(hedged, attrHost, "replica-2")
+	Set(ctx, attrCalls, 10)
+
+	if got, _ := lookup(FromContext(ctx), attrCalls); got != 11 {
+		t.Errorf("original calls = %d, want 11", got)
+	}
+	if _, ok := lookup(FromContext(ctx), attrHost); ok {
+		t.Error("Set on clone affected the original line")
+	}
+	if got, _ := lookup(FromContext(hedged), attrCalls); got != 2 {
+		t.Errorf("clone calls = %d, want 2", got)
+	}
+	if got, _ := lookup(FromContext(hedged), attrUser); got != "usr_123" {
+		t.Errorf("clone user = %q, want %q", got, "usr_123")
+	}
+
+	MergeBack(ctx, hedged, attrCalls)
+	MergeBack(ctx, hedged, attrHost)
+	if got, _ := lookup(FromContext(ctx), attrCalls); got != 13 {
+		t.Errorf("calls after MergeBack = %d, want 13", got)
+	}
+	if got, _ := lookup(FromContext(ctx), attrHost); got != "replica-2" {
+		t.Errorf("host after MergeBack = %q, want %q", got, "replica-2")
+	}
+}
+
+func TestClone_WithoutLine(t *testing.T) {
+	ctx := context.Background()
+	if got := Clone(ctx); got != ctx {
+		t.Error("Clone on context without Line did not return it unchanged")
+	}
+}
+
+func TestDetach(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	ctx, cancel := context.WithCancel(New(context.Background()))
+	detached := Detach(ctx)
+	cancel()
+
+	if detached.Err() != nil {
+		t.Errorf("detached context has error %v after parent canceled", detached.Err())
+	}
+	if FromContext(detached) != FromContext(ctx) {
+		t.Fatal("detached context does not carry the same Line")
+	}
+	Set(detached, attrUser, "usr_123")
+	if got, _ := lookup(FromContext(ctx), attrUser); got != "usr_123" {
+		t.Errorf("user = %q, want %q", got, "usr_123")
+	}
+}
@@ -0,0 +1,143 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// connRegistry holds the attributes recorded by [Emitter.OpenConn], kept out
+// of [DefaultRegistry] for the same reason as those of [Middleware].
+var connRegistry = NewRegistry()
+
+var (
+	attrConn         = RegisterWith[string](connRegistry, "conn")
+	attrConnDuration = RegisterWith[time.Duration](connRegistry, "duration")
+	attrMsgsIn       = RegisterCounterWith[int64](connRegistry, "msgs_in")
+	attrMsgsOut      = RegisterCounterWith[int64](connRegistry, "msgs_out")
+	attrBytesIn      = RegisterCounterWith[int64](connRegistry, "bytes_in")
+	attrBytesOut     = RegisterCounterWith[int64](connRegistry, "bytes_out")
+)
+
+// ConnEventKey is the key under which the lines of a connection opened with
+// [Emitter.OpenConn] record whether they are interval lines ("interval") or
+// the final summary line ("close").
+const ConnEventKey = "conn_event"
+
+// A Conn is a canonical log line that lives for the duration of a
+// long-lived connection, such as a WebSocket or server-sent events stream,
+// rather than a single request. It is created by [Emitter.OpenConn].
+type Conn struct {
+	ctx     context.Context
+	emitter *Emitter
+	start   time.Time
+
+	stop   chan struct{}
+	done   chan struct{} // closed when the interval goroutine exits
+	closed sync.Once
+}
+
+// ConnOption configures a [Conn].
+type ConnOption func(*connConfig)
+
+// connConfig holds the configuration of a [Conn].
+type connConfig struct {
+	interval time.Duration
+}
+
+// WithInterval makes the [Conn] emit an interval line every d while it is
+// open, in addition to the summary line emitted when it is closed, so that
+// activity on connections lasting hours is visible before they end.
+// Interval lines are emitted at [slog.LevelInfo] with the attributes set on
+// the line so far and the time since the connection was opened.
+func WithInterval(d time.Duration) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.interval = d
+	}
+}
+
+// OpenConn creates a canonical log line for a long-lived connection with the
+// given name, and returns a context carrying it along with the [Conn] that
+// emits it. Use the context with [Set] and the other functions of this
+// package while serving the connection, [MessageIn] and [MessageOut] to
+// count messages, and call [Conn.Close] when the connection ends:
+//
+//	ctx, conn := emitter.OpenConn(r.Context(), "chat_ws",
+//		canonlog.WithInterval(time.Minute))
+//	defer func() { conn.Close(err) }()
+//
+// The line records the connection name under the "conn" key, and its
+// duration, the number of messages received and sent, and their total sizes
+// under the "duration", "msgs_in", "msgs_out", "bytes_in" and "bytes_out"
+// keys. Its lines are marked with [ConnEventKey].
+func (e *Emitter) OpenConn(ctx context.Context, name string, opts ...ConnOption) (context.Context, *Conn) {
+	var cfg connConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx = New(ctx)
+	Set(ctx, attrConn, name)
+	c := &Conn{
+		ctx:     ctx,
+		emitter: e,
+		start:   time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if cfg.interval > 0 {
+		go c.emitIntervals(cfg.interval)
+	} else {
+		close(c.done)
+	}
+	return ctx, c
+}
+
+// emitIntervals emits an interval line every d until the connection is
+// closed.
+func (c *Conn) emitIntervals(d time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			Set(c.ctx, attrConnDuration, time.Since(c.start))
+			c.emitter.logAll(c.ctx, slog.LevelInfo, false, slog.String(ConnEventKey, "interval"))
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Close stops any interval lines and emits the connection's summary line,
+// with the level and outcome derived from err as described for
+// [Emitter.EmitOnReturn]. Only the first call has any effect.
+func (c *Conn) Close(err error) {
+	c.closed.Do(func() {
+		close(c.stop)
+		<-c.done
+
+		Set(c.ctx, attrConnDuration, time.Since(c.start))
+		level, attrs := outcome(err)
+		attrs = append([]slog.Attr{slog.String(ConnEventKey, "close")}, attrs...)
+		c.emitter.emit(c.ctx, level, attrs...)
+	})
+}
+
+// MessageIn records a message of size bytes received on the connection
+// whose line is attached to ctx. If the context does not have a [Line],
+// MessageIn silently does nothing.
+func MessageIn(ctx context.Context, size int) {
+	Set(ctx, attrMsgsIn, 1)
+	Set(ctx, attrBytesIn, int64(size))
+}
+
+// MessageOut records a message of size bytes sent on the connection whose
+// line is attached to ctx. See [MessageIn] for details.
+func MessageOut(ctx context.Context, size int) {
+	Set(ctx, attrMsgsOut, 1)
+	Set(ctx, attrBytesOut, int64(size))
+}
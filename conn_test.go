@@ -0,0 +1,46 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestOpenConn(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		e := NewEmitter(testLogger(&buf))
+
+		ctx, conn := e.OpenConn(context.Background(), "chat_ws", WithInterval(time.Minute))
+		MessageIn(ctx, 120)
+		time.Sleep(90 * time.Second)
+		MessageOut(ctx, 40)
+		MessageOut(ctx, 60)
+		time.Sleep(20 * time.Second)
+		conn.Close(errors.New("client went away"))
+		conn.Close(nil) // ignored
+
+		want := "level=INFO msg=canonical-log-line conn=chat_ws msgs_in=1 bytes_in=120 duration=1m0s conn_event=interval\n" +
+			"level=ERROR msg=canonical-log-line conn=chat_ws msgs_in=1 bytes_in=120 duration=1m50s msgs_out=2 bytes_out=100 conn_event=close outcome=error error=\"client went away\"\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestOpenConn_WithoutInterval(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		_, conn := NewEmitter(testLogger(&buf)).OpenConn(context.Background(), "events")
+		time.Sleep(time.Hour)
+		conn.Close(nil)
+
+		want := "level=INFO msg=canonical-log-line conn=events duration=1h0m0s conn_event=close outcome=success\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCounter(t *testing.T) {
+	r := testRegistry(t)
+	attrRows := RegisterCounterWith[int64](r, "rows")
+	attrDBTime := RegisterCounterWith[time.Duration](r, "db_time")
+	attrBalance := RegisterCounterWith[int](r, "balance")
+	attrRetries := RegisterCounterWith[uint8](r, "retries")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				Add(ctx, attrRows, 1)
+			}
+		})
+	}
+	wg.Wait()
+	Set(ctx, attrRows, 5) // Set adds to a counter too
+	Add(ctx, attrDBTime, 30*time.Millisecond)
+	Add(ctx, attrDBTime, 12*time.Millisecond)
+	Add(ctx, attrBalance, 10)
+	Add(ctx, attrBalance, -25)
+	SetMany(ctx, map[string]any{"retries": uint8(2)})
+	Add(ctx, attrRetries, 1)
+
+	want := []slog.Attr{
+		slog.Int64("rows", 8005),
+		slog.Duration("db_time", 42*time.Millisecond),
+		slog.Int("balance", -15),
+		slog.Any("retries", uint8(3)),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
+
+func TestCounterWithMerge(t *testing.T) {
+	r := testRegistry(t)
+	// A merge function given as an option replaces the sum.
+	attrPeak := RegisterCounterWith(r, "peak", WithMerge(func(old, new int) int { return max(old, new) }))
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrPeak, 3)
+	Set(ctx, attrPeak, 7)
+	Set(ctx, attrPeak, 5)
+
+	want := []slog.Attr{slog.Int("peak", 7)}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	r := testRegistry(t)
+	attrLast := RegisterWith[int](r, "last")
+	attrMax := RegisterWith(r, "max",
+		WithMerge(func(old, new int) int { return max(old, new) }),
+		WithCommutativeMerge[int](),
+	)
+
+	// Add adds to attributes that are not counters, regardless of
+	// their merge function.
+	ctx := New(context.Background(), WithRegistry(r))
+	Add(ctx, attrLast, 3)
+	Add(ctx, attrLast, 4)
+	Add(ctx, attrMax, 3)
+	Add(ctx, attrMax, 4)
+
+	want := []slog.Attr{slog.Int("last", 7), slog.Int("max", 7)}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+
+	Add(context.Background(), attrLast, 1) // no line: does nothing
+}
+
+func TestCounterAllocs(t *testing.T) {
+	r := NewRegistry()
+	attrRows := RegisterCounterWith[int64](r, "rows")
+	ctx := New(context.Background(), WithRegistry(r))
+	Add(ctx, attrRows, 1000)
+
+	allocs := testing.AllocsPerRun(100, func() { Add(ctx, attrRows, 1000) })
+	if allocs != 0 {
+		t.Errorf("Add allocated %v times per call, want 0", allocs)
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	r := NewRegistry()
+	attrRows := RegisterCounterWith[int64](r, "rows")
+	ctx := New(context.Background(), WithRegistry(r))
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Add(ctx, attrRows, 1)
+		}
+	})
+}
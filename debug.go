@@ -0,0 +1,222 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DebugHandler returns an [http.Handler] that shows the lines kept by rec,
+// so that operators can see what a live process is doing: the requests in
+// flight, with the attributes they have accumulated so far, and the lines
+// most recently emitted. Like the handlers of net/http/pprof, it should
+// only be served to trusted clients, such as on an internal port:
+//
+//	rec := canonlog.NewRecorder(100)
+//	canonlog.DefaultRegistry.SetRecorder(rec)
+//	debugMux.Handle("/debug/canonlog", canonlog.DebugHandler(rec))
+//
+// Lines are rendered as HTML for browsers, and as JSON otherwise, or as
+// requested by the "format" query parameter, "html" or "json". The JSON
+// form is an object with the keys "in_flight" and "recent", each holding a
+// list of lines, most recent first, with their "time", "level" (recent lines
+// only), and "attrs", an object in which groups are nested objects.
+//
+// Only public attributes are shown (see [WithVisibility]), unless other
+// visibility classes are given, in which case attributes of the given
+// classes are shown instead:
+//
+//	canonlog.DebugHandler(rec, canonlog.VisibilityPublic, canonlog.VisibilityInternal)
+func DebugHandler(rec *Recorder, visible ...Visibility) http.Handler {
+	allowed := visibilitySet(1 << VisibilityPublic)
+	if len(visible) > 0 {
+		allowed = 0
+		for _, v := range visible {
+			allowed |= 1 << v
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page debugPage
+		for _, rl := range rec.Lines() {
+			attrs := filterAttrs(rl.Attrs, "", rl.visibility, allowed)
+			dl := debugLine{Time: rl.Time, attrs: resolveAttrs(attrs)}
+			if rl.InFlight {
+				page.InFlight = append(page.InFlight, dl)
+			} else {
+				dl.Level = rl.Level.String()
+				page.Recent = append(page.Recent, dl)
+			}
+		}
+		slices.Reverse(page.InFlight)
+		slices.Reverse(page.Recent)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+			if strings.Contains(r.Header.Get("Accept"), "text/html") {
+				format = "html"
+			}
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		switch format {
+		case "html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := debugTemplate.Execute(w, page); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		case "json":
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(page); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		default:
+			http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		}
+	})
+}
+
+// debugPage holds the lines rendered by [DebugHandler].
+type debugPage struct {
+	InFlight []debugLine `json:"in_flight"`
+	Recent   []debugLine `json:"recent"`
+}
+
+type debugLine struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level,omitempty"`
+	attrs []slog.Attr
+}
+
+// MarshalJSON encodes the attributes of dl as an object, in which groups
+// are nested objects.
+func (dl debugLine) MarshalJSON() ([]byte, error) {
+	type plain debugLine // without the MarshalJSON method
+	return json.Marshal(struct {
+		plain
+		Attrs map[string]any `json:"attrs"`
+	}{plain(dl), debugValue(slog.GroupValue(dl.attrs...)).(map[string]any)})
+}
+
+// Attrs returns the attributes of dl formatted as by [slog.TextHandler].
+func (dl debugLine) Attrs() string {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "", 0)
+	rec.AddAttrs(dl.attrs...)
+	h.Handle(context.Background(), rec)
+	return strings.TrimSpace(buf.String())
+}
+
+// filterAttrs returns the attributes of attrs, whose keys are prefixed with
+// prefix, that are of a class in allowed according to classes, which holds
+// the classes of those that are not public. Attributes of groups registered
+// [WithGroup] are filtered individually.
+func filterAttrs(attrs []slog.Attr, prefix string, classes map[string]Visibility, allowed visibilitySet) []slog.Attr {
+	if classes == nil && allowed.has(VisibilityPublic) {
+		return attrs
+	}
+	var filtered []slog.Attr
+	for _, a := range attrs {
+		key := prefix + a.Key
+		if v, ok := classes[key]; ok {
+			if allowed.has(v) {
+				filtered = append(filtered, a)
+			}
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			if members := filterAttrs(a.Value.Group(), key+".", classes, allowed); len(members) > 0 {
+				filtered = append(filtered, slog.Attr{Key: a.Key, Value: slog.GroupValue(members...)})
+			}
+			continue
+		}
+		if allowed.has(VisibilityPublic) {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
+}
+
+// resolveAttrs returns a copy of attrs with their values, such as those set
+// with [SetLazy], resolved and sanitized as described for [Attrs].
+func resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	resolved := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			v = slog.GroupValue(resolveAttrs(v.Group())...)
+		} else {
+			v, _ = sanitize(v)
+		}
+		resolved[i] = slog.Attr{Key: a.Key, Value: v}
+	}
+	return resolved
+}
+
+// debugValue returns v, which must be resolved, in a form that encodes to
+// readable JSON: groups as objects, durations as strings such as "1.5s",
+// and errors as their messages.
+func debugValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindGroup:
+		m := make(map[string]any)
+		for _, a := range v.Group() {
+			m[a.Key] = debugValue(a.Value)
+		}
+		return m
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case json.Marshaler:
+			return x
+		case error:
+			return x.Error()
+		case fmt.Stringer:
+			return x.String()
+		}
+	}
+	return v.Any()
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>canonlog</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.attrs { font-family: monospace; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>In flight ({{len .InFlight}})</h1>
+<table>
+<tr><th>Started</th><th>Attributes</th></tr>
+{{range .InFlight}}<tr><td>{{.Time.Format "15:04:05.000"}}</td><td class="attrs">{{.Attrs}}</td></tr>
+{{end}}</table>
+<h1>Recent ({{len .Recent}})</h1>
+<table>
+<tr><th>Emitted</th><th>Level</th><th>Attributes</th></tr>
+{{range .Recent}}<tr><td>{{.Time.Format "15:04:05.000"}}</td><td>{{.Level}}</td><td class="attrs">{{.Attrs}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,135 @@
+package canonlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugHandler(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrWait := RegisterWith[time.Duration](r, "wait", WithGroup[time.Duration]("db"))
+	attrErr := RegisterWith[error](r, "err")
+	rec := NewRecorder(10)
+	r.SetRecorder(rec)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "<usr_1>")
+	Set(ctx, attrWait, 1500*time.Millisecond)
+	Set(ctx, attrErr, errors.New("timeout"))
+	Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelError)
+	inFlight := New(context.Background(), WithRegistry(r))
+	Set(inFlight, attrUser, "usr_2")
+
+	h := DebugHandler(rec)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/canonlog", nil))
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var page struct {
+		InFlight []struct {
+			Attrs map[string]any
+		} `json:"in_flight"`
+		Recent []struct {
+			Time  time.Time
+			Level string
+			Attrs map[string]any
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+	if len(page.InFlight) != 1 || page.InFlight[0].Attrs["user"] != "usr_2" {
+		t.Errorf("in_flight = %+v, want the line of usr_2", page.InFlight)
+	}
+	if len(page.Recent) != 1 {
+		t.Fatalf("recent = %+v, want one line", page.Recent)
+	}
+	got := page.Recent[0]
+	if got.Level != "ERROR" || got.Time.IsZero() {
+		t.Errorf("recent line = %+v, want a time and level ERROR", got)
+	}
+	want := map[string]any{
+		"user": "<usr_1>",
+		"db":   map[string]any{"wait": "1.5s"},
+		"err":  "timeout",
+	}
+	if b1, b2 := mustMarshal(t, got.Attrs), mustMarshal(t, want); b1 != b2 {
+		t.Errorf("attrs = %s, want %s", b1, b2)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/debug/canonlog", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	h.ServeHTTP(w, req)
+	body := w.Body.String()
+	for _, want := range []string{"user=&lt;usr_1&gt; db.wait=1.5s err=timeout", "user=usr_2", "<td>ERROR</td>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("HTML output does not contain %q:\n%s", want, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/debug/canonlog?format=xml", nil))
+	if w.Code != 400 {
+		t.Errorf("unknown format: status = %d, want 400", w.Code)
+	}
+	Emit(inFlight, slog.New(slog.DiscardHandler), slog.LevelInfo)
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestDebugHandler_Visibility(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith(r, "user", WithVisibility[string](VisibilitySensitive), WithAlias[string]("uid"))
+	attrHost := RegisterWith(r, "host", WithGroup[string]("db"), WithVisibility[string](VisibilityInternal))
+	attrQueries := RegisterWith(r, "queries", WithGroup[int]("db"))
+	rec := NewRecorder(10)
+	r.SetRecorder(rec)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_1")
+	Set(ctx, attrHost, "db-1.internal")
+	Set(ctx, attrQueries, 3)
+	Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+
+	for _, tt := range []struct {
+		visible []Visibility
+		want    string
+	}{
+		{nil, `{"db":{"queries":3}}`},
+		{[]Visibility{VisibilityPublic, VisibilityInternal}, `{"db":{"host":"db-1.internal","queries":3}}`},
+		{[]Visibility{VisibilitySensitive}, `{"uid":"usr_1","user":"usr_1"}`},
+	} {
+		w := httptest.NewRecorder()
+		DebugHandler(rec, tt.visible...).ServeHTTP(w, httptest.NewRequest("GET", "/debug/canonlog", nil))
+		var page struct {
+			Recent []struct {
+				Attrs map[string]any
+			}
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decoding %s: %v", w.Body, err)
+		}
+		if len(page.Recent) != 1 {
+			t.Fatalf("recent = %+v, want one line", page.Recent)
+		}
+		if got := mustMarshal(t, page.Recent[0].Attrs); got != tt.want {
+			t.Errorf("DebugHandler(%v) attrs = %s, want %s", tt.visible, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package canonlog
+
+import (
+	"context"
+	"hash/maphash"
+	"log/slog"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// Distinct is an approximate set of values, recorded by a distinct-count
+// attribute. Small sets are stored exactly, as hashes; larger ones as a
+// HyperLogLog sketch of fixed size.
+type Distinct struct {
+	sparse []uint64 // sorted, unique hashes; nil once dense
+	dense  []uint8  // HyperLogLog registers
+
+	// Neither slice is modified once created, so Distinct values may be
+	// freely copied.
+}
+
+const (
+	// distinctPrecision is the number of hash bits used to select a
+	// register; the standard error of the estimate is about
+	// 1.04/sqrt(2^distinctPrecision), or 3%.
+	distinctPrecision = 10
+	distinctRegisters = 1 << distinctPrecision
+
+	// distinctSparseMax is the largest number of hashes stored exactly,
+	// chosen so that the sparse form is never larger than the dense one.
+	distinctSparseMax = distinctRegisters / 8
+)
+
+// distinctSeed seeds the hash of every value added to a distinct-count
+// attribute, so that sketches from different lines can be merged.
+var distinctSeed = maphash.MakeSeed()
+
+// RegisterDistinctWith creates a new distinct-count attribute with the given
+// key in the specified registry. It panics if an attribute with the same key
+// has already been registered in that registry.
+//
+// Values are added to a distinct-count attribute with [AddDistinct], and the
+// attribute is emitted as the number of distinct values added. The count is
+// exact for small sets, and otherwise estimated with a standard error of
+// about 3% using a sketch of fixed size (1KiB), for things like "distinct
+// cache keys touched" where storing the full set would be too heavy:
+//
+//	var AttrCacheKeys = canonlog.RegisterDistinct("distinct_cache_keys")
+//
+//	canonlog.AddDistinct(ctx, AttrCacheKeys, key)
+func RegisterDistinctWith(r *Registry, key string, opts ...Option[Distinct]) Attr[Distinct] {
+	opts = append([]Option[Distinct]{
+		WithMerge(mergeDistinct),
+		WithCommutativeMerge[Distinct](),
+		WithValue(func(d Distinct) slog.Value {
+			return slog.Int64Value(d.Count())
+		}),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterDistinct creates a new distinct-count attribute with the given key
+// using [DefaultRegistry]. See [RegisterDistinctWith] for details.
+func RegisterDistinct(key string, opts ...Option[Distinct]) Attr[Distinct] {
+	return RegisterDistinctWith(DefaultRegistry, key, opts...)
+}
+
+// AddDistinct adds v to the distinct-count attribute attr in the [Line]
+// attached to ctx. If the context does not have a Line, AddDistinct silently
+// does nothing.
+func AddDistinct[T comparable](ctx context.Context, attr Attr[Distinct], v T) {
+	h := maphash.Comparable(distinctSeed, v)
+	Set(ctx, attr, Distinct{sparse: []uint64{h}})
+}
+
+// Count returns the exact or estimated number of distinct values in d.
+func (d Distinct) Count() int64 {
+	if d.dense == nil {
+		return int64(len(d.sparse))
+	}
+
+	var (
+		sum   float64
+		zeros int
+	)
+	for _, reg := range d.dense {
+		sum += math.Ldexp(1, -int(reg))
+		if reg == 0 {
+			zeros++
+		}
+	}
+	const m = float64(distinctRegisters)
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Small range correction: use linear counting.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// mergeDistinct returns the union of two Distinct values.
+func mergeDistinct(old, new Distinct) Distinct {
+	if old.dense == nil && new.dense == nil {
+		union := make([]uint64, 0, len(old.sparse)+len(new.sparse))
+		union = append(union, old.sparse...)
+		union = append(union, new.sparse...)
+		slices.Sort(union)
+		union = slices.Compact(union)
+		if len(union) <= distinctSparseMax {
+			return Distinct{sparse: union}
+		}
+		return Distinct{dense: denseRegisters(nil, union)}
+	}
+
+	dense := make([]uint8, distinctRegisters)
+	for _, d := range []Distinct{old, new} {
+		if d.dense == nil {
+			denseRegisters(dense, d.sparse)
+			continue
+		}
+		for i, reg := range d.dense {
+			dense[i] = max(dense[i], reg)
+		}
+	}
+	return Distinct{dense: dense}
+}
+
+// denseRegisters records each of hashes in the HyperLogLog registers dense,
+// allocating them if dense is nil, and returns the registers.
+func denseRegisters(dense []uint8, hashes []uint64) []uint8 {
+	if dense == nil {
+		dense = make([]uint8, distinctRegisters)
+	}
+	for _, h := range hashes {
+		idx := h >> (64 - distinctPrecision)
+		// Set a sentinel bit so that the rank is bounded when the
+		// remaining bits are all zero.
+		w := h<<distinctPrecision | 1<<(distinctPrecision-1)
+		rank := uint8(bits.LeadingZeros64(w) + 1)
+		dense[idx] = max(dense[idx], rank)
+	}
+	return dense
+}
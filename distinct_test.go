@@ -0,0 +1,71 @@
+package canonlog
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+func TestRegisterDistinct_Exact(t *testing.T) {
+	r := testRegistry(t)
+	attrKeys := RegisterDistinctWith(r, "distinct_keys")
+
+	ctx := New(context.Background())
+	for i := range 50 {
+		AddDistinct(ctx, attrKeys, "key-"+strconv.Itoa(i%20))
+	}
+
+	if got := Attrs(ctx)[0].Value.Int64(); got != 20 {
+		t.Errorf("distinct_keys = %d, want 20", got)
+	}
+}
+
+func TestRegisterDistinct_Estimate(t *testing.T) {
+	r := testRegistry(t)
+	attrKeys := RegisterDistinctWith(r, "distinct_keys")
+
+	for _, n := range []int{500, 5000, 50000} {
+		ctx := New(context.Background())
+		for i := range n {
+			AddDistinct(ctx, attrKeys, i)
+			AddDistinct(ctx, attrKeys, i) // duplicates do not count
+		}
+
+		got := Attrs(ctx)[0].Value.Int64()
+		// Allow four standard errors, so the test is not flaky.
+		if err := math.Abs(float64(got-int64(n))) / float64(n); err > 4*0.0325 {
+			t.Errorf("estimate for %d distinct values = %d (error %.1f%%)", n, got, err*100)
+		}
+	}
+}
+
+func TestRegisterDistinct_MergeProperties(t *testing.T) {
+	attr := RegisterDistinctWith(testRegistry(t), "distinct")
+
+	// Distinct has unexported fields, so generate values by merging
+	// random hashes, producing both sparse and dense forms.
+	cfg := &quick.Config{
+		Values: func(args []reflect.Value, rand *rand.Rand) {
+			for i := range args {
+				var d Distinct
+				n := rand.Intn(2 * distinctSparseMax)
+				for range n {
+					// Draw from a small range so that values
+					// overlap between arguments.
+					h := uint64(rand.Intn(4*distinctSparseMax)) * 0x9e3779b97f4a7c15
+					d = attr.merge(d, Distinct{sparse: []uint64{h}})
+				}
+				args[i] = reflect.ValueOf(d)
+			}
+		},
+	}
+	equal := func(a, b Distinct) bool {
+		return slices.Equal(a.sparse, b.sparse) && slices.Equal(a.dense, b.dense)
+	}
+	checkMergeProperties(t, attr, equal, cfg)
+}
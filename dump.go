@@ -0,0 +1,57 @@
+package canonlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// recordCaller records, in [ModeDebug], the location of the code setting the
+// attribute with the given key on l, for [Dump].
+func (l *Line) recordCaller(key string) {
+	if currentMode() == ModeDebug {
+		l.callers.Store(key, caller())
+	}
+}
+
+// Dump returns a description of the attributes of the [Line] attached to
+// ctx, for debugging, with one attribute per line in the order they are
+// emitted. In [ModeDebug], each attribute is followed by the location of
+// the code that last set it, so that the origin of a surprising value can be
+// found:
+//
+//	user=usr_123	(set at /src/app/auth.go:42)
+//	queries=7	(set at /src/app/db.go:118)
+//
+// Recording locations makes every Set slower, so it is only done in
+// ModeDebug, and only for attributes set while that mode is in effect. If
+// the context does not have a Line, Dump returns the empty string.
+func Dump(ctx context.Context) string {
+	l := FromContext(ctx)
+	if l == nil {
+		return ""
+	}
+
+	l.lockAll()
+	entries := l.appendOrdered(nil)
+	l.unlockAll()
+
+	var b strings.Builder
+	for _, e := range entries {
+		key, sv := e.key, e.sv
+		raw := sv.value()
+		v := slog.AnyValue(raw)
+		if sv.convert != nil {
+			v = sv.convert(raw)
+		}
+		v = v.Resolve()
+
+		fmt.Fprintf(&b, "%s=%s", key, v)
+		if site, ok := l.callers.Load(key); ok {
+			fmt.Fprintf(&b, "\t(set at %s)", site)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package canonlog
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+	attrStatus := RegisterWith(r, "status", WithGroup[int]("http"))
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123") // set before debugging is enabled
+
+	withMode(t, ModeDebug)
+	Set(ctx, attrQueries, 1)
+	Set(ctx, attrQueries, 2)
+	Set(ctx, attrStatus, 200)
+	SetMany(ctx, map[string]any{"region": "eu"})
+
+	want := regexp.MustCompile("^user=usr_123\n" +
+		"queries=3\t\\(set at .*/dump_test.go:23\\)\n" +
+		"http.status=200\t\\(set at .*/dump_test.go:24\\)\n" +
+		"region=eu\t\\(set at .*/dump_test.go:25\\)\n$")
+	if got := Dump(ctx); !want.MatchString(got) {
+		t.Errorf("Dump =\n%s\nwant match for %s", got, want)
+	}
+
+	if got := Dump(context.Background()); got != "" {
+		t.Errorf("Dump on context without Line = %q, want empty", got)
+	}
+}
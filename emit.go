@@ -0,0 +1,302 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Message is the log message used when emitting a canonical log line.
+const Message = "canonical-log-line"
+
+// attrsPool holds scratch slices for the attributes of lines being emitted.
+var attrsPool = sync.Pool{
+	New: func() any { return new([]slog.Attr) },
+}
+
+// Emitter emits canonical log lines to a logger, applying its configured
+// options, such as sampling. An Emitter is safe for concurrent use and is
+// typically created once at startup with [NewEmitter].
+type Emitter struct {
+	logger *slog.Logger
+
+	sampler     Sampler
+	onSample    func(context.Context, SampleDecision)
+	sampleAttrs bool
+
+	keyPrefix string
+	keyMap    func(string) string
+	keys      map[string]bool // see WithKeys
+
+	visibility visibilitySet // see WithAllowedVisibility
+
+	enrichers []Enricher
+	tees      []*Emitter
+	async     *Async // see WithAsync
+}
+
+// EmitterOption configures an [Emitter].
+type EmitterOption func(*Emitter)
+
+// NewEmitter creates an [Emitter] that logs to logger. If logger is nil,
+// [slog.Default] is used at the time each line is emitted, and the Emitter
+// is configured with any options given to [Install] before opts.
+func NewEmitter(logger *slog.Logger, opts ...EmitterOption) *Emitter {
+	e := &Emitter{logger: logger}
+	if p := installed.Load(); p != nil && logger == nil {
+		for _, opt := range *p {
+			opt(e)
+		}
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithKeyPrefix makes the [Emitter] prefix the key of every top-level
+// attribute it emits with prefix, such as "canon.", so that canonical-line
+// attributes can be told apart from attributes added by handlers in a shared
+// logging pipeline. Samplers see the attributes without the prefix.
+func WithKeyPrefix(prefix string) EmitterOption {
+	return func(e *Emitter) {
+		e.keyPrefix = prefix
+	}
+}
+
+// WithKeyMap makes the [Emitter] translate the key of every top-level
+// attribute it emits with fn, so that the keys used in code can stay stable
+// while the output conforms to field names required elsewhere, such as by a
+// vendor during a migration. Keys are translated before any
+// [WithKeyPrefix] prefix is added, and samplers see the original keys.
+func WithKeyMap(fn func(key string) string) EmitterOption {
+	return func(e *Emitter) {
+		e.keyMap = fn
+	}
+}
+
+// WithKeyRenames is like [WithKeyMap], renaming each top-level key found in
+// renames to the corresponding value and leaving other keys unchanged.
+func WithKeyRenames(renames map[string]string) EmitterOption {
+	return WithKeyMap(func(key string) string {
+		if renamed, ok := renames[key]; ok {
+			return renamed
+		}
+		return key
+	})
+}
+
+// WithKeys makes the [Emitter] emit only the top-level attributes with the
+// given keys, such as for a trimmed view of lines for humans alongside the
+// complete lines sent to analytics (see [WithTee]). The key of a group
+// attribute is the group's name. Samplers see all the attributes, and the
+// attributes added by [WithSampleAttrs] are always emitted.
+func WithKeys(keys ...string) EmitterOption {
+	return func(e *Emitter) {
+		if e.keys == nil {
+			e.keys = make(map[string]bool, len(keys))
+		}
+		for _, k := range keys {
+			e.keys[k] = true
+		}
+	}
+}
+
+// WithTee makes the [Emitter] also emit each line through others, each
+// applying its own options, so that a single call emits several views of
+// the same line, such as complete lines to an analytics sink and lines
+// trimmed [WithKeys] to the human-readable log stream:
+//
+//	human := canonlog.NewEmitter(consoleLogger,
+//		canonlog.WithKeys("http_method", "http_path", "http_status", "duration", "error"),
+//	)
+//	emitter := canonlog.NewEmitter(analyticsLogger, canonlog.WithTee(human))
+//
+// Each view is sampled independently, and the line's emit hooks (see
+// [Registry.OnEmit]) run for each.
+func WithTee(others ...*Emitter) EmitterOption {
+	return func(e *Emitter) {
+		e.tees = append(e.tees, others...)
+	}
+}
+
+// Emit logs the canonical log line attached to ctx at the given level. If
+// the context does not have a [Line], Emit does nothing.
+//
+// Once emitted, the Line is frozen: attributes set on it afterwards are
+// dropped and recorded as described for [LateSetsKey]. Emitting it again
+// logs the same attributes.
+func (e *Emitter) Emit(ctx context.Context, level slog.Level) {
+	e.emit(ctx, level)
+}
+
+// emit is the shared implementation of [Emitter.Emit] and
+// [Emitter.EmitOnReturn], which freezes the line and then logs it; any extra
+// attributes are logged after the line's own attributes.
+func (e *Emitter) emit(ctx context.Context, level slog.Level, extra ...slog.Attr) {
+	l := FromContext(ctx)
+	if l == nil {
+		return
+	}
+	first := l.freeze()
+	if first {
+		l.record(level, extra)
+	}
+	e.logAll(ctx, level, first, extra...)
+	if first && l.pooled {
+		l.release()
+	}
+}
+
+// logAll logs the line attached to ctx through e and the emitters given to
+// WithTee. If observe is set, the logged hooks of the line's registry (see
+// Registry.OnLogged) run for the first of them that keeps the line, and
+// logAll reports whether one did.
+func (e *Emitter) logAll(ctx context.Context, level slog.Level, observe bool, extra ...slog.Attr) bool {
+	observed := e.log(ctx, level, observe, extra...)
+	for _, t := range e.tees {
+		if t.logAll(ctx, level, observe && !observed, extra...) {
+			observed = true
+		}
+	}
+	return observed
+}
+
+// log is like emit but does not freeze the line, for emitting lines for
+// operations that are still in progress. If observe is set and the line is
+// kept, it runs the logged hooks of the line's registry and reports true.
+func (e *Emitter) log(ctx context.Context, level slog.Level, observe bool, extra ...slog.Attr) bool {
+	l := FromContext(ctx)
+	if l == nil {
+		return false
+	}
+
+	scratch := attrsPool.Get().(*[]slog.Attr)
+	attrs := l.appendAttrs(*scratch, e.visibility)
+	defer func() {
+		clear(attrs) // drop references to values
+		*scratch = attrs[:0]
+		attrsPool.Put(scratch)
+	}()
+
+	for _, en := range e.enrichers {
+		attrs = append(attrs, en.Enrich(ctx)...)
+	}
+	attrs = append(attrs, extra...)
+	attrs = l.runEmitHooks(ctx, attrs)
+
+	var decision SampleDecision
+	if e.sampler != nil {
+		decision = e.sampler.Sample(ctx, level, attrs)
+		if e.onSample != nil {
+			e.onSample(ctx, decision)
+		}
+		if !decision.Keep {
+			linesSampledOut.Add(1)
+			return false
+		}
+	}
+	if observe {
+		l.runLoggedHooks(ctx, level, attrs)
+	}
+	if e.keys != nil {
+		attrs = slices.DeleteFunc(attrs, func(a slog.Attr) bool { return !e.keys[a.Key] })
+	}
+	if e.sampler != nil && e.sampleAttrs {
+		attrs = append(attrs, decision.attrs()...)
+	}
+
+	if e.keyMap != nil || e.keyPrefix != "" {
+		for i := range attrs {
+			if e.keyMap != nil {
+				attrs[i].Key = e.keyMap(attrs[i].Key)
+			}
+			attrs[i].Key = e.keyPrefix + attrs[i].Key
+		}
+	}
+
+	logger := e.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if e.async != nil {
+		// attrs is reused once log returns, and the line's lazy values
+		// must not be read once it has been emitted. Nor must the line
+		// itself, which may be reset for reuse (see WithPooledLines), so
+		// the handler is given a context without it.
+		queued := slices.Clone(attrs)
+		for i := range queued {
+			queued[i].Value = queued[i].Value.Resolve()
+		}
+		qctx := NewContext(context.WithoutCancel(ctx), nil)
+		l := queuedLine{ctx: qctx, logger: logger, level: level, attrs: queued}
+		if ok, _ := e.async.enqueue(context.Background(), l); ok {
+			return observe
+		}
+	}
+	logger.LogAttrs(ctx, level, Message, attrs...)
+	linesEmitted.Add(1)
+	return observe
+}
+
+// EmitOnReturn returns a function that emits the canonical log line attached
+// to ctx. It is intended to be deferred with a pointer to the function's named
+// error result, so that the error is read after the function returns:
+//
+//	func handle(ctx context.Context) (err error) {
+//		ctx = canonlog.New(ctx)
+//		defer emitter.EmitOnReturn(ctx, &err)()
+//		...
+//	}
+//
+// If the error is nil, the line is emitted at [slog.LevelInfo] with
+// outcome=success. Otherwise it is emitted at [slog.LevelError] with
+// outcome=error and the error recorded under the "error" key. errp may be
+// nil, in which case the outcome is always success.
+func (e *Emitter) EmitOnReturn(ctx context.Context, errp *error) func() {
+	return func() {
+		var err error
+		if errp != nil {
+			err = *errp
+		}
+		level, attrs := outcome(err)
+		e.emit(ctx, level, attrs...)
+	}
+}
+
+// outcome returns the level and attributes with which [Emitter.EmitOnReturn]
+// emits a line for an operation that returned err.
+func outcome(err error) (slog.Level, []slog.Attr) {
+	if err == nil {
+		return slog.LevelInfo, []slog.Attr{slog.String("outcome", "success")}
+	}
+	return slog.LevelError, []slog.Attr{
+		slog.String("outcome", "error"),
+		slog.String("error", err.Error()),
+	}
+}
+
+// Emit logs the canonical log line attached to ctx to logger at the given
+// level. If logger is nil, [slog.Default] is used. If the context does not
+// have a [Line], Emit does nothing.
+//
+// Emit is shorthand for NewEmitter(logger).Emit(ctx, level); use an
+// [Emitter] to configure options such as sampling.
+func Emit(ctx context.Context, logger *slog.Logger, level slog.Level) {
+	NewEmitter(logger).Emit(ctx, level)
+}
+
+// EmitOnReturn returns a function that emits the canonical log line attached
+// to ctx to logger, deriving the level and outcome from the error pointed to
+// by errp. It is shorthand for NewEmitter(logger).EmitOnReturn(ctx, errp); see
+// [Emitter.EmitOnReturn] for details.
+//
+//	func handle(ctx context.Context) (err error) {
+//		ctx = canonlog.New(ctx)
+//		defer canonlog.EmitOnReturn(ctx, logger, &err)()
+//		...
+//	}
+func EmitOnReturn(ctx context.Context, logger *slog.Logger, errp *error) func() {
+	return NewEmitter(logger).EmitOnReturn(ctx, errp)
+}
@@ -0,0 +1,171 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// testLogger returns a logger that writes text output without timestamps to
+// buf, for deterministic comparisons.
+func testLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestEmit(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	var buf bytes.Buffer
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Emit(ctx, testLogger(&buf), slog.LevelWarn)
+
+	want := "level=WARN msg=canonical-log-line user=usr_123\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestEmitWithoutLine(t *testing.T) {
+	var buf bytes.Buffer
+	Emit(context.Background(), testLogger(&buf), slog.LevelInfo)
+
+	if buf.Len() != 0 {
+		t.Errorf("Emit on context without Line wrote %q, want nothing", buf.String())
+	}
+}
+
+func TestEmitOnReturn(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	handle := func(ctx context.Context, logger *slog.Logger, fail bool) (err error) {
+		ctx = New(ctx)
+		defer EmitOnReturn(ctx, logger, &err)()
+
+		Set(ctx, attrUser, "usr_123")
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		fail bool
+		want string
+	}{
+		{
+			name: "success",
+			want: "level=INFO msg=canonical-log-line user=usr_123 outcome=success\n",
+		},
+		{
+			name: "error",
+			fail: true,
+			want: "level=ERROR msg=canonical-log-line user=usr_123 outcome=error error=boom\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handle(context.Background(), testLogger(&buf), tt.fail)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("log output:\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithKeyPrefix(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries", WithGroup[int]("db"))
+
+	var (
+		buf     bytes.Buffer
+		sampled []string
+	)
+	e := NewEmitter(testLogger(&buf),
+		WithKeyPrefix("canon."),
+		WithSampler(SamplerFunc(func(ctx context.Context, level slog.Level, attrs []slog.Attr) SampleDecision {
+			for _, a := range attrs {
+				sampled = append(sampled, a.Key)
+			}
+			return SampleDecision{Keep: true, Rate: 1}
+		})),
+	)
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrQueries, 4)
+	e.EmitOnReturn(ctx, nil)()
+
+	want := "level=INFO msg=canonical-log-line canon.user=usr_123 canon.db.queries=4 canon.outcome=success\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+	if want := "user db outcome"; strings.Join(sampled, " ") != want {
+		t.Errorf("sampler saw keys %q, want %q", sampled, want)
+	}
+}
+
+func TestWithKeyRenames(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrStatus := RegisterWith[int](r, "status")
+
+	var buf bytes.Buffer
+	e := NewEmitter(testLogger(&buf),
+		WithKeyRenames(map[string]string{"user": "usr.id", "outcome": "evt.outcome"}),
+		WithKeyPrefix("canon."),
+	)
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrStatus, 200)
+	e.EmitOnReturn(ctx, nil)()
+
+	want := "level=INFO msg=canonical-log-line canon.usr.id=usr_123 canon.status=200 canon.evt.outcome=success\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestWithTee(t *testing.T) {
+	r := testRegistry(t)
+	attrPath := RegisterWith[string](r, "path")
+	attrStatus := RegisterWith[int](r, "status")
+	attrDBTime := RegisterWith(r, "time", WithGroup[int]("db"))
+
+	var full, human bytes.Buffer
+	humanEmitter := NewEmitter(testLogger(&human),
+		WithKeys("path", "status", "outcome"),
+		WithSampler(RuleSampler(SampleRule{Name: "all", Rate: 1})),
+		WithSampleAttrs(),
+	)
+	e := NewEmitter(testLogger(&full), WithTee(humanEmitter))
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrPath, "/users")
+	Set(ctx, attrStatus, 200)
+	Set(ctx, attrDBTime, 12)
+	e.EmitOnReturn(ctx, nil)()
+
+	if got, want := full.String(), "level=INFO msg=canonical-log-line path=/users status=200 db.time=12 outcome=success\n"; got != want {
+		t.Errorf("full output:\ngot:  %q\nwant: %q", got, want)
+	}
+	if got, want := human.String(), "level=INFO msg=canonical-log-line path=/users status=200 outcome=success sample_rule=all sample_rate=1\n"; got != want {
+		t.Errorf("human output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package canonlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// EncryptionFailed is emitted in place of the value of an attribute
+// registered [WithEncrypt] if it could not be encrypted, so that the
+// plaintext is never emitted.
+const EncryptionFailed = "!ENCRYPTION_FAILED"
+
+// An Encryptor encrypts attribute values, such as with a key from a key
+// management service, for [WithEncrypt]. It must be safe for concurrent use.
+type Encryptor interface {
+	// Encrypt returns the ciphertext of plaintext, the value of the
+	// attribute with the given key, in a form that can be emitted as a
+	// string, such as base64.
+	Encrypt(key string, plaintext []byte) (string, error)
+}
+
+// WithEncrypt makes the attribute emit its values encrypted with enc, so
+// that sensitive values are present in the line for authorized offline
+// decryption, but unreadable to anyone else with access to the logs:
+//
+//	var AttrEmail = canonlog.Register("email",
+//		canonlog.WithEncrypt[string](kmsEncryptor),
+//	)
+//
+// Values are stored unencrypted in the line, and encrypted whenever its
+// attributes are read, such as when it is emitted. The plaintext is the
+// value as converted by the attribute's [WithValue] function, if any:
+// strings as is, and other values encoded as JSON, with groups as objects. If enc fails, the value
+// is emitted as [EncryptionFailed], and the error is logged to
+// [slog.Default] in [ModeDebug] or causes a panic in [ModeStrict].
+func WithEncrypt[T any](enc Encryptor) Option[T] {
+	return func(a *Attr[T]) {
+		a.encryptor = enc
+	}
+}
+
+// encrypting returns a converter calling convert, or [slog.AnyValue] if it
+// is nil, and encrypting the result with enc.
+func encrypting(key string, convert func(any) slog.Value, enc Encryptor) func(any) slog.Value {
+	return func(v any) slog.Value {
+		var sv slog.Value
+		if convert != nil {
+			sv = convert(v)
+		} else {
+			sv = slog.AnyValue(v)
+		}
+		sv = sv.Resolve()
+
+		var plaintext []byte
+		if sv.Kind() == slog.KindString {
+			plaintext = []byte(sv.String())
+		} else {
+			var err error
+			if plaintext, err = json.Marshal(jsonValue(sv)); err != nil {
+				return encryptionFailed(key, err)
+			}
+		}
+		ciphertext, err := enc.Encrypt(key, plaintext)
+		if err != nil {
+			return encryptionFailed(key, err)
+		}
+		return slog.StringValue(ciphertext)
+	}
+}
+
+// jsonValue returns v, which must be resolved, in a form that encodes to
+// JSON as the value it holds, with groups as objects.
+func jsonValue(v slog.Value) any {
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+	m := make(map[string]any)
+	for _, a := range v.Group() {
+		m[a.Key] = jsonValue(a.Value.Resolve())
+	}
+	return m
+}
+
+// encryptionFailed handles a failure to encrypt the value of the attribute
+// with the given key according to the current [Mode], and returns the value
+// to emit in its place.
+func encryptionFailed(key string, err error) slog.Value {
+	switch currentMode() {
+	case ModeDebug:
+		slog.Warn("canonlog: attribute value could not be encrypted", "key", key, "error", err)
+	case ModeStrict:
+		panic(fmt.Sprintf("canonlog: value of attribute %q could not be encrypted: %v", key, err))
+	}
+	return slog.StringValue(EncryptionFailed)
+}
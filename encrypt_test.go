@@ -0,0 +1,54 @@
+package canonlog
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+// testEncryptor "encrypts" values by base64-encoding them with the key.
+type testEncryptor struct{ err error }
+
+func (e testEncryptor) Encrypt(key string, plaintext []byte) (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+	return key + ":" + base64.StdEncoding.EncodeToString(plaintext), nil
+}
+
+func TestWithEncrypt(t *testing.T) {
+	r := testRegistry(t)
+	attrEmail := RegisterWith(r, "email", WithEncrypt[string](testEncryptor{}))
+	attrCard := RegisterWith(r, "card",
+		WithValue(func(last4 int) slog.Value { return slog.GroupValue(slog.Int("last4", last4)) }),
+		WithEncrypt[int](testEncryptor{}),
+	)
+	attrAge := RegisterWith(r, "age", WithEncrypt[int](testEncryptor{}))
+	attrBroken := RegisterWith(r, "broken", WithEncrypt[string](testEncryptor{err: errors.New("kms down")}))
+	attrRegion := RegisterWith(r, "region", WithEncrypt[string](testEncryptor{}))
+	SetGlobalWith(r, attrRegion, "eu")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrEmail, "alice@example.com")
+	Set(ctx, attrCard, 4242)
+	Set(ctx, attrAge, 42)
+	Set(ctx, attrBroken, "secret")
+
+	enc := func(key, plaintext string) string {
+		return key + ":" + base64.StdEncoding.EncodeToString([]byte(plaintext))
+	}
+	want := []slog.Attr{
+		slog.String("email", enc("email", "alice@example.com")),
+		slog.String("card", enc("card", `{"last4":4242}`)),
+		slog.String("age", enc("age", "42")),
+		slog.String("broken", EncryptionFailed),
+		slog.String("region", enc("region", "eu")),
+	}
+	got := Attrs(ctx)
+	if !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
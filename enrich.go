@@ -0,0 +1,53 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// An Enricher contributes attributes to canonical log lines, such as
+// information about the environment the process runs in, so that platform
+// teams can include the same context on every line without each service
+// setting it. Enrichers are run when a line is created, if given to
+// [WithLineEnricher], or when it is emitted, if given to [WithEnricher].
+//
+// Enrich must be safe for concurrent use, and should be fast; information
+// that does not change, such as the host the process runs on, should be
+// looked up once, ahead of time.
+type Enricher interface {
+	Enrich(ctx context.Context) []slog.Attr
+}
+
+// EnricherFunc is an adapter to allow the use of an ordinary function as an
+// [Enricher].
+type EnricherFunc func(ctx context.Context) []slog.Attr
+
+// Enrich calls f(ctx).
+func (f EnricherFunc) Enrich(ctx context.Context) []slog.Attr {
+	return f(ctx)
+}
+
+// StaticEnricher returns an [Enricher] that always contributes attrs.
+func StaticEnricher(attrs ...slog.Attr) Enricher {
+	return EnricherFunc(func(context.Context) []slog.Attr {
+		return attrs
+	})
+}
+
+// WithLineEnricher runs each of enrichers when the new [Line] is created,
+// with the context returned by [New], and includes the attributes they
+// contribute in the line after those set on it.
+func WithLineEnricher(enrichers ...Enricher) LineOption {
+	return func(l *Line) {
+		l.enrichers = append(l.enrichers, enrichers...)
+	}
+}
+
+// WithEnricher makes the [Emitter] run each of enrichers every time it
+// emits a line, and include the attributes they contribute in the line
+// after its own attributes. Samplers see the contributed attributes.
+func WithEnricher(enrichers ...Enricher) EmitterOption {
+	return func(e *Emitter) {
+		e.enrichers = append(e.enrichers, enrichers...)
+	}
+}
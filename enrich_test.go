@@ -0,0 +1,46 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestEnricher(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	type tenantKey struct{}
+	tenant := EnricherFunc(func(ctx context.Context) []slog.Attr {
+		if FromContext(ctx) == nil {
+			t.Error("line enricher called without the new Line in its context")
+		}
+		return []slog.Attr{slog.Any("tenant", ctx.Value(tenantKey{}))}
+	})
+
+	var (
+		buf   bytes.Buffer
+		calls int
+	)
+	e := NewEmitter(testLogger(&buf), WithEnricher(
+		StaticEnricher(slog.String("k8s.pod", "web-1")),
+		EnricherFunc(func(ctx context.Context) []slog.Attr {
+			calls++
+			return nil
+		}),
+	))
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = New(ctx, WithLineEnricher(tenant))
+	Set(ctx, attrUser, "usr_123")
+	e.EmitOnReturn(ctx, nil)()
+
+	want := "level=INFO msg=canonical-log-line user=usr_123 tenant=acme k8s.pod=web-1 outcome=success\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+	if calls != 1 {
+		t.Errorf("emit-time enricher called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,100 @@
+package canonlog
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrNotAllowed is wrapped by the error that [TrySet] returns for a value
+// of an attribute registered with [RegisterEnum] that is not one of its
+// allowed values.
+var ErrNotAllowed = errors.New("canonlog: value not allowed")
+
+// RegisterEnumWith creates a new string attribute in r, like [RegisterWith],
+// that may only be set to one of the given values, so that dimensions used
+// in analytics stay low-cardinality. Other values are rejected as by a
+// validator given to [WithValidator], unless the attribute is registered
+// [WithEnumFallback]. It panics if no values are given.
+//
+// The allowed values are returned by [Registry.Enums].
+func RegisterEnumWith(r *Registry, key string, values []string, opts ...Option[string]) Attr[string] {
+	if len(values) == 0 {
+		panic("canonlog: enum attribute without values: " + key)
+	}
+	allowed := slices.Clone(values)
+	enum := func(a *Attr[string]) {
+		if a.enumFallback != "" {
+			fallback := a.enumFallback
+			if !slices.Contains(allowed, fallback) {
+				allowed = append(allowed, fallback)
+			}
+			a.clamp = func(v any) (any, bool) {
+				if s, ok := v.(string); ok && !slices.Contains(allowed, s) {
+					return fallback, true
+				}
+				return v, false
+			}
+			return
+		}
+		validate := a.validate
+		a.validate = func(s string) error {
+			if !slices.Contains(allowed, s) {
+				return fmt.Errorf("%w: %q", ErrNotAllowed, s)
+			}
+			if validate != nil {
+				return validate(s)
+			}
+			return nil
+		}
+	}
+	attr := RegisterWith(r, key, append(slices.Clip(opts), enum)...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.enums == nil {
+		r.enums = make(map[string][]string)
+	}
+	r.enums[attr.key] = allowed
+	return attr
+}
+
+// RegisterEnum creates a new string attribute using [DefaultRegistry] that
+// may only be set to one of the given values, as described for
+// [RegisterEnumWith]:
+//
+//	var AttrOutcome = canonlog.RegisterEnum("outcome", "success", "client_error", "server_error")
+func RegisterEnum(key string, values ...string) Attr[string] {
+	return RegisterEnumWith(DefaultRegistry, key, values)
+}
+
+// WithEnumFallback makes an attribute registered with [RegisterEnumWith]
+// emit values that are not allowed as fallback, marking the line with
+// [SanitizedKey], rather than reject them:
+//
+//	var AttrOutcome = canonlog.RegisterEnumWith(canonlog.DefaultRegistry, "outcome",
+//		[]string{"success", "client_error", "server_error"},
+//		canonlog.WithEnumFallback("other"),
+//	)
+//
+// Values are stored as set, and replaced whenever the line's attributes are
+// read. The fallback is added to the allowed values if it is not one of
+// them. WithEnumFallback has no effect on other attributes.
+func WithEnumFallback(fallback string) Option[string] {
+	return func(a *Attr[string]) {
+		a.enumFallback = fallback
+	}
+}
+
+// Enums returns the allowed values of the attributes registered in r with
+// [RegisterEnumWith], by key, for exporting the registry's schema, such as
+// to constrain columns in a data warehouse.
+func (r *Registry) Enums() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enums := make(map[string][]string, len(r.enums))
+	for key, values := range r.enums {
+		enums[key] = slices.Clone(values)
+	}
+	return enums
+}
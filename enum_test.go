@@ -0,0 +1,41 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestRegisterEnum(t *testing.T) {
+	r := testRegistry(t)
+	attrOutcome := RegisterEnumWith(r, "outcome", []string{"success", "error"})
+	attrTier := RegisterEnumWith(r, "tier", []string{"free", "paid"}, WithEnumFallback("other"))
+
+	ctx := NewContext(context.Background(), NewLine(WithRegistry(r)))
+	Set(ctx, attrOutcome, "success")
+	if err := TrySet(ctx, attrOutcome, "oops"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("TrySet with a value not allowed = %v, want ErrNotAllowed", err)
+	}
+	Set(ctx, attrOutcome, "oops") // dropped
+	Set(ctx, attrTier, "enterprise")
+
+	want := []slog.Attr{
+		slog.String("outcome", "success"),
+		slog.String("tier", "other"),
+		slog.Bool(SanitizedKey, true),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+
+	wantEnums := map[string][]string{
+		"outcome": {"success", "error"},
+		"tier":    {"free", "paid", "other"},
+	}
+	if got := r.Enums(); !maps.EqualFunc(got, wantEnums, slices.Equal) {
+		t.Errorf("Enums = %v, want %v", got, wantEnums)
+	}
+}
@@ -0,0 +1,152 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+)
+
+// maxErrors is the number of errors an [Errors] value retains.
+const maxErrors = 8
+
+// Errors is a collection of errors, recorded by an error-collection
+// attribute. It counts every error added to it, but only retains the first
+// few, along with the most severe.
+//
+// The fields of Errors are never modified once set, so Errors values may be
+// freely copied.
+type Errors struct {
+	n     int
+	errs  []error // the first maxErrors errors
+	worst error   // the most severe error, if not in errs
+}
+
+// RegisterErrorsWith creates a new error-collection attribute with the given
+// key in the specified registry. It panics if an attribute with the same key
+// has already been registered in that registry.
+//
+// Errors are added to an error-collection attribute with [AddError], for
+// requests that partially fail across several sub-operations, and the
+// attribute is emitted as a group holding the number of errors added, the
+// message of the first, and, if different, the message of the most severe:
+//
+//	var AttrErrors = canonlog.RegisterErrors("errors")
+//
+//	for _, id := range ids {
+//		if err := notify(ctx, id); err != nil {
+//			canonlog.AddError(ctx, AttrErrors, err)
+//		}
+//	}
+//
+// which emits, for example, errors.count=3 errors.first="notify 12: timeout".
+//
+// The severity of an error is the level returned by a Level() [slog.Level]
+// method of the first error in its tree that has one, as found by
+// [errors.As], and [slog.LevelError] if there is none. Of errors of equal
+// severity, the one added first is the most severe.
+func RegisterErrorsWith(r *Registry, key string, opts ...Option[Errors]) Attr[Errors] {
+	opts = append([]Option[Errors]{
+		WithMerge(mergeErrors),
+		WithValue(Errors.value),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterErrors creates a new error-collection attribute with the given key
+// using [DefaultRegistry]. See [RegisterErrorsWith] for details.
+func RegisterErrors(key string, opts ...Option[Errors]) Attr[Errors] {
+	return RegisterErrorsWith(DefaultRegistry, key, opts...)
+}
+
+// AddError adds err to the error-collection attribute attr in the [Line]
+// attached to ctx. A nil err is ignored. If the context does not have a
+// Line, AddError silently does nothing.
+func AddError(ctx context.Context, attr Attr[Errors], err error) {
+	if err == nil {
+		return
+	}
+	Set(ctx, attr, Errors{n: 1, errs: []error{err}})
+}
+
+// Len returns the number of errors added to e.
+func (e Errors) Len() int {
+	return e.n
+}
+
+// Err returns the errors retained by e joined with [errors.Join], or nil if
+// e is empty. At most the first eight errors, and the most severe, are
+// retained.
+func (e Errors) Err() error {
+	if e.worst != nil {
+		return errors.Join(append(slices.Clip(e.errs), e.worst)...)
+	}
+	return errors.Join(e.errs...)
+}
+
+// value returns e as emitted by an error-collection attribute.
+func (e Errors) value() slog.Value {
+	if len(e.errs) == 0 {
+		return slog.GroupValue(slog.Int("count", e.n))
+	}
+	attrs := []slog.Attr{
+		slog.Int("count", e.n),
+		slog.String("first", e.errs[0].Error()),
+	}
+	if worst, first := e.mostSevere(); !first {
+		attrs = append(attrs, slog.String("worst", worst.Error()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// mostSevere returns the most severe error of e, which must not be empty,
+// and whether it is the first. Errors are not compared with ==, which
+// panics for some types.
+func (e Errors) mostSevere() (worst error, first bool) {
+	worst, first = e.errs[0], true
+	for _, err := range e.errs[1:] {
+		if errorLevel(err) > errorLevel(worst) {
+			worst, first = err, false
+		}
+	}
+	if e.worst != nil && errorLevel(e.worst) > errorLevel(worst) {
+		worst, first = e.worst, false
+	}
+	return worst, first
+}
+
+// mergeErrors returns the errors of old followed by those of new.
+func mergeErrors(old, new Errors) Errors {
+	merged := Errors{
+		n:    old.n + new.n,
+		errs: append(slices.Clip(old.errs), new.errs...),
+	}
+	if len(merged.errs) == 0 {
+		return merged
+	}
+	var dropped []error
+	if len(merged.errs) > maxErrors {
+		merged.errs, dropped = merged.errs[:maxErrors:maxErrors], merged.errs[maxErrors:]
+	}
+
+	// Keep the most severe of the errors that are no longer retained
+	// otherwise, if it is more severe than those that are.
+	candidates := append(slices.Clip(dropped), old.worst, new.worst)
+	worst, _ := Errors{errs: merged.errs}.mostSevere()
+	for _, err := range candidates {
+		if err != nil && errorLevel(err) > errorLevel(worst) {
+			worst, merged.worst = err, err
+		}
+	}
+	return merged
+}
+
+// errorLevel returns the severity of err, as described for
+// [RegisterErrorsWith].
+func errorLevel(err error) slog.Level {
+	var l interface{ Level() slog.Level }
+	if errors.As(err, &l) {
+		return l.Level()
+	}
+	return slog.LevelError
+}
@@ -0,0 +1,84 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+type levelError struct {
+	msg   string
+	level slog.Level
+}
+
+func (e levelError) Error() string     { return e.msg }
+func (e levelError) Level() slog.Level { return e.level }
+
+func TestRegisterErrors(t *testing.T) {
+	r := testRegistry(t)
+	attrErrors := RegisterErrorsWith(r, "errors")
+
+	ctx := New(context.Background())
+	AddError(ctx, attrErrors, nil) // ignored
+	AddError(ctx, attrErrors, levelError{"stale cache", slog.LevelWarn})
+	for i := range 10 {
+		AddError(ctx, attrErrors, fmt.Errorf("notify %d: timeout", i))
+	}
+	// Not retained among the first errors, but the most severe.
+	AddError(ctx, attrErrors, fmt.Errorf("notify: %w", levelError{"disk full", slog.LevelError + 4}))
+
+	want := []slog.Attr{
+		slog.Group("errors",
+			slog.Int("count", 12),
+			slog.String("first", "stale cache"),
+			slog.String("worst", "notify: disk full"),
+		),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
+
+func TestErrors_Err(t *testing.T) {
+	var e Errors
+	if err := e.Err(); err != nil {
+		t.Errorf("Err of no errors = %v, want nil", err)
+	}
+	for i := range 10 {
+		e = mergeErrors(e, Errors{n: 1, errs: []error{fmt.Errorf("error %d", i)}})
+	}
+	e = mergeErrors(e, Errors{n: 1, errs: []error{levelError{"fatal", slog.LevelError + 4}}})
+
+	if got := e.Len(); got != 11 {
+		t.Errorf("Len = %d, want 11", got)
+	}
+	errs := e.Err().(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != maxErrors+1 {
+		t.Fatalf("Err wraps %d errors, want %d", len(errs), maxErrors+1)
+	}
+	var le levelError
+	if !errors.As(e.Err(), &le) {
+		t.Errorf("Err = %v, want it to wrap the most severe error", e.Err())
+	}
+}
+
+func TestRegisterErrors_WorstIsFirst(t *testing.T) {
+	attrErrors := RegisterErrorsWith(testRegistry(t), "errors")
+
+	ctx := New(context.Background())
+	AddError(ctx, attrErrors, errors.New("connection refused"))
+	AddError(ctx, attrErrors, levelError{"retrying", slog.LevelWarn})
+
+	want := []slog.Attr{
+		slog.Group("errors",
+			slog.Int("count", 2),
+			slog.String("first", "connection refused"),
+		),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
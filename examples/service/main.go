@@ -0,0 +1,178 @@
+// Command service is a small HTTP service demonstrating how the pieces of
+// canonlog fit together in a real program:
+//
+//   - a canonical log line per request via [canonlog.Middleware], with
+//     attributes set from handlers;
+//   - database transactions recorded in the line by [canontx];
+//   - outbound HTTP calls made through [canonlog.Transport], which carries
+//     the request's trace context on to the upstream service, and accounted
+//     for in the caller's line;
+//   - OpenTelemetry trace context and baggage correlated with the line by
+//     [canonotel];
+//   - sampling with [canonlog.WithSampler], keeping every error and every
+//     traced request, and a fraction of the rest.
+//
+// Its tests show how to check the lines a service emits by recording them
+// with a [canonlog.Recorder].
+//
+// Run it with:
+//
+//	go run ./service -addr :8080 -upstream https://example.com
+//
+// and request http://localhost:8080/users/123. To record transactions, link
+// a database/sql driver into the binary and pass -db-driver and -db.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canonotel"
+	"github.com/andrew-d/canonlog/canontx"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Attributes set by this service. Registering them at package level means a
+// duplicate key is caught at startup rather than in production output.
+var (
+	AttrUserID = canonlog.Register[string]("user_id")
+
+	AttrUpstreamCalls = canonlog.Register[int]("upstream_calls",
+		canonlog.WithMerge(func(old, new int) int { return old + new }))
+	AttrUpstreamErrors = canonlog.Register[int]("upstream_errors",
+		canonlog.WithMerge(func(old, new int) int { return old + new }))
+	AttrUpstreamTime = canonlog.Register[time.Duration]("upstream_time",
+		canonlog.WithMerge(func(old, new time.Duration) time.Duration { return old + new }))
+)
+
+// errorsRule keeps every line logged at error level.
+var errorsRule = canonlog.SampleRule{
+	Name:  "errors",
+	Match: func(level slog.Level, _ []slog.Attr) bool { return level >= slog.LevelError },
+	Rate:  1,
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	upstream := flag.String("upstream", "", "optional upstream URL to fetch for each request")
+	dbDriver := flag.String("db-driver", "", "optional database/sql driver name, which must be linked in")
+	dsn := flag.String("db", "", "data source name of the database to use with -db-driver")
+	sampleRate := flag.Float64("sample-rate", 0.1, "fraction of successful untraced requests to log")
+	flag.Parse()
+
+	var db *sql.DB
+	if *dbDriver != "" {
+		var err error
+		if db, err = sql.Open(*dbDriver, *dsn); err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	sampler := canonotel.TraceSampler(canonlog.RuleSampler(
+		errorsRule,
+		canonlog.SampleRule{Name: "default", Rate: *sampleRate},
+	))
+	srv := &http.Server{
+		Addr:    *addr,
+		Handler: newHandler(logger, sampler, db, *upstream, http.DefaultTransport),
+	}
+
+	logger.Info("listening", "addr", *addr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newHandler returns the service's root handler, which logs the lines kept
+// by sampler to logger. If db is not nil, each request updates the user in
+// a transaction. Outbound requests to upstream are made through transport.
+func newHandler(logger *slog.Logger, sampler canonlog.Sampler, db *sql.DB, upstream string, transport http.RoundTripper) http.Handler {
+	client := &http.Client{Transport: &canonlog.Transport{Base: transport}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := r.PathValue("id")
+		canonlog.Set(ctx, AttrUserID, id)
+
+		if db != nil {
+			err := canontx.Do(ctx, db, nil, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "UPDATE users SET last_seen = CURRENT_TIMESTAMP WHERE id = $1", id)
+				return err
+			})
+			if err != nil {
+				http.Error(w, "database unavailable", http.StatusInternalServerError)
+				return
+			}
+		}
+		if upstream != "" {
+			if err := fetch(ctx, client, upstream); err != nil {
+				http.Error(w, "upstream unavailable", http.StatusBadGateway)
+				return
+			}
+		}
+		fmt.Fprintf(w, "hello, %s\n", id)
+	})
+
+	h := canonlog.Middleware(logger,
+		canonlog.WithTraceparent(),
+		canonlog.WithLineOptions(
+			canonlog.WithLineEnricher(canonotel.Baggage("tenant")),
+		),
+		canonlog.WithEmitterOptions(
+			canonlog.WithSampler(sampler),
+			canonlog.WithEnricher(canonotel.TraceSampled()),
+		),
+	)(mux)
+
+	// The OpenTelemetry context must be extracted before the middleware
+	// creates the request's line, for the baggage to be copied into it.
+	prop := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := prop.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// fetch performs a GET request against url, discarding the response body,
+// and records the number, duration and failures of such requests in the
+// canonical log line carried by ctx.
+func fetch(ctx context.Context, client *http.Client, url string) (err error) {
+	start := time.Now()
+	defer func() {
+		canonlog.Set(ctx, AttrUpstreamCalls, 1)
+		canonlog.Set(ctx, AttrUpstreamTime, time.Since(start))
+		if err != nil {
+			canonlog.Set(ctx, AttrUpstreamErrors, 1)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("upstream returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andrew-d/canonlog"
+	"github.com/andrew-d/canonlog/canonotel"
+)
+
+// sampledTraceparent is the traceparent header of a request whose trace is
+// sampled.
+const sampledTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestService(t *testing.T) {
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get(canonlog.TraceparentHeader)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	// Record every line, including those dropped by the sampler, to check
+	// what the service set on them.
+	rec := canonlog.NewRecorder(10)
+	canonlog.DefaultRegistry.SetRecorder(rec)
+	t.Cleanup(func() { canonlog.DefaultRegistry.SetRecorder(nil) })
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		upstream   string
+		db         *fakeDriver
+		wantStatus int
+		wantLogged bool // whether the line is kept by the sampler
+		wantLine   map[string]any
+	}{
+		{
+			name:       "sampled_out",
+			wantStatus: http.StatusOK,
+			wantLine: map[string]any{
+				"user_id":     "123",
+				"http_status": int64(200),
+			},
+		},
+		{
+			name:       "traced",
+			header:     http.Header{"Traceparent": {sampledTraceparent}, "Baggage": {"tenant=acme,secret=1"}},
+			upstream:   upstream.URL + "/ok",
+			wantStatus: http.StatusOK,
+			wantLogged: true,
+			wantLine: map[string]any{
+				"user_id":        "123",
+				"upstream_calls": int64(1),
+				"http_status":    int64(200),
+				"trace_id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+				"baggage.tenant": "acme",
+			},
+		},
+		{
+			name:       "upstream_error",
+			upstream:   upstream.URL + "/fail",
+			wantStatus: http.StatusBadGateway,
+			wantLogged: true,
+			wantLine: map[string]any{
+				"user_id":         "123",
+				"upstream_calls":  int64(1),
+				"upstream_errors": int64(1),
+				"http_status":     int64(502),
+			},
+		},
+		{
+			name:       "database",
+			header:     http.Header{"Traceparent": {sampledTraceparent}},
+			db:         &fakeDriver{},
+			wantStatus: http.StatusOK,
+			wantLogged: true,
+			wantLine: map[string]any{
+				"db_tx_count":     int64(1),
+				"db_tx_rollbacks": nil,
+				"http_status":     int64(200),
+			},
+		},
+		{
+			name:       "database_error",
+			db:         &fakeDriver{failExec: true},
+			wantStatus: http.StatusInternalServerError,
+			wantLogged: true,
+			wantLine: map[string]any{
+				"db_tx_count":     int64(1),
+				"db_tx_rollbacks": int64(1),
+				"http_status":     int64(500),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			sampler := canonotel.TraceSampler(canonlog.RuleSampler(
+				errorsRule,
+				canonlog.SampleRule{Name: "default", Rate: 0},
+			))
+			var db *sql.DB
+			if tt.db != nil {
+				db = sql.OpenDB(tt.db)
+				defer db.Close()
+			}
+			h := newHandler(logger, sampler, db, tt.upstream, http.DefaultTransport)
+
+			upstreamTraceparent = ""
+			req := httptest.NewRequest("GET", "/users/123", nil)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if logged := buf.Len() > 0; logged != tt.wantLogged {
+				t.Errorf("line logged = %v, want %v: %s", logged, tt.wantLogged, buf.String())
+			}
+			if tt.wantLogged {
+				// Enrichers given to the emitter only contribute to
+				// the logged line.
+				var logged map[string]any
+				if err := json.Unmarshal(buf.Bytes(), &logged); err != nil {
+					t.Fatalf("decoding canonical log line %q: %v", buf.String(), err)
+				}
+				_, traced := tt.header["Traceparent"]
+				if sampled, ok := logged["trace_sampled"].(bool); ok != traced || traced && !sampled {
+					t.Errorf("trace_sampled = %v, want true only for traced requests", logged["trace_sampled"])
+				}
+			}
+
+			lines := rec.Lines()
+			if len(lines) == 0 {
+				t.Fatal("no line recorded")
+			}
+			got := flatten(lines[len(lines)-1].Attrs)
+			for key, want := range tt.wantLine {
+				if got[key] != want {
+					t.Errorf("%s = %v, want %v", key, got[key], want)
+				}
+			}
+			if _, ok := got["upstream_calls"]; ok && tt.upstream == "" {
+				t.Errorf("upstream_calls set without an upstream request")
+			}
+			if tt.upstream != "" && upstreamTraceparent == "" {
+				t.Errorf("upstream request has no %s header", canonlog.TraceparentHeader)
+			}
+		})
+	}
+}
+
+// flatten returns the values of attrs keyed by their keys, qualified by
+// those of the groups they are in.
+func flatten(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any)
+	var add func(prefix string, attrs []slog.Attr)
+	add = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			if v := a.Value.Resolve(); v.Kind() == slog.KindGroup {
+				add(prefix+a.Key+".", v.Group())
+			} else {
+				m[prefix+a.Key] = v.Any()
+			}
+		}
+	}
+	add("", attrs)
+	return m
+}
+
+// fakeDriver is a database/sql driver and connector that supports only
+// transactions and ExecContext, which fails if failExec is set.
+type fakeDriver struct {
+	failExec bool
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error)             { return fakeConn{d}, nil }
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return fakeConn{d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return d }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if c.d.failExec {
+		return nil, errors.New("exec failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
@@ -0,0 +1,29 @@
+package canonlog
+
+import "context"
+
+// MergeOr is a merge function for boolean attributes, for use with
+// [WithMerge], under which the attribute is true if it was ever set to
+// true. It is commutative, so it may be declared [WithCommutativeMerge].
+func MergeOr(old, new bool) bool { return old || new }
+
+// MergeAnd is a merge function for boolean attributes, for use with
+// [WithMerge], under which the attribute is false if it was ever set to
+// false. It is commutative, so it may be declared [WithCommutativeMerge].
+func MergeAnd(old, new bool) bool { return old && new }
+
+// Flag sets attr to true in the [Line] attached to ctx, merging it with any
+// existing value with [MergeOr] in place of the attribute's merge function,
+// so that flags recording whether something happened at all during a
+// request can be raised from many call sites, concurrently or not:
+//
+//	var AttrCacheBypassed = canonlog.Register[bool]("cache_bypassed")
+//
+//	if r.Header.Get("Cache-Control") == "no-cache" {
+//		canonlog.Flag(ctx, AttrCacheBypassed)
+//	}
+//
+// If the context does not have a Line, Flag silently does nothing.
+func Flag(ctx context.Context, attr Attr[bool]) {
+	setWith(ctx, attr, true, MergeOr)
+}
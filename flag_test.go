@@ -0,0 +1,42 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestFlag(t *testing.T) {
+	r := testRegistry(t)
+	attrBypassed := RegisterWith[bool](r, "cache_bypassed")
+	attrFallback := RegisterWith(r, "used_fallback",
+		WithMerge(MergeOr),
+		WithCommutativeMerge[bool](),
+	)
+	attrAllHit := RegisterWith(r, "all_hit", WithMerge(MergeAnd))
+
+	ctx := NewContext(context.Background(), NewLine(WithRegistry(r)))
+	Set(ctx, attrBypassed, false)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			if i == 3 {
+				Flag(ctx, attrBypassed)
+			}
+			Set(ctx, attrFallback, i == 5)
+			Set(ctx, attrAllHit, i != 7)
+		})
+	}
+	wg.Wait()
+
+	want := []slog.Attr{
+		slog.Bool("cache_bypassed", true),
+		slog.Bool("used_fallback", true),
+		slog.Bool("all_hit", false),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package canonlog
+
+import "context"
+
+// Fork returns a copy of ctx carrying a new, empty [Line] with the same
+// registry as the Line in ctx, for a parallel sub-task to record its
+// attributes into without contending with other sub-tasks. Once the
+// sub-task has finished, merge its attributes into the parent with [Join]:
+//
+//	for _, shard := range shards {
+//		child := canonlog.Fork(ctx)
+//		g.Go(func() error {
+//			defer canonlog.Join(ctx, child)
+//			return query(child, shard)
+//		})
+//	}
+//
+// If ctx does not have a Line, Fork returns ctx unchanged.
+func Fork(ctx context.Context) context.Context {
+	l := FromContext(ctx)
+	if l == nil {
+		return ctx
+	}
+	ctx = New(ctx, WithRegistry(l.registry))
+	// The child is joined rather than emitted, so it is not in flight in
+	// its own right.
+	FromContext(ctx).untrack()
+	return ctx
+}
+
+// Join sets every attribute of the [Line] attached to child on the Line
+// attached to parent, in the order they were first set on the child, as if
+// by [Set]: values of attributes with a merge function are combined with
+// the parent's, and others overwrite them. Values set with [SetLazy] stay
+// lazy, and are combined with the parent's only once computed, when the
+// parent is emitted. Join should be called once for each child, after the
+// sub-task using it has finished; if either context does not have a Line,
+// Join does nothing.
+func Join(parent, child context.Context) {
+	c, p := FromContext(child), FromContext(parent)
+	if c == nil || p == nil || c == p {
+		return
+	}
+
+	// Snapshot the child first, so that its locks are not held while
+	// setting values on the parent.
+	c.lockAll()
+	entries := c.entries()
+	c.unlockAll()
+
+	for _, e := range entries {
+		sv := e.sv
+		if _, ok := sv.raw.(lazy); ok {
+			// Left to be computed when the parent is emitted.
+			p.setLazy(e.key, sv, true)
+			continue
+		}
+		if sv.setAny != nil {
+			sv.setAny(parent, sv.value())
+		} else {
+			// Set by SetMany, with a key that is not registered.
+			p.setDynamic(parent, sv.name, sv.value())
+		}
+	}
+}
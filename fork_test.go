@@ -0,0 +1,95 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestForkJoin(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+	attrTables := RegisterSetWith[string](r, "tables")
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrQueries, 1)
+
+	var wg sync.WaitGroup
+	for _, table := range []string{"users", "orders", "users"} {
+		child := Fork(ctx)
+		wg.Go(func() {
+			defer Join(ctx, child)
+			for range 10 {
+				Set(child, attrQueries, 1)
+			}
+			Set(child, attrTables, []string{table})
+		})
+	}
+	wg.Wait()
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line user=usr_123 queries=31 tables=\"[orders users]\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestForkJoin_Lazy(t *testing.T) {
+	r := testRegistry(t)
+	attrSummary := RegisterWith[string](r, "summary")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	child := Fork(ctx)
+	calls := 0
+	SetLazy(child, attrSummary, func() string {
+		calls++
+		return "3 items"
+	})
+	Join(ctx, child)
+	if calls != 0 {
+		t.Errorf("Join computed the lazy value %d times, want 0", calls)
+	}
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line summary=\"3 items\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+	if calls != 1 {
+		t.Errorf("lazy value computed %d times, want 1", calls)
+	}
+}
+
+func TestFork_Isolated(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	child := Fork(ctx)
+
+	if FromContext(child).registry != r {
+		t.Error("Fork did not keep the parent's registry")
+	}
+	if attrs := Attrs(child); attrs != nil {
+		t.Errorf("forked line has attributes %v, want none", attrs)
+	}
+}
+
+func TestForkJoin_WithoutLine(t *testing.T) {
+	ctx := context.Background()
+	if got := Fork(ctx); got != ctx {
+		t.Error("Fork on context without Line did not return it unchanged")
+	}
+	Join(ctx, ctx) // must not panic
+}
@@ -0,0 +1,86 @@
+package canonlog
+
+import (
+	"log/slog"
+	"time"
+)
+
+// WithTimeFormat makes a [time.Time] attribute emit its value as a string
+// formatted with the given layout, as by [time.Time.Format], rather than in
+// whatever form the handler uses:
+//
+//	var AttrCreatedAt = canonlog.Register("created_at",
+//		canonlog.WithTimeFormat(time.RFC3339),
+//	)
+func WithTimeFormat(layout string) Option[time.Time] {
+	return WithValue(func(t time.Time) slog.Value {
+		return slog.StringValue(t.Format(layout))
+	})
+}
+
+// DurationFormat is a representation of [time.Duration] values, for
+// [WithDurationAs].
+type DurationFormat int
+
+const (
+	// DurationString emits durations as strings such as "1.5s", as
+	// formatted by [time.Duration.String].
+	DurationString DurationFormat = iota
+
+	// DurationSeconds emits durations as a floating-point number of
+	// seconds, with the unit "s".
+	DurationSeconds
+
+	// DurationMillis emits durations as a floating-point number of
+	// milliseconds, with the unit "ms".
+	DurationMillis
+)
+
+// unit returns the unit of the numbers emitted with f, or "" if f does not
+// emit numbers.
+func (f DurationFormat) unit() string {
+	switch f {
+	case DurationSeconds:
+		return "s"
+	case DurationMillis:
+		return "ms"
+	}
+	return ""
+}
+
+// WithDurationAs makes a [time.Duration] attribute emit its value in the
+// given format, so that the units of durations are consistent across
+// services whatever handlers they use:
+//
+//	var AttrDBTime = canonlog.Register("db_time",
+//		canonlog.WithDurationAs(canonlog.DurationMillis),
+//		canonlog.WithUnitSuffix(),
+//	)
+//	// emitted as db_time_ms=12.5
+func WithDurationAs(f DurationFormat) Option[time.Duration] {
+	return func(a *Attr[time.Duration]) {
+		a.unit = f.unit()
+		switch f {
+		case DurationSeconds:
+			a.toValue = func(d time.Duration) slog.Value { return slog.Float64Value(d.Seconds()) }
+		case DurationMillis:
+			a.toValue = func(d time.Duration) slog.Value {
+				return slog.Float64Value(float64(d) / float64(time.Millisecond))
+			}
+		default:
+			a.toValue = func(d time.Duration) slog.Value { return slog.StringValue(d.String()) }
+		}
+	}
+}
+
+// WithUnitSuffix appends the unit of a [time.Duration] attribute's format,
+// such as "_ms" for [DurationMillis], to its key, so that the unit is
+// apparent from the key alone. It must be given after the corresponding
+// [WithDurationAs], and has no effect on formats without a unit.
+func WithUnitSuffix() Option[time.Duration] {
+	return func(a *Attr[time.Duration]) {
+		if a.unit != "" {
+			a.name += "_" + a.unit
+		}
+	}
+}
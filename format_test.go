@@ -0,0 +1,60 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithTimeFormat(t *testing.T) {
+	r := testRegistry(t)
+	attrCreated := RegisterWith(r, "created_at", WithTimeFormat(time.RFC3339))
+	attrDay := RegisterWith(r, "day", WithTimeFormat(time.DateOnly))
+
+	ctx := New(context.Background(), WithRegistry(r))
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	Set(ctx, attrCreated, ts)
+	Set(ctx, attrDay, ts)
+
+	want := []slog.Attr{
+		slog.String("created_at", "2024-03-01T12:30:00Z"),
+		slog.String("day", "2024-03-01"),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
+
+func TestWithDurationAs(t *testing.T) {
+	r := testRegistry(t)
+	attrString := RegisterWith(r, "total", WithDurationAs(DurationString), WithUnitSuffix())
+	attrSeconds := RegisterWith(r, "queue", WithDurationAs(DurationSeconds))
+	attrMillis := RegisterWith(r, "db",
+		WithDurationAs(DurationMillis),
+		WithUnitSuffix(),
+		WithGroup[time.Duration]("phase"),
+	)
+	attrSuffixed := RegisterWith(r, "render", WithDurationAs(DurationSeconds), WithUnitSuffix())
+
+	if got, want := attrMillis.Key(), "phase.db_ms"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrString, 1500*time.Millisecond)
+	Set(ctx, attrSeconds, 1500*time.Millisecond)
+	Set(ctx, attrMillis, 12500*time.Microsecond)
+	Set(ctx, attrSuffixed, 250*time.Millisecond)
+
+	want := []slog.Attr{
+		slog.String("total", "1.5s"),
+		slog.Float64("queue", 1.5),
+		slog.Group("phase", slog.Float64("db_ms", 12.5)),
+		slog.Float64("render_s", 0.25),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
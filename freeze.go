@@ -0,0 +1,98 @@
+package canonlog
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// LateSetsKey is the key of the attribute that records the number of
+// attempts to set attributes on a [Line] after it was emitted.
+const LateSetsKey = "canonlog_late_sets"
+
+// lateSets counts the late sets of all lines in the process.
+var lateSets atomic.Uint64
+
+// LateSets returns the number of attempts, since the process started, to set
+// attributes on a [Line] after it was emitted, for exporting as a metric.
+func LateSets() uint64 {
+	return lateSets.Load()
+}
+
+// freeze marks l as emitted, after which attributes can no longer be set on
+// it (see [Line.lateSet]), and records its attributes in its registry's
+// usage statistics the first time it is called. It reports whether this was
+// the first call.
+func (l *Line) freeze() bool {
+	if !l.frozen.CompareAndSwap(false, true) {
+		return false
+	}
+	l.lockAll()
+	l.stopClock()
+	var entries []entry
+	if l.registry != nil {
+		entries = l.entries()
+	}
+	l.unlockAll()
+	if l.registry == nil {
+		return true
+	}
+
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	l.registry.recordUsage(keys)
+	return true
+}
+
+// Emitted reports whether l has been emitted, after which attributes set on
+// it are dropped as late sets (see [LateSetsKey]), for code that records
+// into a line held beyond the request it belongs to and must stop when the
+// request ends. It returns false if l is nil.
+func (l *Line) Emitted() bool {
+	return l != nil && l.frozen.Load()
+}
+
+// lateSet reports whether l has been emitted, in which case setting the
+// attribute with the given key must do nothing.
+//
+// Such a set usually means a goroutine outlived the request it was recording
+// into, and is handled according to the current [Mode]: it is counted in
+// the line's [LateSetsKey] attribute and by [LateSets], and additionally
+// logged with the caller's location to [slog.Default] in ModeDebug, or
+// panics in ModeStrict.
+func (l *Line) lateSet(key string) bool {
+	if !l.frozen.Load() {
+		return false
+	}
+	l.lateSets.Add(1)
+	lateSets.Add(1)
+
+	switch currentMode() {
+	case ModeDebug:
+		slog.Warn("canonlog: attribute set after its line was emitted",
+			"key", key, "caller", caller())
+	case ModeStrict:
+		panic(fmt.Sprintf("canonlog: attribute %q set at %s after its line was emitted", key, caller()))
+	}
+	return true
+}
+
+// caller returns the file and line of the innermost caller outside this
+// package, other than its tests.
+func caller() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/andrew-d/canonlog.") || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	ctx := New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrQueries, 1)
+
+	if FromContext(ctx).Emitted() {
+		t.Error("Emitted() = true before the line was emitted")
+	}
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	if !FromContext(ctx).Emitted() {
+		t.Error("Emitted() = false after the line was emitted")
+	}
+
+	before := LateSets()
+	Set(ctx, attrUser, "usr_456")
+	Set(ctx, attrQueries, 1)
+	SetMany(ctx, map[string]any{"region": "eu"})
+	if got := LateSets() - before; got != 3 {
+		t.Errorf("LateSets increased by %d, want 3", got)
+	}
+
+	buf.Reset()
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line user=usr_123 queries=1 canonlog_late_sets=3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestFreeze_Modes(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	t.Run("debug", func(t *testing.T) {
+		withMode(t, ModeDebug)
+		var buf bytes.Buffer
+		prev := slog.Default()
+		slog.SetDefault(testLogger(&buf))
+		t.Cleanup(func() { slog.SetDefault(prev) })
+
+		ctx := New(context.Background())
+		Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+		Set(ctx, attrUser, "usr_123")
+
+		got := buf.String()
+		if !strings.Contains(got, "key=user") || !strings.Contains(got, "freeze_test.go:") {
+			t.Errorf("log output = %q, want warning with key and caller", got)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		withMode(t, ModeStrict)
+		ctx := New(context.Background())
+		Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+
+		defer func() {
+			p := fmt.Sprint(recover())
+			if !strings.Contains(p, `"user"`) || !strings.Contains(p, "freeze_test.go:") {
+				t.Errorf("recovered %q, want panic naming the key and caller", p)
+			}
+		}()
+		Set(ctx, attrUser, "usr_123")
+	})
+}
@@ -0,0 +1,76 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Gauge is a point-in-time measurement recorded by a gauge attribute.
+type Gauge[T Number] struct {
+	Value T
+	Time  time.Time // when Value was observed
+}
+
+// RegisterGaugeWith creates a new gauge attribute with the given key in the
+// specified registry. It panics if an attribute with the same key has
+// already been registered in that registry.
+//
+// A gauge holds the most recently observed value of a measurement sampled
+// during the operation, such as the depth of a queue, recorded with
+// [SetGauge]. Unlike a counter, values are not summed, and unlike a plain
+// attribute, the value observed last wins even if goroutines observing it
+// concurrently store their values out of order:
+//
+//	var AttrQueueDepth = canonlog.RegisterGauge[int]("queue_depth")
+//
+//	canonlog.SetGauge(ctx, AttrQueueDepth, q.Len())
+//	// emitted as queue_depth=12
+//
+// Use [WithObservedAt] to also emit the time of the observation.
+func RegisterGaugeWith[T Number](r *Registry, key string, opts ...Option[Gauge[T]]) Attr[Gauge[T]] {
+	opts = append([]Option[Gauge[T]]{
+		WithMerge(mergeGauges[T]),
+		WithValue(func(g Gauge[T]) slog.Value {
+			return slog.AnyValue(g.Value)
+		}),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterGauge creates a new gauge attribute with the given key using
+// [DefaultRegistry]. See [RegisterGaugeWith] for details.
+func RegisterGauge[T Number](key string, opts ...Option[Gauge[T]]) Attr[Gauge[T]] {
+	return RegisterGaugeWith(DefaultRegistry, key, opts...)
+}
+
+// WithObservedAt makes a gauge attribute emit an [slog.Group] with the
+// value, under the key "value", and the time it was observed, under the key
+// "observed_at", in place of the value alone:
+//
+//	var AttrQueueDepth = canonlog.RegisterGauge("queue_depth", canonlog.WithObservedAt[int]())
+//	// emitted as queue_depth.value=12 queue_depth.observed_at=2026-10-16T09:30:00.123Z
+func WithObservedAt[T Number]() Option[Gauge[T]] {
+	return WithValue(func(g Gauge[T]) slog.Value {
+		return slog.GroupValue(
+			slog.Any("value", g.Value),
+			slog.Time("observed_at", g.Time),
+		)
+	})
+}
+
+// SetGauge records v, observed now, for the gauge attribute attr in the
+// [Line] attached to ctx. If the context does not have a Line, SetGauge
+// silently does nothing.
+func SetGauge[T Number](ctx context.Context, attr Attr[Gauge[T]], v T) {
+	Set(ctx, attr, Gauge[T]{Value: v, Time: time.Now()})
+}
+
+// mergeGauges returns the more recently observed of old and new, or new if
+// they were observed at the same time.
+func mergeGauges[T Number](old, new Gauge[T]) Gauge[T] {
+	if new.Time.Before(old.Time) {
+		return old
+	}
+	return new
+}
@@ -0,0 +1,33 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestRegisterGauge(t *testing.T) {
+	r := testRegistry(t)
+	attrDepth := RegisterGaugeWith[int](r, "queue_depth")
+	attrLoad := RegisterGaugeWith(r, "load", WithObservedAt[float64]())
+
+	synctest.Test(t, func(t *testing.T) {
+		ctx := New(context.Background())
+		SetGauge(ctx, attrDepth, 12)
+		early := Gauge[int]{Value: 40, Time: time.Now()}
+		time.Sleep(time.Second)
+		SetGauge(ctx, attrDepth, 7)
+		Set(ctx, attrDepth, early) // stored late, but observed earlier
+		SetGauge(ctx, attrLoad, 0.5)
+
+		var buf bytes.Buffer
+		Emit(ctx, testLogger(&buf), slog.LevelInfo)
+		want := "level=INFO msg=canonical-log-line queue_depth=7 load.value=0.5 load.observed_at=2000-01-01T00:00:01.000Z\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
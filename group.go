@@ -0,0 +1,184 @@
+package canonlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// WithGroup places the attribute in the named group, so that it is emitted
+// nested inside an [slog.Group] with the other attributes of that group,
+// rather than in a flat namespace held together by naming convention:
+//
+//	var (
+//		AttrDBQueries = canonlog.Register[int]("queries", canonlog.WithGroup[int]("db"))
+//		AttrDBTime    = canonlog.Register[time.Duration]("time", canonlog.WithGroup[time.Duration]("db"))
+//	)
+//	// emitted as db.queries=4 db.time=80ms by a text handler,
+//	// or "db":{"queries":4,"time":80000000} by a JSON handler
+//
+// The group is emitted at the position where the first of its attributes
+// was set. The attribute's key, as returned by [Attr.Key] and checked for
+// duplicates at registration, is qualified by the group name.
+func WithGroup[T any](name string) Option[T] {
+	return func(a *Attr[T]) {
+		a.group = name
+	}
+}
+
+// GroupAttr is a group attribute, created by [RegisterGroup], whose values
+// are structs of type T emitted as groups of their fields. Its embedded Attr
+// can be used with functions such as [Has] and [TrySet].
+type GroupAttr[T any] struct {
+	Attr[T]
+}
+
+// RegisterGroupWith creates a new group attribute with the given key in the
+// specified registry, whose values are structs of type T, so that related
+// values can be set together and emitted as a group with one attribute in
+// place of several registered [WithGroup]:
+//
+//	type HTTPInfo struct {
+//		Method   string
+//		Status   int
+//		Route    string `canonlog:"route,omitempty"`
+//		internal bool
+//	}
+//
+//	var AttrHTTP = canonlog.RegisterGroup[HTTPInfo]("http")
+//
+//	canonlog.SetGroup(ctx, AttrHTTP, HTTPInfo{Method: "GET", Status: 200})
+//	// emitted as http.method=GET http.status=200 by a text handler
+//
+// Each exported field of T is a member of the group, with a key given by
+// its "canonlog" tag, or otherwise its name in snake case, as in "resp_bytes"
+// for RespBytes. Fields tagged "-" are skipped, and fields of embedded
+// structs are included, as are those of embedded pointers to structs while
+// the pointer is not nil. Fields with the "omitempty" option are omitted from
+// the group if they have their zero value, and when a value is set for an
+// attribute that already has one, such fields keep their previous value
+// rather than being cleared, unless the attribute has another merge
+// function.
+//
+// The keys of the members, qualified by key, are reserved in r like those
+// of attributes registered WithGroup. RegisterGroupWith panics if T is not
+// a struct type, or if an attribute with the same key, or any member's key,
+// has already been registered in r.
+func RegisterGroupWith[T any](r *Registry, key string, opts ...Option[T]) GroupAttr[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("canonlog: group attribute %q of non-struct type %s", key, t))
+	}
+	fields := groupFieldsOf(t)
+
+	var base []Option[T]
+	base = append(base, WithValue(func(v T) slog.Value {
+		return groupValue(reflect.ValueOf(v), fields)
+	}))
+	if slices.ContainsFunc(fields, func(f structField) bool { return f.omitEmpty }) {
+		base = append(base, WithMerge(func(old, new T) T {
+			nv, ov := reflect.ValueOf(&new).Elem(), reflect.ValueOf(old)
+			for _, f := range fields {
+				if !f.omitEmpty {
+					continue
+				}
+				fv, err := nv.FieldByIndexErr(f.index)
+				if err != nil || !fv.IsZero() {
+					continue
+				}
+				if ofv, err := ov.FieldByIndexErr(f.index); err == nil {
+					fv.Set(ofv)
+				}
+			}
+			return new
+		}))
+	}
+	base = append(base, func(a *Attr[T]) {
+		for _, f := range fields {
+			a.members = append(a.members, f.key)
+		}
+	})
+	return GroupAttr[T]{RegisterWith(r, key, append(base, opts...)...)}
+}
+
+// RegisterGroup creates a new group attribute with the given key using
+// [DefaultRegistry]. See [RegisterGroupWith] for details.
+func RegisterGroup[T any](key string, opts ...Option[T]) GroupAttr[T] {
+	return RegisterGroupWith(DefaultRegistry, key, opts...)
+}
+
+// SetGroup stores v for the group attribute g in the [Line] attached to ctx,
+// setting all of its members at once, as described for [RegisterGroupWith].
+// It is otherwise equivalent to [Set].
+func SetGroup[T any](ctx context.Context, g GroupAttr[T], v T) {
+	Set(ctx, g.Attr, v)
+}
+
+// groupFieldsOf returns the members of a group attribute whose values have
+// the struct type t.
+func groupFieldsOf(t reflect.Type) []structField {
+	var fields []structField
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous && isStruct(sf.Type) {
+			continue
+		}
+		tag := sf.Tag.Get("canonlog")
+		if tag == "-" {
+			continue
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		if key == "" {
+			key = snakeCase(sf.Name)
+		}
+		fields = append(fields, structField{
+			index:     sf.Index,
+			name:      sf.Name,
+			key:       key,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	return fields
+}
+
+// isStruct reports whether t is a struct type or a pointer to one.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// groupValue returns the struct v as the group of its fields.
+func groupValue(v reflect.Value, fields []structField) slog.Value {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil || f.omitEmpty && fv.IsZero() {
+			continue // promoted through a nil embedded pointer, or empty
+		}
+		attrs = append(attrs, slog.Any(f.key, fv.Interface()))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// snakeCase returns the Go identifier name in snake case, keeping
+// initialisms together, as in "http_status" for HTTPStatus.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestWithGroup(t *testing.T) {
+	r := testRegistry(t)
+	attrQueries := RegisterWith(r, "queries", WithGroup[int]("db"))
+	attrTime := RegisterWith(r, "time", WithGroup[time.Duration]("db"))
+	attrUser := RegisterWith[string](r, "user")
+	attrHits := RegisterWith(r, "hits", WithGroup[int]("cache"))
+
+	if got := attrQueries.Key(); got != "db.queries" {
+		t.Errorf("Key() = %q, want %q", got, "db.queries")
+	}
+
+	ctx := New(context.Background())
+	Set(ctx, attrQueries, 4)
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrHits, 2)
+	Set(ctx, attrTime, 80*time.Millisecond)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line db.queries=4 db.time=80ms user=usr_123 cache.hits=2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	buf.Reset()
+	Emit(ctx, slog.New(slog.NewJSONHandler(&buf, nil)), slog.LevelInfo)
+	var line struct {
+		DB struct {
+			Queries int
+			Time    time.Duration
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.DB.Queries != 4 || line.DB.Time != 80*time.Millisecond {
+		t.Errorf("JSON db group = %+v, want queries=4 time=80ms", line.DB)
+	}
+}
+
+func TestWithGroup_DuplicateKey(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith(r, "queries", WithGroup[int]("db"))
+
+	// The same name in a different group, or ungrouped, is allowed.
+	RegisterWith(r, "queries", WithGroup[int]("cache"))
+	RegisterWith[int](r, "queries")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("RegisterWith did not panic on a duplicate grouped key")
+		}
+	}()
+	RegisterWith[int](r, "db.queries")
+}
+
+type testHTTPInfo struct {
+	Method    string
+	Status    int
+	RespBytes int64
+	Route     string `canonlog:"route,omitempty"`
+	Secret    string `canonlog:"-"`
+	internal  bool
+	testEmbedded
+}
+
+type testEmbedded struct {
+	HTTPVersion string `canonlog:",omitempty"`
+}
+
+func TestRegisterGroup(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrHTTP := RegisterGroupWith[testHTTPInfo](r, "http")
+
+	ctx := New(context.Background())
+	SetGroup(ctx, attrHTTP, testHTTPInfo{Method: "GET", Route: "/users/{id}", Secret: "s3cret", internal: true})
+	Set(ctx, attrUser, "usr_123")
+	SetGroup(ctx, attrHTTP, testHTTPInfo{Method: "GET", Status: 200, RespBytes: 12}) // keeps the route
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line http.method=GET http.status=200 http.resp_bytes=12 http.route=/users/{id} user=usr_123\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// The keys of the group and its members are reserved.
+	for _, key := range []string{"http", "http.status", "http.http_version"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %q did not panic", key)
+				}
+			}()
+			RegisterWith[int](r, key)
+		}()
+	}
+}
+
+func TestRegisterGroup_DuplicateMember(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith[int](r, "http.status")
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("RegisterGroupWith did not panic on a duplicate member key")
+			}
+		}()
+		RegisterGroupWith[testHTTPInfo](r, "http")
+	}()
+
+	// The failed registration reserved none of its keys.
+	RegisterWith[string](r, "http")
+	RegisterWith[string](r, "http.method")
+}
+
+func TestRegisterGroup_Usage(t *testing.T) {
+	r := testRegistry(t)
+	attrHTTP := RegisterGroupWith[testHTTPInfo](r, "http")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	SetGroup(ctx, attrHTTP, testHTTPInfo{Method: "GET", Status: 200})
+	Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+
+	// Member keys are not attributes of their own.
+	want := map[string]int64{"http": 1}
+	if got := r.Usage().Keys; !maps.Equal(got, want) {
+		t.Errorf("Usage().Keys = %v, want %v", got, want)
+	}
+}
+
+// testPeer is embedded by pointer in testConnInfo.
+type testPeer struct {
+	Addr string
+	Port int `canonlog:",omitempty"`
+}
+
+type testConnInfo struct {
+	*testPeer
+	Proto string
+}
+
+func TestRegisterGroup_NilEmbedded(t *testing.T) {
+	r := testRegistry(t)
+	attrConn := RegisterGroupWith[testConnInfo](r, "conn")
+
+	ctx := New(context.Background())
+	SetGroup(ctx, attrConn, testConnInfo{Proto: "tcp"})
+	SetGroup(ctx, attrConn, testConnInfo{Proto: "tcp", testPeer: &testPeer{Addr: "10.0.0.1"}})
+	SetGroup(ctx, attrConn, testConnInfo{Proto: "udp"})
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line conn.proto=udp\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRegisterGroup_NotStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterGroupWith did not panic on a non-struct type")
+		}
+	}()
+	RegisterGroupWith[map[string]int](testRegistry(t), "http")
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Method":     "method",
+		"RespBytes":  "resp_bytes",
+		"HTTPStatus": "http_status",
+		"UserID":     "user_id",
+		"Retry2Auth": "retry2_auth",
+		"ID":         "id",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package canonlog
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// LargeValuesKey is the key of the group attribute that, in [ModeDebug],
+// records the estimated size in bytes of each large attribute value
+// serialized by reflection.
+const LargeValuesKey = "canonlog_large_values"
+
+// largeValueThreshold is the estimated size in bytes above which a value
+// without a converter is considered too large to serialize by reflection.
+const largeValueThreshold = 1024
+
+// checkLargeValue reports whether v, the value of an attribute without a
+// converter, would be serialized by reflection by [slog.AnyValue] and is
+// estimated to be larger than largeValueThreshold, along with the estimated
+// size. It only does any work outside [ModeLenient].
+func checkLargeValue(v any) (size int, large bool) {
+	if currentMode() == ModeLenient || v == nil {
+		return 0, false
+	}
+	if slog.AnyValue(v).Kind() != slog.KindAny {
+		return 0, false
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer, reflect.Interface:
+	default:
+		return 0, false
+	}
+
+	size = estimateSize(reflect.ValueOf(v), largeValueThreshold+1, make(map[uintptr]bool))
+	return size, size > largeValueThreshold
+}
+
+// reportLargeValue handles a large value for key according to the current
+// [Mode]: in ModeStrict it panics, and otherwise it returns an attribute to
+// add to the [LargeValuesKey] group.
+func reportLargeValue(key string, size int) slog.Attr {
+	if currentMode() == ModeStrict {
+		panic(fmt.Sprintf("canonlog: attribute %q has a large value (about %d bytes) "+
+			"serialized by reflection; register it WithValue to convert it explicitly", key, size))
+	}
+	return slog.Int(key, size)
+}
+
+// estimateSize returns an estimate of the number of bytes needed to
+// represent v, stopping once the estimate reaches budget. seen records the
+// pointers already visited, to avoid counting shared data twice or looping
+// forever on cycles.
+func estimateSize(v reflect.Value, budget int, seen map[uintptr]bool) int {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.String:
+		return v.Len()
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+		ptr := v.Pointer()
+		if seen[ptr] {
+			return 0
+		}
+		seen[ptr] = true
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return estimateSize(v.Elem(), budget, seen)
+	case reflect.Slice, reflect.Array:
+		if elem := v.Type().Elem(); isScalar(elem.Kind()) {
+			return v.Len() * int(elem.Size())
+		}
+		size := 0
+		for i := 0; i < v.Len() && size < budget; i++ {
+			size += estimateSize(v.Index(i), budget-size, seen)
+		}
+		return size
+	case reflect.Map:
+		size := 0
+		for iter := v.MapRange(); iter.Next() && size < budget; {
+			size += estimateSize(iter.Key(), budget-size, seen)
+			size += estimateSize(iter.Value(), budget-size, seen)
+		}
+		return size
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < v.NumField() && size < budget; i++ {
+			size += estimateSize(v.Field(i), budget-size, seen)
+		}
+		return size
+	default:
+		return int(v.Type().Size())
+	}
+}
+
+// isScalar reports whether values of kind k contain no references.
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withMode sets the process-wide mode for the duration of a test.
+func withMode(tb testing.TB, m Mode) {
+	prev := SetMode(m)
+	tb.Cleanup(func() { SetMode(prev) })
+}
+
+type payload struct {
+	ID    int
+	Items []string
+	Meta  map[string]string
+}
+
+func largePayload() payload {
+	p := payload{ID: 1, Meta: map[string]string{"k": "v"}}
+	for range 100 {
+		p.Items = append(p.Items, strings.Repeat("x", 20))
+	}
+	return p
+}
+
+func TestLargeValue_Lenient(t *testing.T) {
+	withMode(t, ModeLenient)
+	r := testRegistry(t)
+	attrPayload := RegisterWith[payload](r, "payload")
+
+	ctx := New(context.Background())
+	Set(ctx, attrPayload, largePayload())
+
+	if attrs := Attrs(ctx); len(attrs) != 1 {
+		t.Errorf("Attrs() returned %d attributes, want 1", len(attrs))
+	}
+}
+
+func TestLargeValue_Debug(t *testing.T) {
+	withMode(t, ModeDebug)
+	r := testRegistry(t)
+	attrPayload := RegisterWith[payload](r, "payload")
+	attrSmall := RegisterWith[payload](r, "small")
+	attrConverted := RegisterWith(r, "converted", WithValue(func(p payload) slog.Value {
+		return slog.IntValue(len(p.Items))
+	}))
+	attrString := RegisterWith[string](r, "string")
+
+	ctx := New(context.Background())
+	Set(ctx, attrPayload, largePayload())
+	Set(ctx, attrSmall, payload{ID: 2})
+	Set(ctx, attrConverted, largePayload())
+	Set(ctx, attrString, strings.Repeat("x", 2000)) // not serialized by reflection
+
+	attrs := Attrs(ctx)
+	last := attrs[len(attrs)-1]
+	if last.Key != LargeValuesKey {
+		t.Fatalf("last attribute = %q, want %q", last.Key, LargeValuesKey)
+	}
+	group := last.Value.Group()
+	if len(group) != 1 || group[0].Key != "payload" {
+		t.Fatalf("%s = %v, want only payload", LargeValuesKey, group)
+	}
+	if size := group[0].Value.Int64(); size <= largeValueThreshold {
+		t.Errorf("estimated size = %d, want > %d", size, largeValueThreshold)
+	}
+}
+
+func TestLargeValue_Strict(t *testing.T) {
+	withMode(t, ModeStrict)
+	r := testRegistry(t)
+	attrPayload := RegisterWith[*payload](r, "payload")
+
+	p := largePayload()
+	ctx := New(context.Background())
+	Set(ctx, attrPayload, &p)
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("Attrs did not panic on a large value")
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, `"payload"`) {
+			t.Errorf("panic message %q does not name the attribute", msg)
+		}
+	}()
+	Attrs(ctx)
+}
+
+func TestEstimateSize_Cycle(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	n := &node{Name: "abc"}
+	n.Next = n
+
+	if got := estimateSize(reflect.ValueOf(n), 1<<20, make(map[uintptr]bool)); got != 3 {
+		t.Errorf("estimateSize() = %d, want %d", got, 3)
+	}
+}
@@ -0,0 +1,65 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logRegistry holds the attributes recorded by [NewHandler], kept out of
+// [DefaultRegistry] for the same reason as those of [Middleware].
+var logRegistry = NewRegistry()
+
+var (
+	attrLogWarnings = RegisterCounterWith[int64](logRegistry, "log_warnings")
+	attrLogErrors   = RegisterCounterWith[int64](logRegistry, "log_errors")
+)
+
+// NewHandler returns a [slog.Handler] that passes records on to h, counting
+// those logged with a context carrying a [Line] at [slog.LevelWarn] and
+// above in the line, under the "log_warnings" and "log_errors" keys, so that
+// a canonical line shows whether the operation it describes logged
+// anything worth looking at:
+//
+//	slog.SetDefault(slog.New(canonlog.NewHandler(handler)))
+//
+//	slog.WarnContext(ctx, "cache unavailable") // log_warnings=1
+//
+// Records are not counted once the line has been emitted, nor are
+// canonical lines themselves. If h was returned by NewHandler, it is
+// returned unchanged.
+func NewHandler(h slog.Handler) slog.Handler {
+	if _, ok := h.(*countingHandler); ok {
+		return h
+	}
+	return &countingHandler{next: h}
+}
+
+// countingHandler is the [slog.Handler] returned by [NewHandler].
+type countingHandler struct {
+	next slog.Handler
+}
+
+func (h *countingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *countingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn && r.Message != Message {
+		if l := FromContext(ctx); l != nil && !l.frozen.Load() {
+			if r.Level >= slog.LevelError {
+				Add(ctx, attrLogErrors, 1)
+			} else {
+				Add(ctx, attrLogWarnings, 1)
+			}
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &countingHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *countingHandler) WithGroup(name string) slog.Handler {
+	return &countingHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,34 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(testLogger(&buf).Handler())
+	if NewHandler(h) != h {
+		t.Error("NewHandler wrapped its own handler again")
+	}
+	logger := slog.New(h).With("component", "test")
+
+	ctx := New(context.Background())
+	logger.InfoContext(ctx, "starting")
+	logger.WarnContext(ctx, "cache unavailable")
+	logger.WarnContext(ctx, "retrying")
+	logger.ErrorContext(ctx, "giving up")
+	logger.Warn("no line") // not counted anywhere
+
+	buf.Reset()
+	Emit(ctx, logger, slog.LevelError)
+	logger.ErrorContext(ctx, "after emitting") // not a late set
+
+	want := "level=ERROR msg=canonical-log-line component=test log_warnings=2 log_errors=1\n"
+	if got, _, _ := strings.Cut(buf.String(), "\n"); got+"\n" != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
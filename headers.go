@@ -0,0 +1,78 @@
+package canonlog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// attrHTTPHeaders records the request headers captured with WithHeaders.
+var attrHTTPHeaders = RegisterWith(httpRegistry, "http_headers",
+	WithValue(mapValue[string]),
+)
+
+// maxHeaderValueLen is the length beyond which captured header values are
+// truncated.
+const maxHeaderValueLen = 256
+
+// sensitiveHeaders are headers whose values are never captured.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+}
+
+// WithHeaders makes the middleware record the values of the given request
+// headers in an "http_headers" group, keyed by lowercased header name, as
+// in http_headers.x-client-version=4.2.0. Only the listed headers are
+// recorded, and only if present in the request; multiple values are joined
+// with ", ", and values longer than 256 bytes are truncated.
+//
+// The values of headers carrying credentials, such as Authorization and
+// Cookie, are never recorded, even if listed; "[redacted]" is recorded in
+// their place, to show that the header was present.
+func WithHeaders(names ...string) MiddlewareOption {
+	canonical := make([]string, len(names))
+	for i, name := range names {
+		canonical[i] = http.CanonicalHeaderKey(name)
+	}
+
+	return func(cfg *middlewareConfig) {
+		cfg.onRequest = append(cfg.onRequest, func(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			var headers map[string]string
+			for _, name := range canonical {
+				values := r.Header.Values(name)
+				if len(values) == 0 {
+					continue
+				}
+				v := strings.Join(values, ", ")
+				if sensitiveHeaders[name] {
+					v = "[redacted]"
+				} else if len(v) > maxHeaderValueLen {
+					v = truncateUTF8(v, maxHeaderValueLen) + "..."
+				}
+				if headers == nil {
+					headers = make(map[string]string)
+				}
+				headers[strings.ToLower(name)] = v
+			}
+			if headers != nil {
+				Set(ctx, attrHTTPHeaders, headers)
+			}
+			return ctx
+		})
+	}
+}
+
+// truncateUTF8 returns the longest prefix of s that is at most n bytes long
+// and does not end in the middle of a UTF-8 encoded rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xc0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,41 @@
+package canonlog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := Middleware(testLogger(&buf), WithHeaders("x-client-version", "Accept-Encoding", "Authorization", "X-Missing", "X-Long"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Client-Version", "4.2.0")
+	req.Header.Add("Accept-Encoding", "gzip")
+	req.Header.Add("Accept-Encoding", "br")
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	req.Header.Set("X-Long", strings.Repeat("é", maxHeaderValueLen))
+	req.Header.Set("X-Unlisted", "nope")
+	mw(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	got := buf.String()
+	for _, want := range []string{
+		` http_headers.accept-encoding="gzip, br" `,
+		` http_headers.authorization=[redacted] `,
+		` http_headers.x-client-version=4.2.0 `,
+		` http_headers.x-long=` + strings.Repeat("é", maxHeaderValueLen/2) + `... `,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output = %q, want it to contain %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{"s3cr3t", "x-missing", "x-unlisted"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("log output = %q, want it not to contain %q", got, unwanted)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// An EmitHook runs whenever a canonical log line is emitted, and returns the
+// attributes to emit in place of attrs, so that policies that apply to every
+// line, such as adding cost attribution tags or dropping attributes that
+// must not leave a region, can be enforced in one place:
+//
+//	canonlog.DefaultRegistry.OnEmit(func(ctx context.Context, attrs []slog.Attr) []slog.Attr {
+//		return append(attrs, slog.String("cost_center", costCenter(ctx)))
+//	})
+//
+// attrs holds the line's attributes, followed by those contributed by the
+// [Emitter], such as the outcome. The hook may modify attrs in place, but
+// must not retain it after returning. It must be safe for concurrent use.
+type EmitHook func(ctx context.Context, attrs []slog.Attr) []slog.Attr
+
+// OnEmit adds hooks to run when lines associated with r (see
+// [WithRegistry]) are emitted, after any hooks added before. Hooks run
+// before sampling, so samplers see the attributes they return, and before
+// keys are renamed or prefixed by the Emitter. They run each time a line is
+// emitted, including by each Emitter given to [WithTee]; use
+// [Registry.OnLogged] to observe each logged line once.
+func (r *Registry) OnEmit(hooks ...EmitHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Copy, so that slices returned by emitHooks are never modified.
+	r.onEmit = append(r.onEmit[:len(r.onEmit):len(r.onEmit)], hooks...)
+}
+
+// emitHooks returns the emit hooks of r. The returned slice must not be
+// modified.
+func (r *Registry) emitHooks() []EmitHook {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.onEmit
+}
+
+// WithEmitHook adds hooks to run when the new [Line] is emitted, after
+// those of its registry added with [Registry.OnEmit].
+func WithEmitHook(hooks ...EmitHook) LineOption {
+	return func(l *Line) {
+		l.onEmit = append(l.onEmit, hooks...)
+	}
+}
+
+// runEmitHooks returns attrs, the attributes of l being emitted, as
+// rewritten by the emit hooks of l's registry and of l itself.
+func (l *Line) runEmitHooks(ctx context.Context, attrs []slog.Attr) []slog.Attr {
+	for _, h := range l.registry.emitHooks() {
+		attrs = h(ctx, attrs)
+	}
+	for _, h := range l.onEmit {
+		attrs = h(ctx, attrs)
+	}
+	return attrs
+}
+
+// A LoggedHook runs once for each canonical log line that is logged, with
+// the context it was emitted with, its level and its attributes, so that
+// metrics derived from lines, such as latency histograms, count each line
+// once and only lines that were kept by the sampler. attrs holds the
+// attributes as returned by the emit hooks and seen by the sampler, before
+// the Emitter filters, renames or prefixes them. The hook must not modify or
+// retain attrs, and must be safe for concurrent use.
+type LoggedHook func(ctx context.Context, level slog.Level, attrs []slog.Attr)
+
+// OnLogged adds hooks to run when lines associated with r (see
+// [WithRegistry]) are logged, after any hooks added before. Hooks run the
+// first time a line is emitted, synchronously, once the [Emitter] has
+// decided to keep it, and not if every Emitter samples it out; of the
+// Emitters given to [WithTee], they run for the first that keeps the line.
+// Lines logged while still in progress, such as the interval lines of a
+// [Conn], and lines emitted again do not run them.
+func (r *Registry) OnLogged(hooks ...LoggedHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onLogged = append(r.onLogged[:len(r.onLogged):len(r.onLogged)], hooks...)
+}
+
+// runLoggedHooks runs the logged hooks of l's registry for the line being
+// logged with the given level and attributes.
+func (l *Line) runLoggedHooks(ctx context.Context, level slog.Level, attrs []slog.Attr) {
+	if l.registry == nil {
+		return
+	}
+	l.registry.mu.Lock()
+	hooks := l.registry.onLogged
+	l.registry.mu.Unlock()
+	for _, h := range hooks {
+		h(ctx, level, attrs)
+	}
+}
+
+// A SetHook runs whenever an attribute is set on a canonical log line, with
+// the context passed to [Set] and the attribute's key and the value passed
+// to Set, before it is merged with any existing value. Set hooks can
+// maintain attributes derived from others, or audit unexpected writes:
+//
+//	canonlog.DefaultRegistry.OnSet(func(ctx context.Context, key string, value any) {
+//		if d, ok := value.(time.Duration); ok && strings.HasPrefix(key, "phase_") {
+//			canonlog.Set(ctx, AttrSlowestPhase, phase{key, d})
+//		}
+//	})
+//
+// A hook may set attributes itself, which runs the hooks again, so it must
+// take care not to recurse forever. It must be safe for concurrent use.
+type SetHook func(ctx context.Context, key string, value any)
+
+// OnSet adds hooks to run when attributes are set on lines associated with
+// r (see [WithRegistry]), after any hooks added before. Hooks run
+// synchronously in the goroutine calling Set, after the value has been
+// stored, and add to the cost of every Set, so they should be fast.
+func (r *Registry) OnSet(hooks ...SetHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var all []SetHook
+	if p := r.onSet.Load(); p != nil {
+		all = *p
+	}
+	all = append(all[:len(all):len(all)], hooks...)
+	r.onSet.Store(&all)
+}
+
+// runSetHooks runs the set hooks of l's registry for a value set for key.
+// It is generic so that value is only converted to an interface, which may
+// allocate, if there are hooks to run. If ctx is nil, as for values set with
+// [SetOn], the hooks are passed a context carrying l.
+func runSetHooks[T any](ctx context.Context, l *Line, key string, value T) {
+	if l.registry == nil {
+		return
+	}
+	p := l.registry.onSet.Load()
+	if p == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = NewContext(context.Background(), l)
+	}
+	for _, h := range *p {
+		h(ctx, key, value)
+	}
+}
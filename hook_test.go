@@ -0,0 +1,139 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEmitHooks(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrEmail := RegisterWith[string](r, "email")
+
+	r.OnEmit(func(ctx context.Context, attrs []slog.Attr) []slog.Attr {
+		return slices.DeleteFunc(attrs, func(a slog.Attr) bool { return a.Key == "email" })
+	})
+	r.OnEmit(func(ctx context.Context, attrs []slog.Attr) []slog.Attr {
+		return append(attrs, slog.String("cost_center", "payments"))
+	})
+
+	var buf bytes.Buffer
+	ctx := New(context.Background(), WithRegistry(r),
+		WithEmitHook(func(ctx context.Context, attrs []slog.Attr) []slog.Attr {
+			for i, a := range attrs {
+				if a.Key == "user" {
+					attrs[i].Value = slog.StringValue("[redacted]")
+				}
+			}
+			return attrs
+		}),
+	)
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrEmail, "user@example.com")
+	NewEmitter(testLogger(&buf)).EmitOnReturn(ctx, nil)()
+
+	want := "level=INFO msg=canonical-log-line user=[redacted] outcome=success cost_center=payments\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// Lines of other registries are unaffected.
+	buf.Reset()
+	ctx = New(context.Background())
+	Set(ctx, attrEmail, "user@example.com")
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want = "level=INFO msg=canonical-log-line email=user@example.com\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestLoggedHooks(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	var logged []string
+	r.OnLogged(func(ctx context.Context, level slog.Level, attrs []slog.Attr) {
+		logged = append(logged, slog.GroupValue(attrs...).String())
+	})
+
+	drop := WithSampler(SamplerFunc(func(context.Context, slog.Level, []slog.Attr) SampleDecision {
+		return SampleDecision{}
+	}))
+	discard := slog.New(slog.DiscardHandler)
+	tests := []struct {
+		name    string
+		emitter *Emitter
+		want    []string
+	}{
+		{"kept", NewEmitter(discard), []string{"[user=usr_123]"}},
+		{"sampled_out", NewEmitter(discard, drop), nil},
+		{"tee", NewEmitter(discard, WithTee(NewEmitter(discard))), []string{"[user=usr_123]"}},
+		{"tee_kept", NewEmitter(discard, drop, WithTee(NewEmitter(discard, WithKeyPrefix("x.")))), []string{"[user=usr_123]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged = nil
+			ctx := New(context.Background(), WithRegistry(r))
+			Set(ctx, attrUser, "usr_123")
+			tt.emitter.Emit(ctx, slog.LevelInfo)
+			tt.emitter.Emit(ctx, slog.LevelInfo) // emitted again: not logged again
+			if !slices.Equal(logged, tt.want) {
+				t.Errorf("logged hooks ran for %q, want %q", logged, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetHooks(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrDBTime := RegisterWith(r, "phase_db",
+		WithMerge(func(old, new time.Duration) time.Duration { return old + new }),
+		WithCommutativeMerge[time.Duration](),
+	)
+	attrRenderTime := RegisterWith[time.Duration](r, "phase_render")
+	attrSlowest := RegisterWith[string](r, "slowest_phase")
+
+	var (
+		mu      sync.Mutex
+		slowest time.Duration
+		seen    []string
+	)
+	r.OnSet(func(ctx context.Context, key string, value any) {
+		mu.Lock()
+		seen = append(seen, key)
+		d, ok := value.(time.Duration)
+		ok = ok && d > slowest
+		if ok {
+			slowest = d
+		}
+		mu.Unlock()
+
+		if ok {
+			Set(ctx, attrSlowest, key)
+		}
+	})
+
+	var buf bytes.Buffer
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrDBTime, 30*time.Millisecond)
+	Set(ctx, attrRenderTime, 50*time.Millisecond)
+	SetMany(ctx, map[string]any{"region": "eu"})
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line user=usr_123 phase_db=30ms slowest_phase=phase_render phase_render=50ms region=eu\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+	wantSeen := []string{"user", "phase_db", "slowest_phase", "phase_render", "slowest_phase", "region"}
+	if !slices.Equal(seen, wantSeen) {
+		t.Errorf("hooks saw keys %q, want %q", seen, wantSeen)
+	}
+}
@@ -0,0 +1,276 @@
+package canonlog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpRegistry holds the attributes recorded by [Middleware]. They are kept
+// out of [DefaultRegistry] so that they cannot collide with keys registered
+// by users of the package.
+var httpRegistry = NewRegistry()
+
+var (
+	attrHTTPMethod = RegisterWith[string](httpRegistry, "http_method")
+	attrHTTPPath   = RegisterWith[string](httpRegistry, "http_path")
+	attrHTTPRoute  = RegisterWith[string](httpRegistry, "http_route")
+	attrHTTPStatus = RegisterWith[int](httpRegistry, "http_status")
+	attrDuration   = RegisterWith[time.Duration](httpRegistry, "duration")
+	attrRequestID  = RegisterWith[string](httpRegistry, "request_id")
+	attrReqBytes   = RegisterWith[int64](httpRegistry, "req_bytes")
+	attrRespBytes  = RegisterWith[int64](httpRegistry, "resp_bytes")
+)
+
+// MiddlewareOption configures the middleware returned by [Middleware].
+type MiddlewareOption func(*middlewareConfig)
+
+// middlewareConfig holds the configuration of a [Middleware].
+type middlewareConfig struct {
+	// onRequest functions are called, in order, with the context holding
+	// the request's Line before the wrapped handler is. Each returns the
+	// context to pass on, which may carry additional values.
+	onRequest []func(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context
+
+	lineOpts    []LineOption
+	emitterOpts []EmitterOption
+	pooled      bool
+
+	// routes matches requests to the routes given to WithRoute, which are
+	// also listed in routeList. defaultRoute applies to other requests.
+	routes       *http.ServeMux
+	routeList    []*routeHandler
+	defaultRoute *routeHandler
+
+	// routePattern returns the pattern of the route that served a request;
+	// see WithRoutePattern.
+	routePattern func(r *http.Request) string
+}
+
+// WithLineOptions makes the middleware create the [Line] of each request
+// with opts, such as [WithRegistry] or [WithLineEnricher].
+func WithLineOptions(opts ...LineOption) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.lineOpts = append(cfg.lineOpts, opts...)
+	}
+}
+
+// WithEmitterOptions makes the middleware emit the [Line] of each request
+// with an [Emitter] configured with opts, such as [WithSampler] or
+// [WithEnricher].
+func WithEmitterOptions(opts ...EmitterOption) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.emitterOpts = append(cfg.emitterOpts, opts...)
+	}
+}
+
+// WithPooledLines makes the middleware create the [Line] of each request
+// with [NewPooled] instead of [New], so that lines are reused once emitted.
+// It must only be used if no goroutine started by the wrapped handler uses
+// the request's context after the handler returns.
+func WithPooledLines() MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.pooled = true
+	}
+}
+
+// Middleware returns HTTP middleware that attaches a new [Line] to the
+// context of every request, records the request's method, path, response
+// status and duration, and the sizes of the request and response bodies
+// under the "req_bytes" and "resp_bytes" keys, and emits the line to logger
+// once the wrapped handler returns. Responses with a 5xx status are emitted
+// at [slog.LevelError], and all others at [slog.LevelInfo]. Use [WithRoute]
+// to handle some requests differently. If the handler panics, the line is
+// emitted with status 500 at slog.LevelError, with outcome=panic and the
+// panic value recorded under the "panic" key, as by [Emitter.Task], and the
+// panic is then resumed.
+//
+// If the request was routed by an [http.ServeMux], the pattern it matched,
+// such as "GET /users/{id}", is recorded under the "http_route" key, so that
+// lines can be grouped by endpoint rather than by raw path. The pattern is
+// read from [http.Request.Pattern], and so is only found if the ServeMux is
+// passed the request given to the wrapped handler, rather than a copy made
+// by another middleware; use [WithRoutePattern] for other routers.
+//
+// If logger is nil, [slog.Default] is used.
+func Middleware(logger *slog.Logger, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := middlewareConfig{
+		routePattern: func(r *http.Request) string { return r.Pattern },
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	create := New
+	if cfg.pooled {
+		create = NewPooled
+	}
+	cfg.initRoutes(logger)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := cfg.route(r)
+			if route.Skip {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ctx := create(r.Context(), cfg.lineOpts...)
+
+			Set(ctx, attrHTTPMethod, r.Method)
+			Set(ctx, attrHTTPPath, r.URL.Path)
+			if route.Attrs != nil {
+				SetMany(ctx, route.Attrs)
+			}
+			for _, fn := range cfg.onRequest {
+				ctx = fn(ctx, w, r)
+			}
+
+			r = r.WithContext(ctx)
+			var body *countingBody
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &countingBody{ReadCloser: r.Body}
+				r.Body = body
+			}
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if pattern := cfg.routePattern(r); pattern != "" {
+					Set(ctx, attrHTTPRoute, pattern)
+				}
+				p := recover()
+				status := rw.status
+				if p != nil {
+					status = http.StatusInternalServerError
+				}
+				Set(ctx, attrHTTPStatus, status)
+				Set(ctx, attrDuration, time.Since(start))
+				reqBytes := max(r.ContentLength, 0)
+				if r.ContentLength < 0 && body != nil {
+					reqBytes = body.n
+				}
+				Set(ctx, attrReqBytes, reqBytes)
+				Set(ctx, attrRespBytes, rw.written)
+
+				if p != nil {
+					route.emitter.emit(ctx, slog.LevelError,
+						slog.String("outcome", "panic"),
+						slog.Any("panic", p),
+					)
+					panic(p)
+				}
+				level := slog.LevelInfo
+				if status >= 500 {
+					level = slog.LevelError
+				}
+				route.emitter.Emit(ctx, level)
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// ServerOption returns a function that configures srv so that every request
+// it serves gets its own [Line], emitted to logger when the request
+// completes, without the caller having to build a middleware chain:
+//
+//	srv := &http.Server{Addr: ":8080", Handler: mux}
+//	canonlog.ServerOption(logger)(srv)
+//
+// A Line cannot be created from srv.BaseContext or srv.ConnContext, since
+// those run once per listener and once per connection respectively and a
+// connection may carry many requests. Instead, srv.Handler (or
+// [http.DefaultServeMux], if it is nil) is wrapped with [Middleware],
+// configured with opts.
+func ServerOption(logger *slog.Logger, opts ...MiddlewareOption) func(*http.Server) {
+	return func(srv *http.Server) {
+		h := srv.Handler
+		if h == nil {
+			h = http.DefaultServeMux
+		}
+		srv.Handler = Middleware(logger, opts...)(h)
+	}
+}
+
+// responseWriter wraps an [http.ResponseWriter] to record the response
+// status code and the number of body bytes written. It implements
+// [http.Flusher], [http.Hijacker] and [io.ReaderFrom] whether or not the
+// underlying ResponseWriter does, so that handlers checking for them keep
+// working; [http.ResponseController] is preferred, and is supported
+// through Unwrap.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	written     int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// ReadFrom implements [io.ReaderFrom], using the underlying ResponseWriter's
+// implementation, such as sendfile for files, if it has one.
+func (w *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.wroteHeader = true
+	var (
+		n   int64
+		err error
+	)
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		// Hide ReadFrom from io.Copy, which would otherwise call it.
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, src)
+	}
+	w.written += n
+	return n, err
+}
+
+// Flush implements [http.Flusher]. It does nothing if the underlying
+// ResponseWriter does not support flushing.
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements [http.Hijacker]. It returns an error wrapping
+// [http.ErrNotSupported] if the underlying ResponseWriter does not support
+// hijacking.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by
+// [http.ResponseController].
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// countingBody wraps a request body to count the bytes read from it.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
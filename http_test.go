@@ -0,0 +1,192 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			Set(r.Context(), attrUser, "usr_123")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "not found")
+		})
+
+		req := httptest.NewRequest("PUT", "/users/123", strings.NewReader(`{"name":"Ann"}`))
+		Middleware(testLogger(&buf))(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		want := "level=INFO msg=canonical-log-line http_method=PUT http_path=/users/123 user=usr_123 http_status=404 duration=20ms req_bytes=14 resp_bytes=9\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestMiddleware_RoutePattern(t *testing.T) {
+	var buf bytes.Buffer
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	h := Middleware(testLogger(&buf))(mux)
+
+	tests := []struct {
+		path string
+		want string // log output, up to the status
+	}{
+		{"/users/123", "level=INFO msg=canonical-log-line http_method=GET http_path=/users/123 http_route=\"GET /users/{id}\" http_status=200"},
+		{"/missing", "level=INFO msg=canonical-log-line http_method=GET http_path=/missing http_status=404"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("GET %s: log output:\ngot:  %q\nwant: %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Other routers can supply the pattern.
+	buf.Reset()
+	h = Middleware(testLogger(&buf),
+		WithRoutePattern(func(r *http.Request) string { return "/users/:id" }),
+	)(mux)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/123", nil))
+	if got := buf.String(); !strings.Contains(got, " http_route=/users/:id ") {
+		t.Errorf("log output = %q, want http_route=/users/:id", got)
+	}
+}
+
+func TestMiddleware_ServerErrorLevel(t *testing.T) {
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.WriteHeader(http.StatusOK) // superfluous; ignored
+	})
+
+	req := httptest.NewRequest("POST", "/", nil)
+	Middleware(testLogger(&buf))(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := buf.String(); !strings.HasPrefix(got, "level=ERROR ") {
+		t.Errorf("log output = %q, want ERROR level", got)
+	}
+	if got := buf.String(); !strings.Contains(got, "http_status=502") {
+		t.Errorf("log output = %q, want http_status=502", got)
+	}
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v, want the handler's panic", p)
+			}
+		}()
+		Middleware(testLogger(&buf))(handler).ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	got := buf.String()
+	if !strings.HasPrefix(got, "level=ERROR ") {
+		t.Errorf("log output = %q, want ERROR level", got)
+	}
+	for _, want := range []string{" http_status=500 ", " outcome=panic panic=boom\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestServerOption(t *testing.T) {
+	var buf bytes.Buffer
+	var sawLine bool
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sawLine = FromContext(r.Context()) != nil
+		}),
+	}
+	ServerOption(testLogger(&buf))(srv)
+
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !sawLine {
+		t.Error("handler did not see a Line in the request context")
+	}
+	if buf.Len() == 0 {
+		t.Error("no canonical log line was emitted")
+	}
+}
+
+func TestMiddleware_WithLineOptions(t *testing.T) {
+	r := testRegistry(t)
+	r.SetSchemaVersion("3")
+
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := Middleware(testLogger(&buf), WithLineOptions(WithRegistry(r)))
+	mw(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if got := buf.String(); !strings.Contains(got, " schema_version=3 ") {
+		t.Errorf("log output = %q, want schema_version=3", got)
+	}
+}
+
+func TestMiddleware_WithEmitterOptions(t *testing.T) {
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := Middleware(testLogger(&buf), WithEmitterOptions(
+		WithEnricher(EnricherFunc(func(context.Context) []slog.Attr {
+			return []slog.Attr{slog.String("region", "eu")}
+		})),
+	))
+	mw(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if got := buf.String(); !strings.Contains(got, " region=eu") {
+		t.Errorf("log output = %q, want region=eu", got)
+	}
+}
+
+func TestMiddleware_Sizes(t *testing.T) {
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("ResponseWriter does not implement http.Flusher")
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("ResponseWriter does not implement http.Hijacker")
+		}
+		io.Copy(w, strings.NewReader("chunk one, "))
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "chunk two")
+	})
+
+	// A body of unknown length is counted as it is read.
+	req := httptest.NewRequest("POST", "/", io.MultiReader(strings.NewReader("abc"), strings.NewReader("def")))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	Middleware(testLogger(&buf))(handler).ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("Flush was not passed through")
+	}
+	if got, want := buf.String(), " req_bytes=6 resp_bytes=20\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("log output = %q, want suffix %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package canonlog
+
+// WithInherit makes the new [Line] start with the current values of the
+// given attributes in the Line attached to the context passed to [New], if
+// it has one, so that the line of a sub-operation can be correlated with
+// that of the request it belongs to:
+//
+//	ctx = canonlog.New(ctx, canonlog.WithInherit(AttrRequestID, AttrTenant))
+//
+// Attributes that are not set in the parent line are ignored. The values
+// are copied, so later Sets on either line do not affect the other.
+func WithInherit(attrs ...interface{ Key() string }) LineOption {
+	return func(l *Line) {
+		keys := make(map[string]bool, len(attrs))
+		for _, attr := range attrs {
+			keys[attr.Key()] = true
+		}
+		l.inherit(func(key string) bool { return keys[key] })
+	}
+}
+
+// WithInheritAll is like [WithInherit], but inherits every attribute set in
+// the parent line.
+func WithInheritAll() LineOption {
+	return func(l *Line) {
+		l.inherit(func(string) bool { return true })
+	}
+}
+
+// inherit copies the values of l.parent for which keep returns true into l,
+// in the order they were first set on the parent.
+func (l *Line) inherit(keep func(key string) bool) {
+	p := l.parent
+	if p == nil {
+		return
+	}
+
+	p.lockAll()
+	defer p.unlockAll()
+
+	// l is not yet shared with other goroutines, so needs no locking.
+	for _, e := range p.entries() {
+		if keep(e.key) {
+			l.put(l.shard(e.key), e.key, e.sv.snapshot())
+		}
+	}
+}
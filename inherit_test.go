@@ -0,0 +1,69 @@
+package canonlog
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestWithInherit(t *testing.T) {
+	r := testRegistry(t)
+	attrRequestID := RegisterWith[string](r, "request_id")
+	attrTenant := RegisterWith[string](r, "tenant")
+	attrStatus := RegisterWith[int](r, "status")
+	attrCalls := RegisterWith(r, "calls",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	parent := New(context.Background())
+	Set(parent, attrTenant, "acme")
+	Set(parent, attrRequestID, "req_1")
+	Set(parent, attrStatus, 200)
+	Set(parent, attrCalls, 2)
+
+	tests := []struct {
+		name string
+		opt  LineOption
+		want []string
+	}{
+		{
+			name: "selected",
+			opt:  WithInherit(attrRequestID, attrTenant, attrCalls),
+			want: []string{"tenant", "request_id", "calls"},
+		},
+		{
+			name: "all",
+			opt:  WithInheritAll(),
+			want: []string{"tenant", "request_id", "status", "calls"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child := New(parent, tt.opt)
+			Set(child, attrCalls, 1)
+
+			var got []string
+			for _, a := range Attrs(child) {
+				got = append(got, a.Key)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("child keys = %v, want %v", got, tt.want)
+			}
+			if calls, _ := lookup(FromContext(child), attrCalls); calls != 3 {
+				t.Errorf("child calls = %d, want 3", calls)
+			}
+			if calls, _ := lookup(FromContext(parent), attrCalls); calls != 2 {
+				t.Errorf("parent calls = %d, want 2", calls)
+			}
+		})
+	}
+}
+
+func TestWithInherit_WithoutParent(t *testing.T) {
+	attrUser := RegisterWith[string](testRegistry(t), "user")
+	ctx := New(context.Background(), WithInherit(attrUser))
+	if attrs := Attrs(ctx); attrs != nil {
+		t.Errorf("line has attributes %v, want none", attrs)
+	}
+}
@@ -0,0 +1,105 @@
+package canonlog
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"sync/atomic"
+)
+
+// installed holds the emitter options given to Install.
+var installed atomic.Pointer[[]EmitterOption]
+
+// builtinHandler is the handler of slog's initial default logger, captured
+// before any package importing canonlog can replace it.
+var builtinHandler = slog.Default().Handler()
+
+// InstallOption configures [Install].
+type InstallOption func(*installConfig)
+
+type installConfig struct {
+	emitterOpts []EmitterOption
+	static      []slog.Attr
+}
+
+// WithDefaultEmitterOptions configures the Emitters created after [Install]
+// by [NewEmitter] with a nil logger with opts, such as [WithSampler], before
+// their own options.
+func WithDefaultEmitterOptions(opts ...EmitterOption) InstallOption {
+	return func(cfg *installConfig) {
+		cfg.emitterOpts = append(cfg.emitterOpts, opts...)
+	}
+}
+
+// WithGlobalAttrs makes [Install] include attrs in every line associated
+// with [DefaultRegistry], as static attributes (see [SetGlobalWith]), for
+// process-level constants such as the service name or region. Their keys
+// need not be registered.
+func WithGlobalAttrs(attrs ...slog.Attr) InstallOption {
+	return func(cfg *installConfig) {
+		cfg.static = append(cfg.static, attrs...)
+	}
+}
+
+// Install sets up canonlog as recommended for a service in one call, at
+// startup:
+//
+//   - logger's handler is wrapped with [NewHandler], so that warnings and
+//     errors logged during an operation are counted in its line, and the
+//     result made the default logger with [slog.SetDefault];
+//   - build information, and any attributes given to [WithGlobalAttrs], are
+//     included in every line associated with [DefaultRegistry], as
+//     described for [SetBuildInfo];
+//   - Emitters created afterwards by [NewEmitter] with a nil logger, such
+//     as by [Emit] and [Middleware] given a nil logger, are configured with
+//     the options given to [WithDefaultEmitterOptions], and log to the
+//     default logger.
+//
+// Adopting canonlog in an HTTP service is then a matter of:
+//
+//	canonlog.Install(logger,
+//		canonlog.WithGlobalAttrs(slog.String("service", "checkout")),
+//		canonlog.WithDefaultEmitterOptions(canonlog.WithSampler(sampler)),
+//	)
+//	srv.Handler = canonlog.Middleware(nil)(mux)
+//
+// If logger is nil, the current default logger is used, unless it is still
+// slog's built-in one, which writes through the [log] package and so cannot
+// be wrapped once [slog.SetDefault] points that package back at slog; a
+// [slog.TextHandler] writing to standard error is used instead. Install
+// replaces the emitter options of any previous call.
+func Install(logger *slog.Logger, opts ...InstallOption) {
+	install(DefaultRegistry, logger, opts)
+}
+
+// install is the implementation of [Install], for the registry r.
+func install(r *Registry, logger *slog.Logger, opts []InstallOption) {
+	var cfg installConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+	h := logger.Handler()
+	if isBuiltinHandler(h) {
+		h = slog.NewTextHandler(os.Stderr, nil)
+	}
+	slog.SetDefault(slog.New(NewHandler(h)))
+	SetBuildInfoWith(r)
+	for _, a := range cfg.static {
+		r.setStatic(staticValue{key: a.Key, name: a.Key, value: a.Value.Resolve()})
+	}
+	installed.Store(&cfg.emitterOpts)
+}
+
+// isBuiltinHandler reports whether h is the handler of slog's initial
+// default logger, possibly wrapped by [NewHandler].
+func isBuiltinHandler(h slog.Handler) bool {
+	if ch, ok := h.(*countingHandler); ok {
+		h = ch.next
+	}
+	// Handlers of other types may not be comparable.
+	return reflect.TypeOf(h) == reflect.TypeOf(builtinHandler) && h == builtinHandler
+}
@@ -0,0 +1,76 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstall(t *testing.T) {
+	old := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(old)
+		installed.Store(nil)
+	})
+
+	r := testRegistry(t)
+	var buf bytes.Buffer
+	install(r, testLogger(&buf), []InstallOption{
+		WithGlobalAttrs(slog.String("service", "checkout")),
+		WithDefaultEmitterOptions(WithKeyPrefix("canon.")),
+	})
+
+	ctx := New(context.Background(), WithRegistry(r))
+	slog.WarnContext(ctx, "slow query")
+	buf.Reset()
+	Emit(ctx, nil, slog.LevelInfo)
+
+	got := buf.String()
+	for _, want := range []string{
+		"msg=canonical-log-line canon.log_warnings=1 ",
+		" canon.go_version=",
+		" canon.service=checkout",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output = %q, want %q", got, want)
+		}
+	}
+
+	// Emitters given a logger are unaffected.
+	buf.Reset()
+	Emit(New(context.Background()), testLogger(&buf), slog.LevelInfo)
+	if got, want := buf.String(), "level=INFO msg=canonical-log-line\n"; got != want {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+}
+
+func TestInstallNil(t *testing.T) {
+	old := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(old)
+		installed.Store(nil)
+	})
+
+	if !isBuiltinHandler(slog.Default().Handler()) {
+		t.Skip("default logger already replaced")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		install(testRegistry(t), nil, nil)
+		slog.Info("installed")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Install(nil) did not return")
+	}
+
+	if isBuiltinHandler(slog.Default().Handler()) {
+		t.Error("default handler still the built-in one after Install(nil)")
+	}
+}
@@ -0,0 +1,130 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// SetLazy sets the value of attr in the [Line] attached to ctx to the result
+// of fn, which is only called if and when the line is actually written by a
+// handler, and at most once. It is intended for values that are expensive to
+// compute, such as serialized summaries, that would be wasted on lines
+// dropped by sampling or by the logger's level:
+//
+//	canonlog.SetLazy(ctx, AttrCartSummary, func() string {
+//		return cart.Summary()
+//	})
+//
+// The value is emitted as an [slog.LogValuer], so a [Sampler] or
+// [EmitHook] that needs it must call [slog.Value.Resolve]. Reading the line
+// in other ways, such as with [Dump], calls fn immediately, but [Join]
+// leaves it to be called when the parent line is emitted. fn may
+// be called from the goroutine emitting the line, after the function that
+// called SetLazy has returned, and must be safe to call from there.
+//
+// SetLazy overwrites any previous value of attr, ignoring its merge
+// function, and a value set later with [Set] overwrites the lazy value
+// rather than being merged with it. Set hooks (see [Registry.OnSet]) are not
+// run, as the value is not known, and only the key counts towards the limit
+// set by [Registry.SetMaxBytes]. If the context does not have a Line,
+// SetLazy does nothing, other than reporting it as described for [Set].
+func SetLazy[T any](ctx context.Context, attr Attr[T], fn func() T) {
+	l := FromContext(ctx)
+	if l == nil {
+		noLine(attr.key)
+		return
+	}
+	lv := &lazyValue[T]{fn: fn, convert: attr.convert, merge: attr.merge}
+	l.setLazy(attr.key, newStoredValue(attr, lv), false)
+}
+
+// setLazy stores sv, holding a lazy value, for key in l, overwriting any
+// previous value as described for [SetLazy], or if merge is set, merging
+// it with the lazy value when that is computed, as [Join] does.
+func (l *Line) setLazy(key string, sv storedValue, merge bool) {
+	if l.lateSet(key) {
+		return
+	}
+
+	s := l.shard(key)
+	s.mu.Lock()
+	existing, exists := s.get(key)
+	if merge && exists {
+		sv.raw = sv.raw.(lazy).after(existing.raw)
+	}
+	sv.size = sizeOf(key, sv.raw, l.maxBytes)
+	if !l.reserve(existing.charged(), sv.size) {
+		s.mu.Unlock()
+		return
+	}
+	if _, ok := existing.raw.(accumulated); ok {
+		l.accums.Delete(key)
+	}
+	l.put(s, key, sv)
+	s.mu.Unlock()
+
+	l.recordCaller(key)
+}
+
+// lazy is implemented by the values stored by [SetLazy].
+type lazy interface {
+	slog.LogValuer
+
+	// eval returns the value, calling the function computing it if it
+	// has not been called yet.
+	eval() any
+
+	// after returns a lazy value that, when computed, merges the value
+	// old it replaces, itself computed then if it is lazy, with its own,
+	// using the merge function of its attribute, if it has one.
+	after(old any) lazy
+}
+
+// lazyValue is a value of type T computed by fn when first needed.
+type lazyValue[T any] struct {
+	fn      func() T
+	convert func(any) slog.Value // see Attr.convert
+	merge   func(old, new T) T   // see Attr.merge
+
+	once sync.Once
+	v    T
+}
+
+func (lv *lazyValue[T]) eval() any {
+	lv.once.Do(func() { lv.v = lv.fn() })
+	return lv.v
+}
+
+func (lv *lazyValue[T]) after(old any) lazy {
+	if lv.merge == nil {
+		return lv
+	}
+	if a, ok := old.(accumulated); ok {
+		old = a.load()
+	}
+	prev := func() any { return old }
+	if o, ok := old.(lazy); ok {
+		prev = o.eval
+	}
+	return &lazyValue[T]{
+		fn: func() T {
+			v := lv.eval().(T)
+			if old, ok := prev().(T); ok {
+				return lv.merge(old, v)
+			}
+			return v
+		},
+		convert: lv.convert,
+		merge:   lv.merge,
+	}
+}
+
+// LogValue implements [slog.LogValuer].
+func (lv *lazyValue[T]) LogValue() slog.Value {
+	v := lv.eval()
+	if lv.convert != nil {
+		return lv.convert(v)
+	}
+	return slog.AnyValue(v)
+}
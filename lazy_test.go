@@ -0,0 +1,82 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"testing"
+)
+
+func TestSetLazy(t *testing.T) {
+	r := testRegistry(t)
+	attrSummary := RegisterWith[string](r, "summary")
+	attrItems := RegisterWith(r, "items",
+		WithGroup[int]("cart"),
+		WithValue(func(n int) slog.Value { return slog.StringValue(strconv.Itoa(n) + " items") }),
+	)
+
+	calls := 0
+	summary := func() string {
+		calls++
+		return "expensive"
+	}
+	newLine := func() context.Context {
+		ctx := New(context.Background(), WithRegistry(r))
+		SetLazy(ctx, attrSummary, summary)
+		SetLazy(ctx, attrItems, func() int { return 3 })
+		return ctx
+	}
+
+	// Lines dropped by sampling or by the logger's level are not
+	// evaluated.
+	var buf bytes.Buffer
+	drop := SamplerFunc(func(context.Context, slog.Level, []slog.Attr) SampleDecision {
+		return SampleDecision{}
+	})
+	NewEmitter(testLogger(&buf), WithSampler(drop)).Emit(newLine(), slog.LevelInfo)
+	Emit(newLine(), testLogger(&buf), slog.LevelDebug)
+	if calls != 0 {
+		t.Errorf("lazy value evaluated %d times for dropped lines", calls)
+	}
+
+	// Emitted lines evaluate the value once, however often they are
+	// emitted.
+	ctx := newLine()
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	if calls != 1 {
+		t.Errorf("lazy value evaluated %d times, want 1", calls)
+	}
+	want := `level=INFO msg=canonical-log-line summary=expensive cart.items="3 items"` + "\n"
+	if got := buf.String(); got != want+want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want+want)
+	}
+}
+
+func TestSetLazyOverwrite(t *testing.T) {
+	r := testRegistry(t)
+	attrCount := RegisterWith(r, "count", WithMerge(func(old, new int) int { return old + new }))
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrCount, 1)
+	SetLazy(ctx, attrCount, func() int { return 10 })
+	if got, want := Dump(ctx), "count=10\n"; got != want {
+		t.Errorf("after SetLazy: Dump = %q, want %q", got, want)
+	}
+	Set(ctx, attrCount, 2)
+	if got, want := Dump(ctx), "count=2\n"; got != want {
+		t.Errorf("after Set: Dump = %q, want %q", got, want)
+	}
+
+	// Joining a line merges its lazy values with the parent's once they
+	// are computed.
+	parent := New(context.Background(), WithRegistry(r))
+	Set(parent, attrCount, 5)
+	child := Fork(parent)
+	SetLazy(child, attrCount, func() int { return 10 })
+	Join(parent, child)
+	if got, want := Dump(parent), "count=15\n"; got != want {
+		t.Errorf("after Join: Dump = %q, want %q", got, want)
+	}
+}
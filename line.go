@@ -0,0 +1,39 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewLine creates a new [Line] that is not attached to a context, for code
+// that holds a line directly, such as a worker pool recording a job, or a
+// test. Use [SetOn] to add attributes to it, and [NewContext] to attach it
+// to a context, such as to emit it. Enrichers given to [WithLineEnricher]
+// are called with a context carrying only the new line.
+func NewLine(opts ...LineOption) *Line {
+	l := new(Line)
+	newLine(context.Background(), l, opts)
+	return l
+}
+
+// NewContext returns a copy of ctx carrying l, so that l is used by [Set],
+// [Emit] and the other functions taking a context.
+func NewContext(ctx context.Context, l *Line) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// SetOn is like [Set], for a Line held directly. Set hooks (see
+// [Registry.OnSet]) are called with a context carrying l. If l is nil, SetOn
+// does nothing, other than reporting the mistake as Set does.
+//
+// Go does not allow generic methods, so this is a function rather than a
+// method of Line.
+func SetOn[T any](l *Line, attr Attr[T], value T) {
+	set(nil, l, attr, value)
+}
+
+// Attrs is like the function [Attrs], for a Line held directly. It returns
+// nil if l is nil.
+func (l *Line) Attrs() []slog.Attr {
+	return l.AppendAttrs(nil)
+}
@@ -0,0 +1,56 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestNewLine(t *testing.T) {
+	r := testRegistry(t)
+	attrJob := RegisterWith[string](r, "job")
+	attrItems := RegisterCounterWith[int](r, "items")
+	attrDerived := RegisterWith[string](r, "derived")
+	r.OnSet(func(ctx context.Context, key string, value any) {
+		if key == "job" {
+			Set(ctx, attrDerived, "from "+value.(string))
+		}
+	})
+
+	l := NewLine(WithRegistry(r))
+	SetOn(l, attrJob, "resize")
+	SetOn(l, attrItems, 2)
+	SetOn(l, attrItems, 3)
+
+	want := []slog.Attr{
+		slog.String("job", "resize"),
+		slog.String("derived", "from resize"),
+		slog.Int("items", 5),
+	}
+	if got := l.Attrs(); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	ctx := NewContext(context.Background(), l)
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	if got, want := buf.String(), "level=INFO msg=canonical-log-line job=resize derived=\"from resize\" items=5\n"; got != want {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+
+	// The line is frozen once emitted, however it is set.
+	SetOn(l, attrJob, "crop")
+	if got := l.Attrs(); got[0].Value.String() != "resize" {
+		t.Errorf("Attrs after emitting = %v, want job=resize", got)
+	}
+}
+
+func TestNilLine(t *testing.T) {
+	var l *Line
+	SetOn(l, RegisterWith[int](testRegistry(t), "n"), 1)
+	if got := l.Attrs(); got != nil {
+		t.Errorf("Attrs of a nil line = %v, want nil", got)
+	}
+}
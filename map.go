@@ -0,0 +1,93 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// Number is a constraint that permits any integer or floating-point type.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// RegisterMapWith creates a new map-valued attribute with the given key in
+// the specified registry. It panics if an attribute with the same key has
+// already been registered in that registry.
+//
+// Entries of a map-valued attribute are set individually with [SetKey], and
+// repeated Sets of the same entry are summed. The attribute is emitted as an
+// [slog.Group] with one member per entry, sorted by entry key, which is
+// useful for per-dependency breakdowns:
+//
+//	var AttrCalls = canonlog.RegisterMap[int64]("calls_by_backend")
+//
+//	canonlog.SetKey(ctx, AttrCalls, "billing", 1)
+//	canonlog.SetKey(ctx, AttrCalls, "billing", 1)
+//	canonlog.SetKey(ctx, AttrCalls, "users", 1)
+//	// emitted as calls_by_backend.billing=2 calls_by_backend.users=1
+//
+// To combine entries other than by summing them, pass [WithMerge] with a
+// function built by [MergeMap], followed by [WithCommutativeMerge] if that
+// function is commutative and associative.
+func RegisterMapWith[V Number](r *Registry, key string, opts ...Option[map[string]V]) Attr[map[string]V] {
+	opts = append([]Option[map[string]V]{
+		WithMerge(MergeMap(func(old, new V) V { return old + new })),
+		WithCommutativeMerge[map[string]V](),
+		WithValue(mapValue[V]),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterMap creates a new map-valued attribute with the given key using
+// [DefaultRegistry]. See [RegisterMapWith] for details.
+func RegisterMap[V Number](key string, opts ...Option[map[string]V]) Attr[map[string]V] {
+	return RegisterMapWith(DefaultRegistry, key, opts...)
+}
+
+// MergeMap returns a merge function for map-valued attributes, for use with
+// [WithMerge], that combines entries present in both maps using fn and keeps
+// all other entries unchanged. Neither input map is modified.
+//
+// Example:
+//
+//	var AttrMaxLatency = canonlog.RegisterMap[time.Duration]("max_latency_by_backend",
+//		canonlog.WithMerge(canonlog.MergeMap(func(old, new time.Duration) time.Duration {
+//			return max(old, new)
+//		})),
+//	)
+func MergeMap[V any](fn func(old, new V) V) func(old, new map[string]V) map[string]V {
+	return func(old, new map[string]V) map[string]V {
+		merged := maps.Clone(old)
+		if merged == nil {
+			merged = make(map[string]V, len(new))
+		}
+		for k, v := range new {
+			if existing, ok := merged[k]; ok {
+				v = fn(existing, v)
+			}
+			merged[k] = v
+		}
+		return merged
+	}
+}
+
+// SetKey stores value under the entry k of the map-valued attribute attr in
+// the [Line] attached to ctx, merging it with any existing value for that
+// entry. If the context does not have a Line, SetKey silently does nothing.
+func SetKey[V any](ctx context.Context, attr Attr[map[string]V], k string, value V) {
+	Set(ctx, attr, map[string]V{k: value})
+}
+
+// mapValue converts a map to an [slog.Group] value with members sorted by
+// key.
+func mapValue[V any](m map[string]V) slog.Value {
+	attrs := make([]slog.Attr, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		attrs = append(attrs, slog.Any(k, m[k]))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,64 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRegisterMap(t *testing.T) {
+	r := testRegistry(t)
+	attrCalls := RegisterMapWith[int64](r, "calls_by_backend")
+
+	ctx := New(context.Background())
+	SetKey(ctx, attrCalls, "users", 1)
+	SetKey(ctx, attrCalls, "billing", 1)
+	SetKey(ctx, attrCalls, "billing", 2)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line calls_by_backend.billing=3 calls_by_backend.users=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRegisterMap_CustomMerge(t *testing.T) {
+	r := testRegistry(t)
+	attrMax := RegisterMapWith(r, "max_latency",
+		WithMerge(MergeMap(func(old, new time.Duration) time.Duration {
+			return max(old, new)
+		})),
+	)
+
+	ctx := New(context.Background())
+	SetKey(ctx, attrMax, "db", 20*time.Millisecond)
+	SetKey(ctx, attrMax, "db", 50*time.Millisecond)
+	SetKey(ctx, attrMax, "db", 10*time.Millisecond)
+
+	group := Attrs(ctx)[0].Value.Group()
+	if len(group) != 1 {
+		t.Fatalf("max_latency has %d entries, want 1", len(group))
+	}
+	if got := group[0].Value.Duration(); got != 50*time.Millisecond {
+		t.Errorf("max_latency.db = %v, want %v", got, 50*time.Millisecond)
+	}
+}
+
+func TestMergeMap_DoesNotModifyInputs(t *testing.T) {
+	merge := MergeMap(func(old, new int) int { return old + new })
+
+	old := map[string]int{"a": 1}
+	new := map[string]int{"a": 2, "b": 3}
+	got := merge(old, new)
+
+	if got["a"] != 3 || got["b"] != 3 {
+		t.Errorf("merged = %v, want map[a:3 b:3]", got)
+	}
+	if len(old) != 1 || old["a"] != 1 {
+		t.Errorf("old map modified to %v", old)
+	}
+}
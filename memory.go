@@ -0,0 +1,98 @@
+package canonlog
+
+import "reflect"
+
+// CappedSetsKey is the key of the attribute that records the number of
+// values dropped from a [Line] because they would have taken it over the
+// size set with [Registry.SetMaxBytes].
+const CappedSetsKey = "canonlog_capped_sets"
+
+// SetMaxBytes limits the approximate number of bytes of attribute values
+// held by each line associated with r (see [WithRegistry]) and created
+// afterwards to n, so that a single pathological request, such as one
+// appending to a list attribute in a loop, cannot hold megabytes of data
+// until it is emitted. A Set that would take a line over the limit is
+// dropped, leaving any previous value in place, and counted in the line's
+// [CappedSetsKey] attribute. A limit of zero, the default, disables it.
+//
+// Sizes are estimated from the length of strings, slices and maps, and the
+// memory size of other values, and include the attribute's key. Values of
+// attributes registered with [WithCommutativeMerge], such as sets, maps and
+// top-K lists, are counted as they grow, and a value merged into one that
+// would exceed the limit is dropped in the same way; on limited lines they
+// are accumulated without sharding, so that their size is known. Counters
+// (see [RegisterCounterWith]) hold a number of fixed size and are not
+// counted.
+func (r *Registry) SetMaxBytes(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxBytes = n
+}
+
+// MaxBytes returns the limit set with [Registry.SetMaxBytes].
+func (r *Registry) MaxBytes() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxBytes
+}
+
+// reserve reports whether replacing a value of size old in l with one of
+// size new keeps l within its limit, and if so accounts for it. Otherwise it
+// counts the value as dropped.
+func (l *Line) reserve(old, new int) bool {
+	if l.maxBytes == 0 {
+		return true
+	}
+	delta := int64(new - old)
+	for {
+		bytes := l.bytes.Load()
+		if bytes+delta > int64(l.maxBytes) {
+			l.cappedSets.Add(1)
+			cappedSets.Add(1)
+			return false
+		}
+		if l.bytes.CompareAndSwap(bytes, bytes+delta) {
+			return true
+		}
+	}
+}
+
+// account recomputes the size of l's values, such as those inherited from
+// its parent, for its size limit. The values are kept even if they exceed
+// it. It is only called while l is being created.
+func (l *Line) account() {
+	if l.maxBytes == 0 {
+		return
+	}
+	var bytes int
+	for i := range l.shards {
+		for j := range l.shards[i].entries {
+			e := &l.shards[i].entries[j]
+			e.sv.size = sizeOf(e.key, e.sv.raw, l.maxBytes)
+			bytes += e.sv.size
+		}
+	}
+	l.bytes.Store(int64(bytes))
+}
+
+// sizeOf returns the approximate number of bytes held by the value v of the
+// attribute with the given key, for lines limited to budget bytes, or zero
+// if budget is zero.
+func sizeOf(key string, v any, budget int) int {
+	if budget == 0 {
+		return 0
+	}
+	size := len(key)
+	switch v := v.(type) {
+	case string:
+		return size + len(v)
+	case []byte:
+		return size + len(v)
+	case lazy:
+		return size // not known until the line is emitted
+	}
+	return size + estimateSize(reflect.ValueOf(v), budget+1, make(map[uintptr]bool))
+}
@@ -0,0 +1,60 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestSetMaxBytes(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrHosts := RegisterWith[[]string](r, "hosts")
+	r.SetMaxBytes(40)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")                 // 11 bytes
+	Append(ctx, attrHosts, "db-1.internal")       // 18 bytes, 29 in total
+	Append(ctx, attrHosts, "db-2.internal")       // 31 bytes, 42 in total: dropped
+	Set(ctx, attrUser, "usr_1")                   // 9 bytes, 27 in total
+	SetMany(ctx, map[string]any{"note": "x"})     // 5 bytes, 32 in total
+	Set(ctx, attrUser, "a much longer user name") // dropped
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line user=usr_1 hosts=[db-1.internal] note=x canonlog_capped_sets=2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// Lines of other registries are not limited.
+	ctx = New(context.Background())
+	Append(ctx, attrHosts, "db-1.internal", "db-2.internal", "db-3.internal")
+	if got := len(Attrs(ctx)); got != 1 {
+		t.Errorf("unlimited line has %d attributes, want 1", got)
+	}
+}
+
+func TestSetMaxBytes_Set(t *testing.T) {
+	r := testRegistry(t)
+	attrTables := RegisterSetWith[string](r, "tables")
+	r.SetMaxBytes(100)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	for i := range 1000 {
+		Set(ctx, attrTables, []string{fmt.Sprintf("table_%03d", i)})
+	}
+	Set(ctx, attrTables, []string{"table_000"}) // already present: kept
+
+	got := AttrsMap(ctx)
+	tables, _ := got["tables"].Any().([]string)
+	if size := sizeOf("tables", tables, 100); size > 100 || len(tables) == 0 {
+		t.Errorf("set holds %d values of %d bytes, want at most 100 bytes", len(tables), size)
+	}
+	if capped, want := got[CappedSetsKey].Int64(), int64(1000-len(tables)); capped != want {
+		t.Errorf("%s = %d, want %d", CappedSetsKey, capped, want)
+	}
+}
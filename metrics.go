@@ -0,0 +1,53 @@
+package canonlog
+
+import "sync/atomic"
+
+// Counters of the process-wide metrics returned by ReadMetrics, other than
+// lateSets.
+var (
+	linesEmitted    atomic.Uint64
+	linesSampledOut atomic.Uint64
+	cappedSets      atomic.Uint64
+	invalidValues   atomic.Uint64
+)
+
+// Metrics holds counters describing the health of canonlog itself in the
+// process, since it started, as returned by [ReadMetrics].
+type Metrics struct {
+	// LinesEmitted is the number of lines logged by Emitters, counting a
+	// line once for each Emitter given to WithTee that logs it too.
+	LinesEmitted uint64
+
+	// LinesSampledOut is the number of lines that Emitters did not log
+	// because their Sampler dropped them.
+	LinesSampledOut uint64
+
+	// LateSets is the number of attempts to set attributes on lines after
+	// they were emitted, as returned by [LateSets].
+	LateSets uint64
+
+	// CappedSets is the number of values dropped for exceeding the size
+	// limit of their line; see [Registry.SetMaxBytes].
+	CappedSets uint64
+
+	// InvalidValues is the number of values rejected by the validator of
+	// their attribute; see [WithValidator].
+	InvalidValues uint64
+}
+
+// ReadMetrics returns the current values of canonlog's metrics, for
+// exporting them, so that operators can be alerted when the pipeline
+// producing canonical lines is unhealthy. To publish them with expvar:
+//
+//	expvar.Publish("canonlog", expvar.Func(func() any { return canonlog.ReadMetrics() }))
+//
+// The canonprom module exports them to Prometheus.
+func ReadMetrics() Metrics {
+	return Metrics{
+		LinesEmitted:    linesEmitted.Load(),
+		LinesSampledOut: linesSampledOut.Load(),
+		LateSets:        lateSets.Load(),
+		CappedSets:      cappedSets.Load(),
+		InvalidValues:   invalidValues.Load(),
+	}
+}
@@ -0,0 +1,44 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestReadMetrics(t *testing.T) {
+	r := testRegistry(t)
+	r.SetMaxBytes(64)
+	attrUser := RegisterWith(r, "user", WithValidator(func(s string) error {
+		if s == "" {
+			return errors.New("empty user")
+		}
+		return nil
+	}))
+	logger := slog.New(slog.DiscardHandler)
+	dropAll := WithSampler(SamplerFunc(func(context.Context, slog.Level, []slog.Attr) SampleDecision {
+		return SampleDecision{}
+	}))
+
+	before := ReadMetrics()
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "")                       // invalid
+	Set(ctx, attrUser, strings.Repeat("x", 100)) // too large
+	Set(ctx, attrUser, "usr_1")
+	NewEmitter(logger, WithTee(NewEmitter(logger, dropAll))).Emit(ctx, slog.LevelInfo)
+	Set(ctx, attrUser, "usr_2") // late
+	got := ReadMetrics()
+
+	want := Metrics{
+		LinesEmitted:    before.LinesEmitted + 1,
+		LinesSampledOut: before.LinesSampledOut + 1,
+		LateSets:        before.LateSets + 1,
+		CappedSets:      before.CappedSets + 1,
+		InvalidValues:   before.InvalidValues + 1,
+	}
+	if got != want {
+		t.Errorf("ReadMetrics = %+v, want %+v", got, want)
+	}
+}
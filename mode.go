@@ -0,0 +1,35 @@
+package canonlog
+
+import "sync/atomic"
+
+// Mode controls how canonlog reacts to instrumentation mistakes, such as
+// attributes whose values are too large to log. The mode applies to the
+// whole process; see [SetMode].
+type Mode int32
+
+const (
+	// ModeLenient tolerates mistakes silently. It is the default, and is
+	// intended for production.
+	ModeLenient Mode = iota
+
+	// ModeDebug records mistakes in the affected canonical log lines, or
+	// logs them to [slog.Default] where there is no line to record them
+	// in, so that they can be found in development and staging
+	// environments.
+	ModeDebug
+
+	// ModeStrict panics on mistakes, so that they fail tests.
+	ModeStrict
+)
+
+var mode atomic.Int32
+
+// SetMode sets the process-wide [Mode] and returns the previous one.
+func SetMode(m Mode) Mode {
+	return Mode(mode.Swap(int32(m)))
+}
+
+// currentMode returns the process-wide [Mode].
+func currentMode() Mode {
+	return Mode(mode.Load())
+}
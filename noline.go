@@ -0,0 +1,28 @@
+package canonlog
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// noLineSites holds the call sites already reported by noLine.
+var noLineSites sync.Map // string -> struct{}
+
+// noLine handles an attempt to set the attribute with the given key on a
+// context without a [Line], which usually means the code setting it runs
+// outside of any instrumented request, or was given the wrong context. It is
+// ignored in [ModeLenient]. In ModeDebug it is logged to [slog.Default] the
+// first time it happens at each call site, and in ModeStrict it panics.
+func noLine(key string) {
+	switch currentMode() {
+	case ModeDebug:
+		site := caller()
+		if _, reported := noLineSites.LoadOrStore(site, struct{}{}); !reported {
+			slog.Warn("canonlog: attribute set on a context without a line",
+				"key", key, "caller", site)
+		}
+	case ModeStrict:
+		panic(fmt.Sprintf("canonlog: attribute %q set at %s on a context without a line", key, caller()))
+	}
+}
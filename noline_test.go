@@ -0,0 +1,59 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNoLine(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+	ctx := context.Background()
+
+	t.Run("lenient", func(t *testing.T) {
+		withMode(t, ModeLenient)
+		Set(ctx, attrUser, "usr_123") // must not panic
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		withMode(t, ModeDebug)
+		var buf bytes.Buffer
+		prev := slog.Default()
+		slog.SetDefault(testLogger(&buf))
+		t.Cleanup(func() { slog.SetDefault(prev) })
+
+		for range 3 {
+			Set(ctx, attrQueries, 1)
+		}
+		Set(ctx, attrUser, "usr_123")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("logged %d lines, want one per call site:\n%s", len(lines), buf.String())
+		}
+		for i, key := range []string{"queries", "user"} {
+			if !strings.Contains(lines[i], "key="+key) || !strings.Contains(lines[i], "noline_test.go:") {
+				t.Errorf("line %d = %q, want key %q and caller", i, lines[i], key)
+			}
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		withMode(t, ModeStrict)
+		defer func() {
+			p := fmt.Sprint(recover())
+			if !strings.Contains(p, `"region"`) || !strings.Contains(p, "noline_test.go:") {
+				t.Errorf("recovered %q, want panic naming the key and caller", p)
+			}
+		}()
+		SetMany(ctx, map[string]any{"region": "eu"})
+	})
+}
@@ -0,0 +1,119 @@
+package canonlog
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Ordering determines the order in which a line's attributes are returned
+// by [Attrs] and emitted. See [Registry.SetOrdering].
+type Ordering int
+
+const (
+	// OrderInsertion orders attributes by when they were first set on the
+	// line. It is the default.
+	OrderInsertion Ordering = iota
+
+	// OrderRegistration orders attributes by when they were registered,
+	// giving the same order on every line regardless of which code path
+	// ran first. Attributes from different registries are ordered by
+	// their position within their own registry.
+	OrderRegistration
+
+	// OrderLexicographic orders attributes by key.
+	OrderLexicographic
+)
+
+// SetOrdering sets the order in which the attributes of lines associated
+// with r (see [WithRegistry]) are emitted. Regardless of the ordering,
+// attributes with a higher [WithPriority] come first.
+//
+// Attributes registered [WithGroup] are emitted together, at the position
+// of the first of them in the ordering.
+func (r *Registry) SetOrdering(o Ordering) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ordering = o
+}
+
+// Ordering returns the ordering set with [Registry.SetOrdering].
+func (r *Registry) Ordering() Ordering {
+	if r == nil {
+		return OrderInsertion
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ordering
+}
+
+// WithPriority sets the attribute's priority, which defaults to zero.
+// Attributes with a higher priority are emitted before those with a lower
+// one, whatever the registry's [Ordering], so that key columns such as
+// request IDs can be placed first.
+func WithPriority[T any](n int) Option[T] {
+	return func(a *Attr[T]) {
+		a.priority = n
+	}
+}
+
+// appendOrdered appends the values of l to dst in the order in which they
+// are emitted, and returns the extended slice. The shards of l must be
+// locked with lockAll.
+func (l *Line) appendOrdered(dst []entry) []entry {
+	ordering := l.registry.Ordering()
+	if ordering != OrderInsertion || l.hasPriority() {
+		start := len(dst)
+		for i := range l.shards {
+			dst = append(dst, l.shards[i].entries...)
+		}
+		slices.SortFunc(dst[start:], func(a, b entry) int {
+			if c := cmp.Compare(b.sv.priority, a.sv.priority); c != 0 {
+				return c
+			}
+			switch ordering {
+			case OrderRegistration:
+				if c := cmp.Compare(a.sv.seq, b.sv.seq); c != 0 {
+					return c
+				}
+			case OrderLexicographic:
+				return cmp.Compare(a.key, b.key)
+			}
+			return cmp.Compare(a.sv.inserted, b.sv.inserted)
+		})
+		return dst
+	}
+
+	// Each shard holds its entries in insertion order, so merging them
+	// gives the line's insertion order without sorting.
+	var pos [lineShards]int
+	for {
+		next := -1
+		for i := range l.shards {
+			s := &l.shards[i]
+			if pos[i] == len(s.entries) {
+				continue
+			}
+			if next < 0 || s.entries[pos[i]].sv.inserted < l.shards[next].entries[pos[next]].sv.inserted {
+				next = i
+			}
+		}
+		if next < 0 {
+			return dst
+		}
+		dst = append(dst, l.shards[next].entries[pos[next]])
+		pos[next]++
+	}
+}
+
+// hasPriority reports whether any value of l has a non-zero priority. The
+// shards of l must be locked with lockAll.
+func (l *Line) hasPriority() bool {
+	for i := range l.shards {
+		for _, e := range l.shards[i].entries {
+			if e.sv.priority != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
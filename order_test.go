@@ -0,0 +1,46 @@
+package canonlog
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestOrdering(t *testing.T) {
+	r := testRegistry(t)
+	attrC := RegisterWith[int](r, "c")
+	attrA := RegisterWith[int](r, "a")
+	attrB := RegisterWith[int](r, "b")
+	attrID := RegisterWith(r, "z_id", WithPriority[int](10))
+	attrDB := RegisterWith(r, "queries", WithGroup[int]("db"))
+
+	tests := []struct {
+		ordering Ordering
+		want     []string
+	}{
+		{OrderInsertion, []string{"z_id", "b", "db", "c", "a"}},
+		{OrderRegistration, []string{"z_id", "c", "a", "b", "db"}},
+		{OrderLexicographic, []string{"z_id", "a", "b", "c", "db"}},
+	}
+	for _, tt := range tests {
+		r.SetOrdering(tt.ordering)
+		if got := r.Ordering(); got != tt.ordering {
+			t.Errorf("Ordering() = %v, want %v", got, tt.ordering)
+		}
+
+		ctx := New(context.Background(), WithRegistry(r))
+		Set(ctx, attrB, 1)
+		Set(ctx, attrDB, 1)
+		Set(ctx, attrC, 1)
+		Set(ctx, attrID, 1)
+		Set(ctx, attrA, 1)
+
+		var got []string
+		for _, a := range Attrs(ctx) {
+			got = append(got, a.Key)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ordering %d: keys = %v, want %v", tt.ordering, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package canonlog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// linePool holds Lines released after being emitted, for reuse by
+// NewPooled.
+var linePool = sync.Pool{
+	New: func() any { return new(Line) },
+}
+
+// NewPooled is like [New], but takes the [Line] from a pool of lines that
+// have been emitted before, rather than allocating a new one, to reduce
+// allocations in servers handling many requests. The line returns to the
+// pool as soon as it is first emitted, by [Emit], [EmitOnReturn] or an
+// [Emitter], after which it will be reused by another request.
+//
+// The returned context, and any derived from it, must therefore not be
+// used with this package once the line has been emitted: setting
+// attributes may record them in an unrelated request's line, and [Attrs]
+// may return its attributes. Only use NewPooled where no goroutine started
+// while handling the request can outlive it, such as through
+// [Middleware] configured [WithPooledLines].
+func NewPooled(ctx context.Context, opts ...LineOption) context.Context {
+	l := linePool.Get().(*Line)
+	l.pooled = true
+	return newLine(ctx, l, opts)
+}
+
+// release resets l and returns it to the pool.
+func (l *Line) release() {
+	l.Reset()
+	linePool.Put(l)
+}
+
+// Reset clears l, so that it can be reused as if newly created by [New]
+// with no options, while keeping the memory allocated for its attributes.
+// Reset must not be called while l is in use by other goroutines.
+func (l *Line) Reset() {
+	for i := range l.shards {
+		s := &l.shards[i]
+		clear(s.entries) // drop references to values
+		s.entries = s.entries[:0]
+	}
+	l.inserted.Store(0)
+	l.accums.Clear()
+	l.callers.Clear()
+
+	l.registry = DefaultRegistry
+	l.enriched = nil
+	l.enrichers = nil
+	l.onEmit = nil
+	l.parent = nil
+	l.pooled = false
+	l.untrack()
+	l.now = nil
+	l.start, l.end = time.Time{}, time.Time{}
+	l.durationKey = ""
+	l.timestamps, l.timestampLayout = false, ""
+
+	l.frozen.Store(false)
+	l.lateSets.Store(0)
+	l.maxBytes = l.registry.MaxBytes()
+	l.bytes.Store(0)
+	l.cappedSets.Store(0)
+}
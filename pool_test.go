@@ -0,0 +1,111 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	Set(ctx, attrQueries, 3)
+	Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+
+	l := FromContext(ctx)
+	l.Reset()
+	if attrs := Attrs(ctx); attrs != nil {
+		t.Errorf("Attrs after Reset = %v, want nil", attrs)
+	}
+	if l.registry != DefaultRegistry {
+		t.Error("Reset did not restore the default registry")
+	}
+
+	Set(ctx, attrQueries, 1)
+	Set(ctx, attrUser, "usr_456")
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line queries=1 user=usr_456\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestNewPooled(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+
+	ctx := NewPooled(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	l := FromContext(ctx)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line user=usr_123\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+	if l.pooled || l.frozen.Load() || Attrs(ctx) != nil {
+		t.Error("line was not reset and released after being emitted")
+	}
+}
+
+func TestMiddleware_WithPooledLines(t *testing.T) {
+	var buf bytes.Buffer
+	handler := Middleware(testLogger(&buf), WithPooledLines())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+
+	for _, path := range []string{"/", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{"http_path=/ http_status=200", "http_path=/missing http_status=404"} {
+		if !bytes.Contains(lines[i], []byte(want)) {
+			t.Errorf("line %d = %q, want it to contain %q", i, lines[i], want)
+		}
+	}
+}
+
+func BenchmarkNewPooled(b *testing.B) {
+	r := NewRegistry()
+	attrUser := RegisterWith[string](r, "user")
+	attrStatus := RegisterWith[int](r, "status")
+	e := NewEmitter(slog.New(slog.DiscardHandler))
+
+	for _, tt := range []struct {
+		name string
+		new  func(context.Context, ...LineOption) context.Context
+	}{
+		{"New", New},
+		{"NewPooled", NewPooled},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				ctx := tt.new(context.Background())
+				Set(ctx, attrUser, "usr_123")
+				Set(ctx, attrStatus, 200)
+				e.Emit(ctx, slog.LevelInfo)
+			}
+		})
+	}
+}
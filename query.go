@@ -0,0 +1,65 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Has reports whether attr has been set on the [Line] attached to ctx, so
+// that middleware can skip work whose result is already recorded, such as
+// looking up a user that the handler already identified. It reports false if
+// the context does not have a Line. Static attributes (see [SetGlobalWith])
+// and those from enrichers are not counted as set.
+func Has[T any](ctx context.Context, attr Attr[T]) bool {
+	l := FromContext(ctx)
+	if l == nil {
+		return false
+	}
+	s := l.shard(attr.key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.get(attr.key)
+	return ok
+}
+
+// Len returns the number of attributes set on the [Line] attached to ctx, as
+// counted by [Has], so that middleware can enforce a budget of attributes
+// per request. It returns 0 if the context does not have a Line.
+func Len(ctx context.Context) int {
+	l := FromContext(ctx)
+	if l == nil {
+		return 0
+	}
+	l.lockAll()
+	defer l.unlockAll()
+	return l.len()
+}
+
+// AttrsMap returns the attributes returned by [Attrs] keyed by their keys,
+// for code that looks up several of them, such as sampling predicates and
+// tests. Members of groups, such as those of attributes registered
+// [WithGroup], are keyed by their key qualified by the group's, as in
+// "db.queries", as returned by [Attr.Key]. If the context does not have a
+// [Line], or the line has no attributes, nil is returned.
+func AttrsMap(ctx context.Context) map[string]slog.Value {
+	attrs := Attrs(ctx)
+	if attrs == nil {
+		return nil
+	}
+	m := make(map[string]slog.Value, len(attrs))
+	addToMap(m, "", attrs)
+	return m
+}
+
+// addToMap adds attrs to m, qualifying their keys with prefix and flattening
+// groups.
+func addToMap(m map[string]slog.Value, prefix string, attrs []slog.Attr) {
+	for _, a := range attrs {
+		key := prefix + a.Key
+		if a.Value.Kind() == slog.KindGroup {
+			addToMap(m, key+".", a.Value.Group())
+			continue
+		}
+		m[key] = a.Value
+	}
+}
@@ -0,0 +1,74 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestHasLen(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterCounterWith[int](r, "queries")
+	attrRegion := RegisterWith[string](r, "region")
+	SetGlobalWith(r, attrRegion, "eu")
+
+	if Has(context.Background(), attrUser) || Len(context.Background()) != 0 {
+		t.Error("Has or Len found attributes without a line")
+	}
+
+	ctx := New(context.Background(), WithRegistry(r),
+		WithLineEnricher(StaticEnricher(slog.String("host", "a"))))
+	if Has(ctx, attrUser) {
+		t.Error("Has(user) = true before setting it")
+	}
+	if got := Len(ctx); got != 0 {
+		t.Errorf("Len = %d, want 0", got)
+	}
+
+	Set(ctx, attrUser, "alice")
+	Add(ctx, attrQueries, 3)
+	Add(ctx, attrQueries, 4)
+	if !Has(ctx, attrUser) || !Has(ctx, attrQueries) {
+		t.Error("Has = false for a set attribute")
+	}
+	if Has(ctx, attrRegion) {
+		t.Error("Has(region) = true for a static attribute")
+	}
+	if got := Len(ctx); got != 2 {
+		t.Errorf("Len = %d, want 2", got)
+	}
+}
+
+func TestAttrsMap(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries", WithGroup[int]("db"))
+
+	if got := AttrsMap(context.Background()); got != nil {
+		t.Errorf("AttrsMap without a line = %v, want nil", got)
+	}
+
+	ctx := New(context.Background(), WithRegistry(r),
+		WithLineEnricher(StaticEnricher(slog.Group("host", slog.String("name", "a")))))
+	Set(ctx, attrUser, "alice")
+	Set(ctx, attrQueries, 3)
+
+	got := AttrsMap(ctx)
+	want := map[string]slog.Value{
+		"user":       slog.StringValue("alice"),
+		"db.queries": slog.IntValue(3),
+		"host.name":  slog.StringValue("a"),
+	}
+	if len(got) != len(want) {
+		t.Errorf("AttrsMap = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if !got[k].Equal(v) {
+			t.Errorf("AttrsMap[%q] = %v, want %v", k, got[k], v)
+		}
+	}
+	if _, ok := got[attrQueries.Key()]; !ok {
+		t.Errorf("AttrsMap has no value for Key() %q", attrQueries.Key())
+	}
+}
@@ -0,0 +1,125 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quota configures a sampler returned by [QuotaSampler].
+type Quota struct {
+	// Name identifies the quota in sampling decisions.
+	Name string
+
+	// Limit is the number of lines kept for each value of the key per
+	// Interval before lines are sampled.
+	Limit int
+
+	// Interval is the period over which Limit applies. It defaults to one
+	// minute.
+	Interval time.Duration
+
+	// Rate is the probability, between 0 and 1, with which lines are kept
+	// once the Limit for their value of the key is reached.
+	Rate float64
+
+	// MaxKeys bounds the number of values of the key that are tracked in
+	// each Interval, so that a key with unbounded values cannot exhaust
+	// memory. Lines with values beyond the first MaxKeys share a single
+	// quota. It defaults to 10000.
+	MaxKeys int
+}
+
+// QuotaSampler returns a [Sampler] that keeps up to q.Limit lines per
+// q.Interval for each value of the attribute with the given key, such as a
+// tenant ID, and beyond that keeps each line with probability q.Rate, so
+// that one noisy tenant cannot consume the whole budget of canonical lines
+// while small tenants keep full visibility:
+//
+//	canonlog.WithSampler(canonlog.QuotaSampler("tenant_id", canonlog.Quota{
+//		Name:  "tenant",
+//		Limit: 1000,
+//		Rate:  0.01,
+//	}))
+//
+// Lines without the attribute share a quota. The key of an attribute
+// registered [WithGroup] is qualified by the group's, as returned by
+// [Attr.Key]. Kept lines within the limit record a rate of 1.
+func QuotaSampler(key string, q Quota) Sampler {
+	if q.Interval <= 0 {
+		q.Interval = time.Minute
+	}
+	if q.MaxKeys <= 0 {
+		q.MaxKeys = 10000
+	}
+	s := &quotaSampler{key: key, quota: q}
+	return SamplerFunc(s.sample)
+}
+
+// quotaSampler is the state of a sampler returned by QuotaSampler. Counts
+// are kept in fixed windows of the quota's interval, and reset at the start
+// of each.
+type quotaSampler struct {
+	key   string
+	quota Quota
+
+	mu       sync.Mutex
+	window   time.Time      // start of the current window
+	counts   map[string]int // value of key -> lines seen in the window
+	overflow int            // lines with values beyond MaxKeys
+}
+
+func (s *quotaSampler) sample(_ context.Context, _ slog.Level, attrs []slog.Attr) SampleDecision {
+	var value string
+	if v, ok := findAttr(attrs, s.key); ok {
+		value = v.String()
+	}
+
+	if s.count(value) <= s.quota.Limit {
+		return SampleDecision{Keep: true, Rule: s.quota.Name, Rate: 1}
+	}
+	return SampleDecision{
+		Keep: sampleKeep(s.quota.Rate),
+		Rule: s.quota.Name,
+		Rate: s.quota.Rate,
+	}
+}
+
+// count counts a line with the given value of the key in the current
+// window, and returns the number of such lines in it so far.
+func (s *quotaSampler) count(value string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.counts == nil || now.Sub(s.window) >= s.quota.Interval {
+		s.window = now
+		s.counts = make(map[string]int)
+		s.overflow = 0
+	}
+	n, ok := s.counts[value]
+	if !ok && len(s.counts) >= s.quota.MaxKeys {
+		s.overflow++
+		return s.overflow
+	}
+	s.counts[value] = n + 1
+	return n + 1
+}
+
+// findAttr returns the value of the attribute with the given key in attrs,
+// resolving keys qualified by the names of groups, as in "db.queries".
+func findAttr(attrs []slog.Attr, key string) (slog.Value, bool) {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.Resolve(), true
+		}
+		if rest, ok := strings.CutPrefix(key, a.Key+"."); ok && a.Value.Kind() == slog.KindGroup {
+			if v, ok := findAttr(a.Value.Group(), rest); ok {
+				return v, true
+			}
+		}
+	}
+	return slog.Value{}, false
+}
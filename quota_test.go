@@ -0,0 +1,50 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestQuotaSampler(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := QuotaSampler("tenant.id", Quota{Name: "tenant", Limit: 2, Interval: time.Minute, Rate: 0, MaxKeys: 2})
+		sample := func(tenant string) SampleDecision {
+			var attrs []slog.Attr
+			if tenant != "" {
+				attrs = append(attrs, slog.Group("tenant", slog.String("id", tenant)))
+			}
+			return s.Sample(context.Background(), slog.LevelInfo, attrs)
+		}
+
+		kept := SampleDecision{Keep: true, Rule: "tenant", Rate: 1}
+		dropped := SampleDecision{Keep: false, Rule: "tenant", Rate: 0}
+		steps := []struct {
+			tenant string
+			want   SampleDecision
+		}{
+			{"noisy", kept},
+			{"noisy", kept},
+			{"noisy", dropped},
+			{"small", kept},
+			{"noisy", dropped},
+			{"small", kept},
+			// Beyond MaxKeys, the other values share a quota.
+			{"", kept},
+			{"other", kept},
+			{"third", dropped},
+		}
+		for i, step := range steps {
+			if got := sample(step.tenant); got != step.want {
+				t.Errorf("step %d (%q): decision = %+v, want %+v", i, step.tenant, got, step.want)
+			}
+		}
+
+		time.Sleep(time.Minute)
+		if got := sample("noisy"); got != kept {
+			t.Errorf("decision in the next interval = %+v, want %+v", got, kept)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// AddToRecord adds the attributes returned by [Attrs] to r, so that custom
+// handlers and bridges to other logging libraries can fold a canonical line
+// into a record they are building. The attributes are gathered in a reused
+// scratch slice rather than a new one for each call. If the context does not
+// have a [Line], r is left unchanged.
+func AddToRecord(ctx context.Context, r *slog.Record) {
+	if FromContext(ctx) == nil {
+		return
+	}
+	scratch := attrsPool.Get().(*[]slog.Attr)
+	attrs := AppendAttrs(ctx, *scratch)
+	r.AddAttrs(attrs...)
+	clear(attrs) // drop references to values
+	*scratch = attrs[:0]
+	attrsPool.Put(scratch)
+}
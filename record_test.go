@@ -0,0 +1,41 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAddToRecord(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries", WithGroup[int]("db"))
+
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "request", 0)
+	rec.AddAttrs(slog.String("component", "api"))
+	AddToRecord(context.Background(), &rec)
+	if got := rec.NumAttrs(); got != 1 {
+		t.Errorf("NumAttrs without a line = %d, want 1", got)
+	}
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "alice")
+	Set(ctx, attrQueries, 3)
+	AddToRecord(ctx, &rec)
+
+	var got []slog.Attr
+	rec.Attrs(func(a slog.Attr) bool {
+		got = append(got, a)
+		return true
+	})
+	want := []slog.Attr{
+		slog.String("component", "api"),
+		slog.String("user", "alice"),
+		slog.Group("db", slog.Int("queries", 3)),
+	}
+	if !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("record attrs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,226 @@
+package canonlog
+
+import (
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+	"weak"
+)
+
+// A Recorder keeps the most recently emitted lines of the registries it is
+// set for with [Registry.SetRecorder], along with the lines still in flight,
+// in memory, so that crash handlers and debugging tools can show what the
+// process was doing, even if its logs never made it out. A Recorder is safe
+// for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	emitted []RecordedLine // ring buffer, oldest at next once full
+	next    int
+	full    bool
+
+	// live holds the lines being recorded that have not been emitted yet,
+	// by an ID unique within the Recorder. Lines that are never emitted
+	// are removed once garbage collected.
+	live   map[uint64]liveLine
+	lastID uint64
+}
+
+// RecordedLine is a line returned by [Recorder.Lines].
+type RecordedLine struct {
+	// Time is when the line was emitted, or, if it is in flight, created.
+	Time time.Time
+
+	// Level is the level at which the line was emitted, and zero if it is
+	// in flight.
+	Level slog.Level
+
+	// Attrs holds the line's attributes as returned by [Attrs], followed
+	// by any contributed by the [Emitter] that emitted it, such as the
+	// outcome, but not those of its enrichers.
+	Attrs []slog.Attr
+
+	// InFlight is set for lines that have not been emitted yet, whose
+	// attributes are those set when [Recorder.Lines] was called.
+	InFlight bool
+
+	// visibility holds the classes of the attributes that are not
+	// public, as returned by Line.visibilities.
+	visibility map[string]Visibility
+}
+
+type liveLine struct {
+	created time.Time
+	line    weak.Pointer[Line]
+}
+
+// NewRecorder creates a [Recorder] that keeps the last n emitted lines. It
+// panics if n is not positive.
+func NewRecorder(n int) *Recorder {
+	if n <= 0 {
+		panic("canonlog: recorder size must be positive")
+	}
+	return &Recorder{
+		emitted: make([]RecordedLine, 0, n),
+		live:    make(map[uint64]liveLine),
+	}
+}
+
+// Lines returns the lines kept by rec, from the oldest emitted to the most
+// recently emitted, followed by those still in flight, from the oldest.
+func (rec *Recorder) Lines() []RecordedLine {
+	rec.mu.Lock()
+	lines := make([]RecordedLine, 0, len(rec.emitted)+len(rec.live))
+	lines = append(lines, rec.emitted[rec.next:]...)
+	lines = append(lines, rec.emitted[:rec.next]...)
+	rec.mu.Unlock()
+
+	// Read in-flight lines without holding rec.mu, which their
+	// attributes' converters could otherwise delay emitting other lines
+	// on.
+	for _, ll := range rec.inFlight() {
+		lines = append(lines, RecordedLine{
+			Time:       ll.created,
+			Attrs:      ll.l.Attrs(),
+			InFlight:   true,
+			visibility: ll.l.visibilities(),
+		})
+	}
+	return lines
+}
+
+// inFlight returns the lines of rec that have not been emitted, from the
+// oldest, with strong references.
+func (rec *Recorder) inFlight() []inFlightLine {
+	rec.mu.Lock()
+	live := make([]liveLine, 0, len(rec.live))
+	for _, ll := range rec.live {
+		live = append(live, ll)
+	}
+	rec.mu.Unlock()
+
+	slices.SortFunc(live, func(a, b liveLine) int { return a.created.Compare(b.created) })
+	lines := make([]inFlightLine, 0, len(live))
+	for _, ll := range live {
+		if l := ll.line.Value(); l != nil && !l.frozen.Load() {
+			lines = append(lines, inFlightLine{ll.created, l})
+		}
+	}
+	return lines
+}
+
+type inFlightLine struct {
+	created time.Time
+	l       *Line
+}
+
+// track records l as in flight, until it is emitted or garbage collected.
+func (rec *Recorder) track(l *Line) {
+	rec.mu.Lock()
+	rec.lastID++
+	id := rec.lastID
+	rec.live[id] = liveLine{created: time.Now(), line: weak.Make(l)}
+	rec.mu.Unlock()
+
+	l.recorder, l.recorderID = rec, id
+	if !l.pooled {
+		// Pooled lines are reused rather than collected, and are
+		// released only once emitted.
+		runtime.AddCleanup(l, rec.forget, id)
+	}
+}
+
+// forget stops recording the line with the given ID as in flight.
+func (rec *Recorder) forget(id uint64) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	delete(rec.live, id)
+}
+
+// untrack stops recording l, if it is being recorded, without recording its
+// emission.
+func (l *Line) untrack() {
+	if l.recorder != nil {
+		l.recorder.forget(l.recorderID)
+		l.recorder, l.recorderID = nil, 0
+	}
+}
+
+// record records the emission of the line with the given ID.
+func (rec *Recorder) record(id uint64, level slog.Level, attrs []slog.Attr, visibility map[string]Visibility) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	delete(rec.live, id)
+
+	rl := RecordedLine{Time: time.Now(), Level: level, Attrs: attrs, visibility: visibility}
+	if !rec.full {
+		rec.emitted = append(rec.emitted, rl)
+		rec.full = len(rec.emitted) == cap(rec.emitted)
+		return
+	}
+	rec.emitted[rec.next] = rl
+	rec.next = (rec.next + 1) % len(rec.emitted)
+}
+
+// record records the emission of l, which must have just been frozen, if it
+// is being recorded.
+func (l *Line) record(level slog.Level, extra []slog.Attr) {
+	if l.recorder != nil {
+		l.recorder.record(l.recorderID, level, append(l.Attrs(), extra...), l.visibilities())
+	}
+}
+
+// visibilities returns the visibility classes of the attributes of l that
+// are not public, by key, including those they are aliased as, or nil if
+// all are public. Members of groups registered [WithGroup] are listed
+// under their dotted keys.
+func (l *Line) visibilities() map[string]Visibility {
+	var buf [16]entry
+	l.lockAll()
+	entries := l.appendOrdered(buf[:0])
+	l.unlockAll()
+
+	var m map[string]Visibility
+	add := func(key string, aliases []string, v Visibility) {
+		if v == VisibilityPublic {
+			return
+		}
+		if m == nil {
+			m = make(map[string]Visibility)
+		}
+		m[key] = v
+		for _, alias := range aliases {
+			m[alias] = v
+		}
+	}
+	for _, e := range entries {
+		add(e.key, e.sv.aliases, e.sv.visibility)
+	}
+	for _, st := range l.registry.statics() {
+		if !hasKey(entries, st.key) {
+			add(st.key, st.aliases, st.visibility)
+		}
+	}
+	return m
+}
+
+// SetRecorder makes rec record the lines associated with r (see
+// [WithRegistry]) created from now on, or stops recording them if rec is
+// nil. Recording a line costs reading its attributes a second time when it
+// is emitted.
+func (r *Registry) SetRecorder(rec *Recorder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorder = rec
+}
+
+// getRecorder returns the recorder set with [Registry.SetRecorder].
+func (r *Registry) getRecorder() *Recorder {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recorder
+}
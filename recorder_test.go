@@ -0,0 +1,87 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := testRegistry(t)
+	attrN := RegisterWith[int](r, "n")
+	rec := NewRecorder(2)
+	r.SetRecorder(rec)
+
+	for i := range 3 {
+		ctx := New(context.Background(), WithRegistry(r))
+		Set(ctx, attrN, i)
+		Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelWarn)
+	}
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrN, 3)
+	func() {
+		defer EmitOnReturn(NewPooled(context.Background(), WithRegistry(r)), slog.New(slog.DiscardHandler), nil)()
+	}()
+
+	type line struct {
+		level    slog.Level
+		attrs    []slog.Attr
+		inFlight bool
+	}
+	want := []line{
+		{slog.LevelWarn, []slog.Attr{slog.Int("n", 2)}, false},
+		{slog.LevelInfo, []slog.Attr{slog.String("outcome", "success")}, false},
+		{0, []slog.Attr{slog.Int("n", 3)}, true},
+	}
+	got := rec.Lines()
+	if len(got) != len(want) {
+		t.Fatalf("Lines returned %d lines, want %d: %v", len(got), len(want), got)
+	}
+	for i, rl := range got {
+		w := want[i]
+		if rl.Level != w.level || rl.InFlight != w.inFlight || !slices.EqualFunc(rl.Attrs, w.attrs, slog.Attr.Equal) {
+			t.Errorf("line %d = %+v, want %+v", i, rl, w)
+		}
+		if rl.Time.IsZero() {
+			t.Errorf("line %d has no time", i)
+		}
+	}
+
+	runtime.KeepAlive(ctx)
+
+	// Lines that are never emitted are forgotten once collected.
+	for range 5 {
+		runtime.GC()
+	}
+	if got := rec.Lines(); len(got) != 2 {
+		t.Errorf("Lines returned %d lines after the in-flight line was collected, want 2", len(got))
+	}
+}
+
+func TestRecorder_Untracked(t *testing.T) {
+	r := testRegistry(t)
+	rec := NewRecorder(2)
+	r.SetRecorder(rec)
+
+	// Forked lines are joined rather than emitted.
+	ctx := New(context.Background(), WithRegistry(r))
+	child := Fork(ctx)
+	g, _ := Group(ctx)
+	g.Go("fetch", func(context.Context) error { return nil })
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	Join(ctx, child)
+
+	// Pooled lines reset without being emitted are no longer in flight.
+	pooled := NewPooled(context.Background(), WithRegistry(r))
+	FromContext(pooled).release()
+
+	got := rec.Lines()
+	if len(got) != 1 || !got[0].InFlight {
+		t.Errorf("Lines = %+v, want only the line created with New", got)
+	}
+	runtime.KeepAlive(ctx)
+}
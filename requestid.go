@@ -0,0 +1,61 @@
+package canonlog
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+)
+
+// RequestIDHeader is the header from which [WithRequestID] reads incoming
+// request IDs, and in which it returns them in responses.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen is the longest incoming request ID that is accepted.
+const maxRequestIDLen = 128
+
+// WithRequestID makes the middleware give every request an ID, recorded
+// under the "request_id" key and returned to the client in the
+// [RequestIDHeader] response header. The ID is taken from the request's
+// RequestIDHeader header if it has a valid one, as when set by a load
+// balancer or upstream service, and otherwise generated randomly. Handlers
+// can get the ID with [RequestID].
+//
+// An incoming ID is valid if it is at most 128 characters long and consists
+// of ASCII letters, digits, and the characters "-", "_", ".", ":" and "=",
+// so that clients cannot inject arbitrary text into log lines.
+func WithRequestID() MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.onRequest = append(cfg.onRequest, func(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = rand.Text()
+			}
+			Set(ctx, attrRequestID, id)
+			w.Header().Set(RequestIDHeader, id)
+			return ctx
+		})
+	}
+}
+
+// RequestID returns the ID given to the request by the middleware with
+// [WithRequestID], or the empty string if the context does not have one.
+func RequestID(ctx context.Context) string {
+	id, _ := lookup(FromContext(ctx), attrRequestID)
+	return id
+}
+
+// validRequestID reports whether id is acceptable as an incoming request ID.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
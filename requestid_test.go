@@ -0,0 +1,61 @@
+package canonlog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		want     string // empty if a new ID should be generated
+	}{
+		{name: "generated"},
+		{name: "propagated", incoming: "req-8f14e45f", want: "req-8f14e45f"},
+		{name: "invalid", incoming: "bad id\nlevel=ERROR"},
+		{name: "too long", incoming: strings.Repeat("a", maxRequestIDLen+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				buf     bytes.Buffer
+				handled string
+			)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = RequestID(r.Context())
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			Middleware(testLogger(&buf), WithRequestID())(handler).ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tt.want != "" && id != tt.want {
+				t.Errorf("response request ID = %q, want %q", id, tt.want)
+			}
+			if !validRequestID(id) || id == tt.incoming && tt.want == "" {
+				t.Errorf("response request ID = %q, want a new valid ID", id)
+			}
+			if handled != id {
+				t.Errorf("RequestID in handler = %q, want %q", handled, id)
+			}
+			if !strings.Contains(buf.String(), " request_id="+id+" ") {
+				t.Errorf("log output = %q, want request_id=%s", buf.String(), id)
+			}
+		})
+	}
+}
+
+func TestRequestID_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if id := RequestID(req.Context()); id != "" {
+		t.Errorf("RequestID = %q, want empty", id)
+	}
+}
@@ -0,0 +1,127 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// retryRegistry holds the attribute recorded by [RetryRecorder], kept out of
+// [DefaultRegistry] for the same reason as those of [Middleware].
+var retryRegistry = NewRegistry()
+
+// attrRetries records retried operations by name.
+var attrRetries = RegisterWith(retryRegistry, "retries",
+	WithMerge(MergeMap(mergeRetryStats)),
+	WithValue(retriesValue),
+)
+
+// retryStats describes the retries of a named operation.
+type retryStats struct {
+	attempts int
+	backoff  time.Duration
+	outcome  string
+}
+
+// mergeRetryStats sums attempts and backoff, and keeps the latest outcome.
+func mergeRetryStats(old, new retryStats) retryStats {
+	outcome := old.outcome
+	if new.outcome != "" {
+		outcome = new.outcome
+	}
+	return retryStats{
+		attempts: old.attempts + new.attempts,
+		backoff:  old.backoff + new.backoff,
+		outcome:  outcome,
+	}
+}
+
+// retriesValue converts retried operations to a group with a member per
+// operation, sorted by name.
+func retriesValue(m map[string]retryStats) slog.Value {
+	ops := make([]slog.Attr, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		st := m[name]
+		attrs := []slog.Attr{
+			slog.Int("attempts", st.attempts),
+			slog.Duration("backoff", st.backoff),
+		}
+		if st.outcome != "" {
+			attrs = append(attrs, slog.String("outcome", st.outcome))
+		}
+		ops = append(ops, slog.Attr{Key: name, Value: slog.GroupValue(attrs...)})
+	}
+	return slog.GroupValue(ops...)
+}
+
+// A RetryRecorder records the attempts, time spent backing off, and final
+// outcome of a retried operation in a canonical log line. It is created by
+// [Retryer].
+type RetryRecorder struct {
+	ctx  context.Context
+	name string
+}
+
+// Retryer returns a RetryRecorder for the operation with the given name,
+// recording into the [Line] attached to ctx. The operation's retries are
+// emitted in a "retries" group, with a member per operation name containing
+// its number of attempts, the total time spent backing off between them,
+// and the outcome of the last attempt, as in
+// retries.charge.attempts=3 retries.charge.backoff=300ms
+// retries.charge.outcome=success. Recording the same operation name more
+// than once in a line adds up the attempts and backoff time.
+//
+//	retry := canonlog.Retryer(ctx, "charge")
+//	for i := 0; ; i++ {
+//		retry.Attempt()
+//		err = charge(ctx)
+//		if err == nil || i == maxAttempts-1 {
+//			break
+//		}
+//		retry.Backoff(delay)
+//		time.Sleep(delay)
+//	}
+//	retry.Done(err)
+//
+// If the context does not have a Line, the RetryRecorder does nothing.
+func Retryer(ctx context.Context, name string) *RetryRecorder {
+	return &RetryRecorder{ctx: ctx, name: name}
+}
+
+// Attempt records that an attempt of the operation is being made.
+func (r *RetryRecorder) Attempt() {
+	SetKey(r.ctx, attrRetries, r.name, retryStats{attempts: 1})
+}
+
+// Backoff records that d is being spent waiting before the next attempt.
+func (r *RetryRecorder) Backoff(d time.Duration) {
+	SetKey(r.ctx, attrRetries, r.name, retryStats{backoff: d})
+}
+
+// Notify records that next is being spent waiting before the next attempt,
+// like [RetryRecorder.Backoff]. Its signature matches the notification hooks
+// of retry libraries such as github.com/cenkalti/backoff, with attempts
+// recorded by the operation itself:
+//
+//	retry := canonlog.Retryer(ctx, "charge")
+//	err := backoff.RetryNotify(func() error {
+//		retry.Attempt()
+//		return charge(ctx)
+//	}, b, retry.Notify)
+//	retry.Done(err)
+func (r *RetryRecorder) Notify(err error, next time.Duration) {
+	r.Backoff(next)
+}
+
+// Done records the outcome of the operation, with err being the error
+// returned by its last attempt: "success" if err is nil, and "error"
+// otherwise.
+func (r *RetryRecorder) Done(err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	SetKey(r.ctx, attrRetries, r.name, retryStats{outcome: outcome})
+}
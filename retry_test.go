@@ -0,0 +1,45 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRetryer(t *testing.T) {
+	ctx := New(context.Background())
+
+	charge := Retryer(ctx, "charge")
+	for i := range 3 {
+		charge.Attempt()
+		if i < 2 {
+			charge.Backoff(100 * time.Millisecond * time.Duration(i+1))
+		}
+	}
+	charge.Done(nil)
+
+	lookup := Retryer(ctx, "lookup")
+	lookup.Attempt()
+	lookup.Notify(errors.New("timeout"), 50*time.Millisecond)
+	lookup.Attempt()
+	lookup.Done(errors.New("timeout"))
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line" +
+		" retries.charge.attempts=3 retries.charge.backoff=300ms retries.charge.outcome=success" +
+		" retries.lookup.attempts=2 retries.lookup.backoff=50ms retries.lookup.outcome=error\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRetryer_WithoutLine(t *testing.T) {
+	r := Retryer(context.Background(), "charge")
+	r.Attempt()
+	r.Done(nil) // must not panic
+}
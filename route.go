@@ -0,0 +1,100 @@
+package canonlog
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Route configures how the middleware returned by [Middleware] handles the
+// requests matching a pattern given to [WithRoute].
+type Route struct {
+	// Skip makes the middleware pass matching requests straight to the
+	// wrapped handler, without creating or emitting a Line, as for health
+	// checks.
+	Skip bool
+
+	// Sampler, if not nil, decides whether the lines of matching requests
+	// are emitted, as described for [WithSampler], in place of any sampler
+	// given to [WithEmitterOptions].
+	Sampler Sampler
+
+	// Attrs are set on the Line of each matching request, as if by
+	// [SetMany], before the wrapped handler is called, such as the team
+	// owning the route.
+	Attrs map[string]any
+}
+
+// WithRoute makes the middleware handle the requests matching pattern as
+// configured by route:
+//
+//	canonlog.Middleware(logger,
+//		canonlog.WithRoute("GET /healthz", canonlog.Route{Skip: true}),
+//		canonlog.WithRoute("/api/payments/", canonlog.Route{
+//			Attrs: map[string]any{"team": "payments"},
+//		}),
+//		canonlog.WithRoute("GET /api/search", canonlog.Route{
+//			Sampler: canonlog.RuleSampler(canonlog.SampleRule{Name: "search", Rate: 0.1}),
+//		}),
+//	)
+//
+// Patterns have the syntax of [http.ServeMux] patterns, and a request
+// matching several of them is handled as configured for the most specific,
+// as ServeMux would choose. Requests that match no pattern are handled as
+// usual. [Middleware] panics if pattern is invalid or conflicts with another
+// pattern given to it.
+func WithRoute(pattern string, route Route) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		if cfg.routes == nil {
+			cfg.routes = http.NewServeMux()
+		}
+		rh := &routeHandler{Route: route}
+		cfg.routes.Handle(pattern, rh)
+		cfg.routeList = append(cfg.routeList, rh)
+	}
+}
+
+// WithRoutePattern makes the middleware record the route pattern returned by
+// fn, rather than [http.Request.Pattern], under the "http_route" key, for
+// routers other than [http.ServeMux]. fn is called with the request given to
+// the wrapped handler after it returns, and returns "" if the request was not
+// routed. See the canonchi package for routers built with chi.
+func WithRoutePattern(fn func(r *http.Request) string) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.routePattern = fn
+	}
+}
+
+// routeHandler holds a [Route] in the ServeMux that matches requests to
+// routes. It is never used to serve requests.
+type routeHandler struct {
+	Route
+	emitter *Emitter // with the route's sampler, created by Middleware
+}
+
+func (*routeHandler) ServeHTTP(http.ResponseWriter, *http.Request) {
+	panic("canonlog: route handler called")
+}
+
+// initRoutes creates the emitters of the configured routes, and the one used
+// for requests matching no route, which log to logger.
+func (cfg *middlewareConfig) initRoutes(logger *slog.Logger) {
+	cfg.defaultRoute = &routeHandler{emitter: NewEmitter(logger, cfg.emitterOpts...)}
+	for _, rh := range cfg.routeList {
+		rh.emitter = cfg.defaultRoute.emitter
+		if rh.Sampler != nil {
+			opts := append(cfg.emitterOpts[:len(cfg.emitterOpts):len(cfg.emitterOpts)], WithSampler(rh.Sampler))
+			rh.emitter = NewEmitter(logger, opts...)
+		}
+	}
+}
+
+// route returns the configuration of the route matching r.
+func (cfg *middlewareConfig) route(r *http.Request) *routeHandler {
+	if cfg.routes != nil {
+		h, _ := cfg.routes.Handler(r)
+		if rh, ok := h.(*routeHandler); ok {
+			return rh
+		}
+	}
+	return cfg.defaultRoute
+}
@@ -0,0 +1,70 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRoute(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith[string](r, "team") // set by the routes' Attrs
+
+	var buf bytes.Buffer
+	var sawLine bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawLine = FromContext(r.Context()) != nil
+	})
+	dropAll := SamplerFunc(func(context.Context, slog.Level, []slog.Attr) SampleDecision {
+		return SampleDecision{}
+	})
+	h := Middleware(testLogger(&buf),
+		WithLineOptions(WithRegistry(r)),
+		WithRoute("GET /healthz", Route{Skip: true}),
+		WithRoute("/api/payments/", Route{Attrs: map[string]any{"team": "payments", "tier": 1}}),
+		WithRoute("/api/payments/refunds", Route{Attrs: map[string]any{"team": "refunds"}}),
+		WithRoute("GET /api/search", Route{Sampler: dropAll}),
+	)(handler)
+
+	tests := []struct {
+		method, path string
+		want         string // log output, without the duration and sizes
+		wantLine     bool
+	}{
+		{"GET", "/healthz", "", false},
+		{"POST", "/healthz", "level=INFO msg=canonical-log-line http_method=POST http_path=/healthz http_status=200", true},
+		{"POST", "/api/payments/charge", "level=INFO msg=canonical-log-line http_method=POST http_path=/api/payments/charge team=payments tier=1 http_status=200", true},
+		{"POST", "/api/payments/refunds", "level=INFO msg=canonical-log-line http_method=POST http_path=/api/payments/refunds team=refunds http_status=200", true},
+		{"GET", "/api/search", "", true},
+		{"GET", "/api/payments", "level=INFO msg=canonical-log-line http_method=GET http_path=/api/payments http_status=200", true},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		sawLine = false
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+		got, _, _ := strings.Cut(buf.String(), " duration=")
+		if got != tt.want {
+			t.Errorf("%s %s: log output:\ngot:  %q\nwant: %q", tt.method, tt.path, got, tt.want)
+		}
+		if sawLine != tt.wantLine {
+			t.Errorf("%s %s: handler saw line = %v, want %v", tt.method, tt.path, sawLine, tt.wantLine)
+		}
+	}
+}
+
+func TestWithRoute_Conflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Middleware did not panic with conflicting routes")
+		}
+	}()
+	Middleware(nil,
+		WithRoute("/a/{x}", Route{Skip: true}),
+		WithRoute("/a/{y}", Route{Skip: true}),
+	)
+}
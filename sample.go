@@ -0,0 +1,131 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+)
+
+// Keys of the attributes added to kept lines by [WithSampleAttrs].
+const (
+	SampleRuleKey = "sample_rule"
+	SampleRateKey = "sample_rate"
+)
+
+// Sampler decides whether a canonical log line is emitted. Sample is called
+// with the level and the complete set of attributes the line would be
+// emitted with, which it must not modify or retain.
+type Sampler interface {
+	Sample(ctx context.Context, level slog.Level, attrs []slog.Attr) SampleDecision
+}
+
+// SamplerFunc is an adapter to allow the use of ordinary functions as a
+// [Sampler].
+type SamplerFunc func(ctx context.Context, level slog.Level, attrs []slog.Attr) SampleDecision
+
+// Sample calls f(ctx, level, attrs).
+func (f SamplerFunc) Sample(ctx context.Context, level slog.Level, attrs []slog.Attr) SampleDecision {
+	return f(ctx, level, attrs)
+}
+
+// SampleDecision is the outcome of sampling a canonical log line.
+type SampleDecision struct {
+	// Keep reports whether the line is emitted.
+	Keep bool
+
+	// Rule names the sampling rule that made the decision, if any.
+	Rule string
+
+	// Rate is the probability with which lines matching Rule are kept.
+	// Analytics consumers can weight each kept line by 1/Rate.
+	Rate float64
+}
+
+// attrs returns the attributes recording d on a kept line.
+func (d SampleDecision) attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String(SampleRuleKey, d.Rule),
+		slog.Float64(SampleRateKey, d.Rate),
+	}
+}
+
+// SampleRule is a rule used by [RuleSampler].
+type SampleRule struct {
+	// Name identifies the rule in sampling decisions.
+	Name string
+
+	// Match reports whether the rule applies to a line. A nil Match
+	// applies to every line.
+	Match func(level slog.Level, attrs []slog.Attr) bool
+
+	// Rate is the probability, between 0 and 1, with which lines matching
+	// the rule are kept.
+	Rate float64
+}
+
+// RuleSampler returns a [Sampler] that keeps each line with the rate of the
+// first rule that matches it. Lines that match no rule are always kept, with
+// an empty rule name.
+//
+// Example, keeping every error but only 10% of other lines:
+//
+//	canonlog.RuleSampler(
+//		canonlog.SampleRule{
+//			Name:  "errors",
+//			Match: func(level slog.Level, _ []slog.Attr) bool { return level >= slog.LevelError },
+//			Rate:  1,
+//		},
+//		canonlog.SampleRule{Name: "default", Rate: 0.1},
+//	)
+func RuleSampler(rules ...SampleRule) Sampler {
+	return SamplerFunc(func(ctx context.Context, level slog.Level, attrs []slog.Attr) SampleDecision {
+		for _, rule := range rules {
+			if rule.Match == nil || rule.Match(level, attrs) {
+				return SampleDecision{
+					Keep: sampleKeep(rule.Rate),
+					Rule: rule.Name,
+					Rate: rule.Rate,
+				}
+			}
+		}
+		return SampleDecision{Keep: true, Rate: 1}
+	})
+}
+
+// sampleKeep randomly returns true with the given probability.
+func sampleKeep(rate float64) bool {
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	default:
+		return rand.Float64() < rate
+	}
+}
+
+// WithSampler makes the [Emitter] consult s before emitting each line, and
+// drop the line if s decides not to keep it.
+func WithSampler(s Sampler) EmitterOption {
+	return func(e *Emitter) {
+		e.sampler = s
+	}
+}
+
+// WithSampleHook makes the [Emitter] call fn with every sampling decision,
+// whether the line is kept or dropped, so that decisions can be recorded or
+// audited elsewhere. It has no effect without [WithSampler].
+func WithSampleHook(fn func(ctx context.Context, d SampleDecision)) EmitterOption {
+	return func(e *Emitter) {
+		e.onSample = fn
+	}
+}
+
+// WithSampleAttrs makes the [Emitter] record the sampling decision on every
+// kept line, as the attributes [SampleRuleKey] and [SampleRateKey]. It has no
+// effect without [WithSampler].
+func WithSampleAttrs() EmitterOption {
+	return func(e *Emitter) {
+		e.sampleAttrs = true
+	}
+}
@@ -0,0 +1,113 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestRuleSampler(t *testing.T) {
+	sampler := RuleSampler(
+		SampleRule{
+			Name:  "errors",
+			Match: func(level slog.Level, _ []slog.Attr) bool { return level >= slog.LevelError },
+			Rate:  1,
+		},
+		SampleRule{
+			Name: "health",
+			Match: func(_ slog.Level, attrs []slog.Attr) bool {
+				for _, a := range attrs {
+					if a.Key == "path" && a.Value.String() == "/healthz" {
+						return true
+					}
+				}
+				return false
+			},
+			Rate: 0,
+		},
+	)
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		path  string
+		want  SampleDecision
+	}{
+		{"error", slog.LevelError, "/healthz", SampleDecision{Keep: true, Rule: "errors", Rate: 1}},
+		{"health", slog.LevelInfo, "/healthz", SampleDecision{Keep: false, Rule: "health", Rate: 0}},
+		{"unmatched", slog.LevelInfo, "/users", SampleDecision{Keep: true, Rate: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := []slog.Attr{slog.String("path", tt.path)}
+			got := sampler.Sample(context.Background(), tt.level, attrs)
+			if got != tt.want {
+				t.Errorf("Sample() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmitter_Sampling(t *testing.T) {
+	r := testRegistry(t)
+	attrPath := RegisterWith[string](r, "path")
+
+	var (
+		buf       bytes.Buffer
+		decisions []SampleDecision
+	)
+	e := NewEmitter(testLogger(&buf),
+		WithSampler(RuleSampler(
+			SampleRule{
+				Name: "drop_health",
+				Match: func(_ slog.Level, attrs []slog.Attr) bool {
+					return len(attrs) > 0 && attrs[0].Value.String() == "/healthz"
+				},
+				Rate: 0,
+			},
+			SampleRule{Name: "default", Rate: 1},
+		)),
+		WithSampleHook(func(ctx context.Context, d SampleDecision) {
+			decisions = append(decisions, d)
+		}),
+		WithSampleAttrs(),
+	)
+
+	for _, path := range []string{"/healthz", "/users"} {
+		ctx := New(context.Background())
+		Set(ctx, attrPath, path)
+		e.Emit(ctx, slog.LevelInfo)
+	}
+
+	want := "level=INFO msg=canonical-log-line path=/users sample_rule=default sample_rate=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	wantDecisions := []SampleDecision{
+		{Keep: false, Rule: "drop_health", Rate: 0},
+		{Keep: true, Rule: "default", Rate: 1},
+	}
+	if len(decisions) != len(wantDecisions) {
+		t.Fatalf("hook called %d times, want %d", len(decisions), len(wantDecisions))
+	}
+	for i := range wantDecisions {
+		if decisions[i] != wantDecisions[i] {
+			t.Errorf("decisions[%d] = %+v, want %+v", i, decisions[i], wantDecisions[i])
+		}
+	}
+}
+
+func TestSampleKeep(t *testing.T) {
+	const n = 10000
+	var kept int
+	for range n {
+		if sampleKeep(0.25) {
+			kept++
+		}
+	}
+	if kept < n/5 || kept > n*3/10 {
+		t.Errorf("sampleKeep(0.25) kept %d of %d lines", kept, n)
+	}
+}
@@ -0,0 +1,80 @@
+package canonlog
+
+import (
+	"cmp"
+	"log/slog"
+	"math"
+	"slices"
+)
+
+// SanitizedKey is the key of the attribute that is set to true on lines
+// with values that were replaced because downstream consumers could not
+// handle them: values outside the range of an attribute registered
+// [WithClamp], NaN or infinite floats, and negative durations.
+const SanitizedKey = "sanitized"
+
+// WithClamp makes the attribute emit values below lo as lo, and values above
+// hi as hi, marking the line with [SanitizedKey], so that bad arithmetic
+// upstream cannot produce absurd values:
+//
+//	var AttrRatio = canonlog.Register("cache_hit_ratio", canonlog.WithClamp(0.0, 1.0))
+//
+// Values are stored as set, and clamped whenever the line's attributes are
+// read, before they are converted by any [WithValue] function.
+func WithClamp[T cmp.Ordered](lo, hi T) Option[T] {
+	return func(a *Attr[T]) {
+		a.clamp = func(v any) (any, bool) {
+			t, ok := v.(T)
+			switch {
+			case !ok:
+				return v, false
+			case t < lo:
+				return lo, true
+			case t > hi:
+				return hi, true
+			}
+			return v, false
+		}
+	}
+}
+
+// sanitize replaces v, a resolved value, if downstream consumers, such as
+// JSON pipelines, could not handle it: NaN floats become 0, infinite ones
+// the largest finite float of the same sign, and negative durations 0.
+// Members of groups are resolved and sanitized in turn. It reports whether
+// any value was replaced.
+func sanitize(v slog.Value) (slog.Value, bool) {
+	switch v.Kind() {
+	case slog.KindGroup:
+		members := v.Group()
+		var replaced []slog.Attr // copy of members, once one is replaced
+		for i, a := range members {
+			mv, ok := sanitize(a.Value.Resolve())
+			if !ok {
+				continue
+			}
+			if replaced == nil {
+				replaced = slices.Clone(members)
+			}
+			replaced[i].Value = mv
+		}
+		if replaced != nil {
+			return slog.GroupValue(replaced...), true
+		}
+	case slog.KindFloat64:
+		f := v.Float64()
+		switch {
+		case math.IsNaN(f):
+			return slog.Float64Value(0), true
+		case math.IsInf(f, 1):
+			return slog.Float64Value(math.MaxFloat64), true
+		case math.IsInf(f, -1):
+			return slog.Float64Value(-math.MaxFloat64), true
+		}
+	case slog.KindDuration:
+		if v.Duration() < 0 {
+			return slog.DurationValue(0), true
+		}
+	}
+	return v, false
+}
@@ -0,0 +1,91 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSanitize(t *testing.T) {
+	r := testRegistry(t)
+	attrRatio := RegisterWith(r, "ratio", WithClamp(0.0, 1.0))
+	attrRetries := RegisterWith(r, "retries", WithClamp(0, 5))
+	attrRate := RegisterWith[float64](r, "rate")
+	attrWait := RegisterWith[time.Duration](r, "wait")
+	attrLatency := RegisterStatsWith[float64](r, "latency")
+
+	tests := []struct {
+		name string
+		set  func(ctx context.Context)
+		want []slog.Attr
+	}{
+		{
+			name: "in range",
+			set: func(ctx context.Context) {
+				Set(ctx, attrRatio, 0.5)
+				Set(ctx, attrRate, 2.5)
+				Set(ctx, attrWait, time.Second)
+			},
+			want: []slog.Attr{
+				slog.Float64("ratio", 0.5),
+				slog.Float64("rate", 2.5),
+				slog.Duration("wait", time.Second),
+			},
+		},
+		{
+			name: "clamped",
+			set: func(ctx context.Context) {
+				Set(ctx, attrRatio, 1.5)
+				Set(ctx, attrRetries, -1)
+			},
+			want: []slog.Attr{
+				slog.Float64("ratio", 1),
+				slog.Int("retries", 0),
+				slog.Bool(SanitizedKey, true),
+			},
+		},
+		{
+			name: "NaN",
+			set:  func(ctx context.Context) { Set(ctx, attrRate, math.NaN()) },
+			want: []slog.Attr{slog.Float64("rate", 0), slog.Bool(SanitizedKey, true)},
+		},
+		{
+			name: "infinite",
+			set:  func(ctx context.Context) { Set(ctx, attrRate, math.Inf(-1)) },
+			want: []slog.Attr{slog.Float64("rate", -math.MaxFloat64), slog.Bool(SanitizedKey, true)},
+		},
+		{
+			name: "negative duration",
+			set:  func(ctx context.Context) { Set(ctx, attrWait, -time.Second) },
+			want: []slog.Attr{slog.Duration("wait", 0), slog.Bool(SanitizedKey, true)},
+		},
+		{
+			name: "group",
+			set: func(ctx context.Context) {
+				Observe(ctx, attrLatency, 2)
+				Observe(ctx, attrLatency, math.Inf(1))
+			},
+			want: []slog.Attr{
+				slog.Group("latency",
+					slog.Int64("count", 2),
+					slog.Float64("sum", math.MaxFloat64),
+					slog.Float64("min", 2),
+					slog.Float64("max", math.MaxFloat64),
+				),
+				slog.Bool(SanitizedKey, true),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), NewLine(WithRegistry(r)))
+			tt.set(ctx)
+			if got := Attrs(ctx); !slices.EqualFunc(got, tt.want, slog.Attr.Equal) {
+				t.Errorf("Attrs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
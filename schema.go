@@ -0,0 +1,142 @@
+package canonlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SchemaVersionKey is the key under which a registry's schema version is
+// included in every line, unless another is set with
+// [Registry.SetSchemaVersionKey].
+const SchemaVersionKey = "schema_version"
+
+// CanonlogSchemaKey is an alternative to [SchemaVersionKey] for registries
+// whose lines are mixed with other logs that have their own schema_version
+// field, namespaced so that the version of the canonical line schema stands
+// apart; see [Registry.SetSchemaVersionKey].
+const CanonlogSchemaKey = "canonlog_schema"
+
+// SetSchemaVersion sets the schema version of r, which is included under
+// [SchemaVersionKey], or the key set with [Registry.SetSchemaVersionKey], as
+// the first attribute of every line associated with r (see [WithRegistry]).
+// Downstream parsers can use it to branch on schema generations during a
+// migration. Setting an empty version stops the attribute from being
+// included.
+//
+// SetSchemaVersion panics if an attribute with that key has been registered
+// in r; conversely, once a version is set, registering such an attribute
+// panics.
+func (r *Registry) SetSchemaVersion(version string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setSchemaVersion(version)
+}
+
+// setSchemaVersion is the implementation of [Registry.SetSchemaVersion].
+// r.mu must be held.
+func (r *Registry) setSchemaVersion(version string) {
+	if r.keys == nil {
+		r.keys = make(map[string]bool)
+	}
+	key := r.schemaVersionKey()
+	if r.keys[key] && r.schemaVersion == "" {
+		panic("canonlog: duplicate attribute key: " + key)
+	}
+	r.keys[key] = version != ""
+	r.schemaVersion = version
+}
+
+// SetSchemaVersionKey sets the key under which the schema version of r is
+// included in its lines, [SchemaVersionKey] by default, such as to
+// [CanonlogSchemaKey]:
+//
+//	canonlog.DefaultRegistry.SetSchemaVersionKey(canonlog.CanonlogSchemaKey)
+//	canonlog.DefaultRegistry.SetSchemaVersion("v7") // canonlog_schema=v7
+//
+// SetSchemaVersionKey panics if key is empty, or if r has a schema version
+// and an attribute with the key has been registered in r.
+func (r *Registry) SetSchemaVersionKey(key string) {
+	if key == "" {
+		panic("canonlog: empty schema version key")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.schemaVersionKey()
+	if key == old {
+		return
+	}
+	if r.schemaVersion != "" {
+		if r.keys[key] {
+			panic("canonlog: duplicate attribute key: " + key)
+		}
+		delete(r.keys, old)
+		r.keys[key] = true
+	}
+	r.schemaKey = key
+}
+
+// schemaVersionKey returns the key set with [Registry.SetSchemaVersionKey].
+// r.mu must be held.
+func (r *Registry) schemaVersionKey() string {
+	if r.schemaKey == "" {
+		return SchemaVersionKey
+	}
+	return r.schemaKey
+}
+
+// SchemaVersion returns the schema version of r, or the empty string if none
+// is set.
+func (r *Registry) SchemaVersion() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.schemaVersion
+}
+
+// schema returns the key and value of the schema version attribute of r.
+func (r *Registry) schema() (key, version string) {
+	if r == nil {
+		return SchemaVersionKey, ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.schemaVersionKey(), r.schemaVersion
+}
+
+// BumpSchemaVersion increments the number at the end of the schema version
+// of r, keeping anything before it, as in "v8" following "v7" or "2026.4"
+// following "2026.3", sets it as with [Registry.SetSchemaVersion], and
+// returns it, so that a change to the attributes emitted can be marked
+// without tracking the current version separately:
+//
+//	func init() {
+//		// Split the latency attribute into queue and processing times.
+//		canonlog.DefaultRegistry.BumpSchemaVersion()
+//	}
+//
+// A registry without a schema version gets the version "1", and a version
+// that does not end in a number gets a "2" appended, as in "beta2"
+// following "beta".
+func (r *Registry) BumpSchemaVersion() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setSchemaVersion(nextVersion(r.schemaVersion))
+	return r.schemaVersion
+}
+
+// nextVersion returns the schema version following version, as described
+// for [Registry.BumpSchemaVersion].
+func nextVersion(version string) string {
+	if version == "" {
+		return "1"
+	}
+	prefix := strings.TrimRight(version, "0123456789")
+	n, err := strconv.ParseUint(version[len(prefix):], 10, 64)
+	if err != nil {
+		// No number, or one too large to increment.
+		return version + "2"
+	}
+	return prefix + strconv.FormatUint(n+1, 10)
+}
@@ -0,0 +1,137 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	r.SetSchemaVersion("v7")
+
+	if got := r.SchemaVersion(); got != "v7" {
+		t.Errorf("SchemaVersion() = %q, want %q", got, "v7")
+	}
+
+	var buf bytes.Buffer
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line schema_version=v7 user=usr_123\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// Lines associated with other registries are unaffected.
+	ctx = New(context.Background())
+	Set(ctx, attrUser, "usr_123")
+	if attrs := Attrs(ctx); len(attrs) != 1 || attrs[0].Key != "user" {
+		t.Errorf("Attrs() for DefaultRegistry line = %v, want only user", attrs)
+	}
+}
+
+func TestSchemaVersion_EmptyLine(t *testing.T) {
+	r := testRegistry(t)
+	r.SetSchemaVersion("v1")
+
+	attrs := Attrs(New(context.Background(), WithRegistry(r)))
+	if len(attrs) != 1 || attrs[0].Key != SchemaVersionKey {
+		t.Errorf("Attrs() = %v, want only %s", attrs, SchemaVersionKey)
+	}
+}
+
+func TestSchemaVersion_Unset(t *testing.T) {
+	r := testRegistry(t)
+	r.SetSchemaVersion("v1")
+	r.SetSchemaVersion("")
+
+	if attrs := Attrs(New(context.Background(), WithRegistry(r))); attrs != nil {
+		t.Errorf("Attrs() = %v, want nil", attrs)
+	}
+
+	// Unsetting the version frees the key.
+	RegisterWith[string](r, SchemaVersionKey)
+}
+
+func TestSchemaVersion_DuplicateKey(t *testing.T) {
+	r := testRegistry(t)
+	r.SetSchemaVersion("v1")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("RegisterWith did not panic on the schema version key")
+		}
+	}()
+	RegisterWith[string](r, SchemaVersionKey)
+}
+
+func TestSetSchemaVersionKey(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	r.SetSchemaVersion("v6")
+	r.SetSchemaVersionKey(CanonlogSchemaKey)
+	r.BumpSchemaVersion()
+
+	var buf bytes.Buffer
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line canonlog_schema=v7 user=usr_123\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// The old key is freed, and the new one reserved.
+	RegisterWith[string](r, SchemaVersionKey)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("RegisterWith did not panic on the schema version key")
+		}
+	}()
+	RegisterWith[string](r, CanonlogSchemaKey)
+}
+
+func TestSetSchemaVersionKey_Empty(t *testing.T) {
+	r := testRegistry(t)
+	r.SetSchemaVersion("v1")
+	defer func() {
+		if recover() == nil {
+			t.Error("SetSchemaVersionKey did not panic on an empty key")
+		}
+		if got := r.SchemaVersion(); got != "v1" {
+			t.Errorf("SchemaVersion() = %q, want %q", got, "v1")
+		}
+	}()
+	r.SetSchemaVersionKey("")
+}
+
+func TestBumpSchemaVersion(t *testing.T) {
+	r := testRegistry(t)
+	if got := r.BumpSchemaVersion(); got != "1" {
+		t.Errorf("BumpSchemaVersion without a version = %q, want %q", got, "1")
+	}
+	r.SetSchemaVersion("v7")
+	if got := r.BumpSchemaVersion(); got != "v8" {
+		t.Errorf("BumpSchemaVersion of v7 = %q, want %q", got, "v8")
+	}
+	if got := r.SchemaVersion(); got != "v8" {
+		t.Errorf("SchemaVersion() = %q, want %q", got, "v8")
+	}
+
+	for version, want := range map[string]string{
+		"2026.3": "2026.4",
+		"v9":     "v10",
+		"v099":   "v100",
+		"beta":   "beta2",
+		"beta-":  "beta-2",
+	} {
+		if got := nextVersion(version); got != want {
+			t.Errorf("nextVersion(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package canonlog
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+)
+
+// RegisterSetWith creates a new set-valued attribute with the given key in
+// the specified registry. It panics if an attribute with the same key has
+// already been registered in that registry.
+//
+// Repeated Sets of a set-valued attribute are combined, discarding
+// duplicates, and the attribute is emitted as a sorted slice. This is useful
+// for recording things like which feature flags, tables, or downstream
+// services a request touched:
+//
+//	var AttrTables = canonlog.RegisterSet[string]("tables_touched")
+//
+//	canonlog.Set(ctx, AttrTables, []string{"users", "orgs"})
+//	canonlog.Set(ctx, AttrTables, []string{"users"})
+//	// emitted as tables_touched=[orgs users]
+//
+// Options are applied after the set's own merge and value functions, and so
+// can override them.
+func RegisterSetWith[T cmp.Ordered](r *Registry, key string, opts ...Option[[]T]) Attr[[]T] {
+	opts = append([]Option[[]T]{
+		WithMerge(mergeSet[T]),
+		WithCommutativeMerge[[]T](),
+		WithValue(func(v []T) slog.Value {
+			return slog.AnyValue(normalizeSet(slices.Clone(v)))
+		}),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterSet creates a new set-valued attribute with the given key using
+// [DefaultRegistry]. See [RegisterSetWith] for details.
+func RegisterSet[T cmp.Ordered](key string, opts ...Option[[]T]) Attr[[]T] {
+	return RegisterSetWith(DefaultRegistry, key, opts...)
+}
+
+// mergeSet returns the sorted union of old and new. Neither input is
+// modified.
+func mergeSet[T cmp.Ordered](old, new []T) []T {
+	merged := make([]T, 0, len(old)+len(new))
+	merged = append(merged, old...)
+	merged = append(merged, new...)
+	return normalizeSet(merged)
+}
+
+// normalizeSet sorts s and removes duplicate elements in place.
+func normalizeSet[T cmp.Ordered](s []T) []T {
+	slices.Sort(s)
+	return slices.Compact(s)
+}
@@ -0,0 +1,49 @@
+package canonlog
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestRegisterSet(t *testing.T) {
+	r := testRegistry(t)
+	attrTables := RegisterSetWith[string](r, "tables_touched")
+
+	ctx := New(context.Background())
+	Set(ctx, attrTables, []string{"users", "orgs", "users"})
+	Set(ctx, attrTables, []string{"billing", "orgs"})
+
+	attrs := Attrs(ctx)
+	if len(attrs) != 1 {
+		t.Fatalf("Attrs() returned %d attributes, want 1", len(attrs))
+	}
+
+	got, ok := attrs[0].Value.Any().([]string)
+	if !ok {
+		t.Fatalf("tables_touched value has type %T, want []string", attrs[0].Value.Any())
+	}
+	want := []string{"billing", "orgs", "users"}
+	if !slices.Equal(got, want) {
+		t.Errorf("tables_touched = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterSet_SingleSet(t *testing.T) {
+	r := testRegistry(t)
+	attrFlags := RegisterSetWith[int](r, "flags")
+
+	// A single Set never calls the merge function, but must still be
+	// emitted sorted and deduplicated without modifying the caller's slice.
+	in := []int{3, 1, 3, 2}
+	ctx := New(context.Background())
+	Set(ctx, attrFlags, in)
+
+	got := Attrs(ctx)[0].Value.Any().([]int)
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("flags = %v, want %v", got, want)
+	}
+	if want := []int{3, 1, 3, 2}; !slices.Equal(in, want) {
+		t.Errorf("input slice modified to %v, want %v", in, want)
+	}
+}
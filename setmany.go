@@ -0,0 +1,88 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// SetMany sets an attribute in the [Line] attached to ctx for each entry of
+// kv, for integration points that receive dynamic key/value pairs, such as
+// responses from an enrichment service. Entries are set in order of key.
+//
+// The registered schema is enforced where possible: an entry whose key is
+// that of an attribute registered in the line's registry (see
+// [WithRegistry]) is set as if by [Set] if its value has the attribute's
+// type, and otherwise ignored. Entries with other keys are set as is,
+// overwriting any previous value; they are emitted after registered
+// attributes with [OrderRegistration]. Use [SetManyStrict] to reject such
+// entries instead. If the context does not have a Line, SetMany does
+// nothing, other than reporting each entry as described for [Set].
+func SetMany(ctx context.Context, kv map[string]any) {
+	l := FromContext(ctx)
+	if l == nil {
+		for key := range kv {
+			noLine(key)
+		}
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(kv)) {
+		if set := l.registry.setter(key); set != nil {
+			set(ctx, kv[key])
+			continue
+		}
+		l.setDynamic(ctx, key, kv[key])
+	}
+}
+
+// SetManyStrict is like [SetMany], but only sets entries whose key is that
+// of an attribute registered in the line's registry and whose value has the
+// attribute's type. It returns an error describing each other entry, which
+// is not set.
+func SetManyStrict(ctx context.Context, kv map[string]any) error {
+	l := FromContext(ctx)
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(kv)) {
+		set := l.registry.setter(key)
+		if set == nil {
+			errs = append(errs, fmt.Errorf("canonlog: no attribute registered with key %q", key))
+			continue
+		}
+		if !set(ctx, kv[key]) {
+			errs = append(errs, fmt.Errorf("canonlog: type %T does not match attribute %q", kv[key], key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setDynamic sets value for key, which is not the key of a registered
+// attribute, overwriting any previous value, and runs the set hooks of l's
+// registry with ctx.
+func (l *Line) setDynamic(ctx context.Context, key string, value any) {
+	if l.lateSet(key) {
+		return
+	}
+
+	s := l.shard(key)
+	s.mu.Lock()
+	existing, _ := s.get(key)
+	size := sizeOf(key, value, l.maxBytes)
+	if !l.reserve(existing.charged(), size) {
+		s.mu.Unlock()
+		return
+	}
+	if _, ok := existing.raw.(accumulated); ok {
+		l.accums.Delete(key)
+	}
+	l.put(s, key, storedValue{raw: value, name: key, seq: math.MaxUint64, size: size})
+	s.mu.Unlock()
+
+	l.recordCaller(key)
+	runSetHooks(ctx, l, key, value)
+}
@@ -0,0 +1,64 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetMany(t *testing.T) {
+	r := testRegistry(t)
+	attrTier := RegisterWith[string](r, "tier")
+	RegisterWith[int](r, "seats")
+	RegisterWith(r, "calls",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrTier, "free")
+	SetMany(ctx, map[string]any{
+		"tier":    "enterprise",
+		"seats":   "many", // wrong type; ignored
+		"calls":   2,
+		"segment": "smb",
+	})
+	SetMany(ctx, map[string]any{"calls": 3, "segment": "mid-market"})
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line tier=enterprise calls=5 segment=mid-market\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestSetManyStrict(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith[string](r, "tier")
+	RegisterWith[int](r, "seats")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	err := SetManyStrict(ctx, map[string]any{
+		"tier":    "enterprise",
+		"seats":   "many",
+		"segment": "smb",
+	})
+	if err == nil {
+		t.Fatal("SetManyStrict succeeded, want error")
+	}
+	for _, want := range []string{`type string does not match attribute "seats"`, `no attribute registered with key "segment"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to contain %q", err, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line tier=enterprise\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package canonlog
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// lineShards is the number of shards a [Line] spreads its values over.
+const lineShards = 8
+
+// lineShard holds the values of a Line whose keys hash to it. A line
+// typically has a few dozen attributes, so each shard only holds a handful,
+// which are cheaper to find by scanning a slice than to store in a map.
+type lineShard struct {
+	mu      sync.Mutex
+	entries []entry
+
+	_ [32]byte // keep neighbouring shards off the same cache line
+}
+
+// get returns the value stored for key in s, and whether there is one.
+func (s *lineShard) get(key string) (storedValue, bool) {
+	for i := range s.entries {
+		if s.entries[i].key == key {
+			return s.entries[i].sv, true
+		}
+	}
+	return storedValue{}, false
+}
+
+// shardIndex returns the index of the shard holding key, using the FNV-1a
+// hash of the key.
+func shardIndex(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % lineShards)
+}
+
+// shard returns the shard of l holding key.
+func (l *Line) shard(key string) *lineShard {
+	return &l.shards[shardIndex(key)]
+}
+
+// put stores sv for key in s, a shard of l that must be locked (or not yet
+// shared with other goroutines). If s already holds a value for key, sv
+// takes its position in the line's insertion order.
+func (l *Line) put(s *lineShard, key string, sv storedValue) {
+	for i := range s.entries {
+		if e := &s.entries[i]; e.key == key {
+			sv.inserted = e.sv.inserted
+			e.sv = sv
+			return
+		}
+	}
+	sv.inserted = l.inserted.Add(1)
+	s.entries = append(s.entries, entry{key, sv})
+}
+
+// lockAll locks every shard of l, for a consistent view of all its values.
+// Shards are always locked in the same order, and Sets only ever lock one,
+// so this cannot deadlock.
+func (l *Line) lockAll() {
+	for i := range l.shards {
+		l.shards[i].mu.Lock()
+	}
+}
+
+// unlockAll unlocks the shards locked by lockAll.
+func (l *Line) unlockAll() {
+	for i := range l.shards {
+		l.shards[i].mu.Unlock()
+	}
+}
+
+// len returns the number of values in l. The shards of l must be locked
+// with lockAll.
+func (l *Line) len() int {
+	var n int
+	for i := range l.shards {
+		n += len(l.shards[i].entries)
+	}
+	return n
+}
+
+// entry is a value stored in a Line, with its key.
+type entry struct {
+	key string
+	sv  storedValue
+}
+
+// entries returns the values of l in the order they were first set. The
+// shards of l must be locked with lockAll.
+func (l *Line) entries() []entry {
+	es := make([]entry, 0, l.len())
+	for i := range l.shards {
+		es = append(es, l.shards[i].entries...)
+	}
+	slices.SortFunc(es, func(a, b entry) int {
+		return cmp.Compare(a.sv.inserted, b.sv.inserted)
+	})
+	return es
+}
+
+// WithCapacity preallocates room in the new [Line] for n attributes, so
+// that a request setting a known number of attributes does not grow the
+// line's storage as it sets them. Storage for all n attributes is allocated
+// at once, with some slack, since attributes are spread unevenly over the
+// line's internal shards; setting more than n attributes still works.
+func WithCapacity(n int) LineOption {
+	return func(l *Line) {
+		l.grow(n)
+	}
+}
+
+// grow ensures that each shard of l has room for its share of n more
+// entries, taking the room for all shards from a single allocation.
+func (l *Line) grow(n int) {
+	if n <= 0 {
+		return
+	}
+	per := (n+lineShards-1)/lineShards + 1
+	backing := make([]entry, 0, per*lineShards)
+	for i := range l.shards {
+		s := &l.shards[i]
+		if cap(s.entries)-len(s.entries) >= per {
+			continue
+		}
+		buf := backing[i*per : i*per : (i+1)*per]
+		s.entries = append(buf, s.entries...)
+	}
+}
@@ -0,0 +1,51 @@
+package canonlog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestWithCapacity(t *testing.T) {
+	r := testRegistry(t)
+	attrs := make([]Attr[int], 40)
+	for i := range attrs {
+		attrs[i] = RegisterWith[int](r, fmt.Sprintf("attr_%d", i))
+	}
+
+	// Setting more attributes than the capacity must keep every value
+	// and the order they were set in.
+	ctx := New(context.Background(), WithCapacity(16))
+	for i, attr := range attrs {
+		Set(ctx, attr, i)
+	}
+	got := Attrs(ctx)
+	if len(got) != len(attrs) {
+		t.Fatalf("Attrs returned %d attributes, want %d", len(got), len(attrs))
+	}
+	for i, a := range got {
+		if a.Key != attrs[i].Key() || a.Value.Int64() != int64(i) {
+			t.Errorf("attrs[%d] = %v, want %s=%d", i, a, attrs[i].Key(), i)
+		}
+	}
+}
+
+func BenchmarkWithCapacity(b *testing.B) {
+	r := NewRegistry()
+	attrs := make([]Attr[int], 16)
+	for i := range attrs {
+		attrs[i] = RegisterWith[int](r, fmt.Sprintf("attr_%d", i))
+	}
+
+	for _, capacity := range []int{0, 16} {
+		b.Run(fmt.Sprintf("capacity=%d", capacity), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				ctx := New(context.Background(), WithCapacity(capacity))
+				for i, attr := range attrs {
+					Set(ctx, attr, i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package canonlog
+
+import "log/slog"
+
+// staticValue is an attribute included in every line of a registry.
+type staticValue struct {
+	key   string
+	group string
+	name  string
+	value slog.Value
+
+	visibility Visibility
+	aliases    []string
+}
+
+// SetGlobalWith sets a static value for attr in r, which is included in
+// every line associated with r (see [WithRegistry]) without being set on
+// each one, for process-level constants such as the service name, version,
+// region, hostname or process ID:
+//
+//	canonlog.SetGlobal(AttrService, "checkout")
+//	canonlog.SetGlobal(AttrPID, os.Getpid())
+//
+// Static attributes follow the attributes set on the line, in the order
+// they were first set with SetGlobalWith. A value set on the line itself
+// for the same attribute takes precedence over the static value. The value
+// is converted to a [slog.Value] once, when SetGlobalWith is called.
+func SetGlobalWith[T any](r *Registry, attr Attr[T], value T) {
+	var v slog.Value
+	if attr.convert != nil {
+		v = attr.convert(value)
+	} else {
+		v = slog.AnyValue(value)
+	}
+	r.setStatic(staticValue{
+		key: attr.key, group: attr.group, name: attr.name, value: v,
+		visibility: attr.visibility, aliases: attr.aliases,
+	})
+}
+
+// setStatic sets st as a static attribute of r, replacing any with the same
+// key.
+func (r *Registry) setStatic(st staticValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	static := make([]staticValue, 0, len(r.static)+1)
+	replaced := false
+	for _, old := range r.static {
+		if old.key == st.key {
+			old, replaced = st, true
+		}
+		static = append(static, old)
+	}
+	if !replaced {
+		static = append(static, st)
+	}
+	r.static = static
+}
+
+// SetGlobal sets a static value for attr in [DefaultRegistry]. See
+// [SetGlobalWith] for details.
+func SetGlobal[T any](attr Attr[T], value T) {
+	SetGlobalWith(DefaultRegistry, attr, value)
+}
+
+// statics returns the static attributes of r. The returned slice must not be
+// modified.
+func (r *Registry) statics() []staticValue {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.static
+}
@@ -0,0 +1,53 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestSetGlobalWith(t *testing.T) {
+	r := testRegistry(t)
+	attrService := RegisterWith[string](r, "service")
+	attrRegion := RegisterWith[string](r, "region", WithGroup[string]("cloud"))
+	attrPID := RegisterWith[int](r, "pid")
+	attrUser := RegisterWith[string](r, "user")
+
+	SetGlobalWith(r, attrService, "checkout")
+	SetGlobalWith(r, attrRegion, "us-east-1")
+	SetGlobalWith(r, attrPID, 41)
+	SetGlobalWith(r, attrPID, 42)
+
+	tests := []struct {
+		name string
+		set  func(ctx context.Context)
+		want string
+	}{
+		{
+			name: "empty line",
+			set:  func(ctx context.Context) {},
+			want: "level=INFO msg=canonical-log-line service=checkout cloud.region=us-east-1 pid=42\n",
+		},
+		{
+			name: "line values",
+			set: func(ctx context.Context) {
+				Set(ctx, attrUser, "usr_123")
+				Set(ctx, attrService, "checkout-canary")
+			},
+			want: "level=INFO msg=canonical-log-line user=usr_123 service=checkout-canary cloud.region=us-east-1 pid=42\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := New(context.Background(), WithRegistry(r))
+			tt.set(ctx)
+
+			var buf bytes.Buffer
+			Emit(ctx, testLogger(&buf), slog.LevelInfo)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("log output:\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
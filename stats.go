@@ -0,0 +1,225 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+)
+
+// Stats summarizes the observations recorded by a stats attribute.
+type Stats[T Number] struct {
+	Count int64
+	Sum   T
+	Min   T
+	Max   T
+
+	// sketch holds the distribution of observations, sorted by bucket
+	// key, if the attribute was registered with [WithQuantiles]. It is
+	// never modified once created, so Stats values may be freely copied.
+	sketch []sketchBucket
+}
+
+// RegisterStatsWith creates a new stats attribute with the given key in the
+// specified registry. It panics if an attribute with the same key has already
+// been registered in that registry.
+//
+// Each call to [Observe] records one observation, and the attribute is
+// emitted as an [slog.Group] with the count, sum, min and max of all
+// observations, so that repeated operations are represented by their
+// distribution rather than just a total:
+//
+//	var AttrQueryLatency = canonlog.RegisterStats[time.Duration]("query_latency")
+//
+//	canonlog.Observe(ctx, AttrQueryLatency, 12*time.Millisecond)
+//	canonlog.Observe(ctx, AttrQueryLatency, 30*time.Millisecond)
+//	// emitted as query_latency.count=2 query_latency.sum=42ms
+//	//   query_latency.min=12ms query_latency.max=30ms
+//
+// Use [WithQuantiles] to also emit estimated quantiles.
+func RegisterStatsWith[T Number](r *Registry, key string, opts ...Option[Stats[T]]) Attr[Stats[T]] {
+	opts = append([]Option[Stats[T]]{
+		WithMerge(mergeStats[T]),
+		WithCommutativeMerge[Stats[T]](),
+		WithValue(func(s Stats[T]) slog.Value {
+			return slog.GroupValue(statsAttrs(s)...)
+		}),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterStats creates a new stats attribute with the given key using
+// [DefaultRegistry]. See [RegisterStatsWith] for details.
+func RegisterStats[T Number](key string, opts ...Option[Stats[T]]) Attr[Stats[T]] {
+	return RegisterStatsWith(DefaultRegistry, key, opts...)
+}
+
+// WithQuantiles makes a stats attribute also emit an estimate of each of the
+// given quantiles, which must be between 0 and 1. Each is emitted with a key
+// formed from its percentile, e.g. "p50" for 0.5 and "p99.9" for 0.999.
+//
+// Quantiles are estimated from a sketch of the observations with a relative
+// error of about 1%, whose size grows with the logarithm of the range of
+// observed values rather than with their number.
+func WithQuantiles[T Number](quantiles ...float64) Option[Stats[T]] {
+	for _, q := range quantiles {
+		if !(q >= 0 && q <= 1) {
+			panic("canonlog: quantile out of range: " + strconv.FormatFloat(q, 'g', -1, 64))
+		}
+	}
+	return func(a *Attr[Stats[T]]) {
+		a.merge = func(old, new Stats[T]) Stats[T] {
+			old, new = withSketch(old), withSketch(new)
+			merged := mergeStats(old, new)
+			merged.sketch = mergeSketches(old.sketch, new.sketch)
+			return merged
+		}
+		a.commutative = true
+		a.toValue = func(s Stats[T]) slog.Value {
+			s = withSketch(s)
+			attrs := statsAttrs(s)
+			for _, q := range quantiles {
+				key := "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
+				attrs = append(attrs, slog.Any(key, s.quantile(q)))
+			}
+			return slog.GroupValue(attrs...)
+		}
+	}
+}
+
+// Observe records one observation of v for the stats attribute attr in the
+// [Line] attached to ctx. If the context does not have a Line, Observe
+// silently does nothing.
+func Observe[T Number](ctx context.Context, attr Attr[Stats[T]], v T) {
+	Set(ctx, attr, Stats[T]{Count: 1, Sum: v, Min: v, Max: v})
+}
+
+// mergeStats combines the count, sum, min and max of two Stats. The sketch
+// of the result is nil.
+func mergeStats[T Number](old, new Stats[T]) Stats[T] {
+	if old.Count == 0 {
+		return Stats[T]{Count: new.Count, Sum: new.Sum, Min: new.Min, Max: new.Max}
+	}
+	if new.Count == 0 {
+		return Stats[T]{Count: old.Count, Sum: old.Sum, Min: old.Min, Max: old.Max}
+	}
+	return Stats[T]{
+		Count: old.Count + new.Count,
+		Sum:   old.Sum + new.Sum,
+		Min:   min(old.Min, new.Min),
+		Max:   max(old.Max, new.Max),
+	}
+}
+
+func statsAttrs[T Number](s Stats[T]) []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("count", s.Count),
+		slog.Any("sum", s.Sum),
+		slog.Any("min", s.Min),
+		slog.Any("max", s.Max),
+	}
+}
+
+// withSketch returns s with a sketch. Stats recorded by [Observe] hold a
+// single observation and no sketch, so one is created from it; Stats
+// aggregated elsewhere cannot be sketched and are returned unchanged.
+func withSketch[T Number](s Stats[T]) Stats[T] {
+	if s.sketch == nil && s.Count == 1 {
+		s.sketch = []sketchBucket{{key: sketchKey(float64(s.Sum)), count: 1}}
+	}
+	return s
+}
+
+// quantile returns an estimate of the q-quantile of the observations in s,
+// or zero if s has no sketch.
+func (s Stats[T]) quantile(q float64) T {
+	var total int64
+	for _, b := range s.sketch {
+		total += b.count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(total-1))
+	var seen int64
+	for _, b := range s.sketch {
+		seen += b.count
+		if seen > rank {
+			// Clamp before converting, since the bucket's value
+			// may be outside the range of T.
+			v := min(max(sketchValue(b.key), float64(s.Min)), float64(s.Max))
+			return T(v)
+		}
+	}
+	return s.Max
+}
+
+// The sketch used for quantiles is a simplified DDSketch: each observation
+// v is counted in a bucket whose index is ceil(log(|v|) / log(gamma)), so
+// that every value in a bucket is within sketchAccuracy of the bucket's
+// representative value.
+const sketchAccuracy = 0.01
+
+var sketchGamma = (1 + sketchAccuracy) / (1 - sketchAccuracy)
+
+// sketchBucket counts the observations falling into one sketch bucket.
+type sketchBucket struct {
+	key   int64
+	count int64
+}
+
+// sketchOffset separates the bucket keys of positive, zero and negative
+// values, so that keys sort in the same order as the values they represent.
+const sketchOffset = 1 << 32
+
+// sketchKey returns the key of the bucket containing v.
+func sketchKey(v float64) int64 {
+	switch {
+	case v > 0:
+		return sketchOffset + sketchIndex(v)
+	case v < 0:
+		return -sketchOffset - sketchIndex(-v)
+	default:
+		return 0
+	}
+}
+
+func sketchIndex(v float64) int64 {
+	return int64(math.Ceil(math.Log(v) / math.Log(sketchGamma)))
+}
+
+// sketchValue returns the representative value of the bucket with key k.
+func sketchValue(k int64) float64 {
+	value := func(index int64) float64 {
+		return 2 * math.Pow(sketchGamma, float64(index)) / (sketchGamma + 1)
+	}
+	switch {
+	case k > 0:
+		return value(k - sketchOffset)
+	case k < 0:
+		return -value(-k - sketchOffset)
+	default:
+		return 0
+	}
+}
+
+// mergeSketches returns a new sketch combining the counts of a and b.
+func mergeSketches(a, b []sketchBucket) []sketchBucket {
+	merged := make([]sketchBucket, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0].key < b[0].key:
+			merged = append(merged, a[0])
+			a = a[1:]
+		case a[0].key > b[0].key:
+			merged = append(merged, b[0])
+			b = b[1:]
+		default:
+			merged = append(merged, sketchBucket{key: a[0].key, count: a[0].count + b[0].count})
+			a, b = a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
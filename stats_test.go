@@ -0,0 +1,132 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestRegisterStats(t *testing.T) {
+	r := testRegistry(t)
+	attrLatency := RegisterStatsWith[time.Duration](r, "query_latency")
+
+	ctx := New(context.Background())
+	Observe(ctx, attrLatency, 30*time.Millisecond)
+	Observe(ctx, attrLatency, 10*time.Millisecond)
+	Observe(ctx, attrLatency, 20*time.Millisecond)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line query_latency.count=3 query_latency.sum=60ms query_latency.min=10ms query_latency.max=30ms\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRegisterStats_Quantiles(t *testing.T) {
+	r := testRegistry(t)
+	attrSize := RegisterStatsWith(r, "size", WithQuantiles[float64](0, 0.5, 0.99, 0.999))
+
+	ctx := New(context.Background())
+	for i := 1; i <= 1000; i++ {
+		Observe(ctx, attrSize, float64(i))
+	}
+
+	group := Attrs(ctx)[0].Value.Group()
+	got := make(map[string]float64)
+	for _, a := range group {
+		if a.Value.Kind() == slog.KindFloat64 {
+			got[a.Key] = a.Value.Float64()
+		}
+	}
+
+	for key, want := range map[string]float64{
+		"p0":    1,
+		"p50":   500,
+		"p99":   990,
+		"p99.9": 999,
+	} {
+		v, ok := got[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if math.Abs(v-want)/want > 2*sketchAccuracy {
+			t.Errorf("%s = %v, want %v (within %v%%)", key, v, want, 2*sketchAccuracy*100)
+		}
+	}
+}
+
+func TestRegisterStats_QuantilesSingleObservation(t *testing.T) {
+	r := testRegistry(t)
+	attrBytes := RegisterStatsWith(r, "bytes", WithQuantiles[uint8](0.5))
+
+	ctx := New(context.Background())
+	Observe(ctx, attrBytes, 255)
+
+	group := Attrs(ctx)[0].Value.Group()
+	p50 := group[len(group)-1]
+	if p50.Key != "p50" || p50.Value.Uint64() != 255 {
+		t.Errorf("last group member = %v, want p50=255", p50)
+	}
+}
+
+func TestRegisterStats_NegativeAndZero(t *testing.T) {
+	r := testRegistry(t)
+	attrDelta := RegisterStatsWith(r, "delta", WithQuantiles[int](0, 0.5, 1))
+
+	ctx := New(context.Background())
+	for _, v := range []int{-100, 0, 0, 0, 100} {
+		Observe(ctx, attrDelta, v)
+	}
+
+	group := Attrs(ctx)[0].Value.Group()
+	got := make(map[string]int64)
+	for _, a := range group {
+		got[a.Key] = a.Value.Int64()
+	}
+	if got["p0"] != -100 || got["p50"] != 0 || got["p100"] != 100 {
+		t.Errorf("quantiles = p0:%d p50:%d p100:%d, want -100, 0, 100", got["p0"], got["p50"], got["p100"])
+	}
+}
+
+func TestWithQuantiles_OutOfRange(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("WithQuantiles did not panic with quantile 1.5")
+		}
+	}()
+	WithQuantiles[float64](1.5)
+}
+
+func TestRegisterStats_MergeProperties(t *testing.T) {
+	attr := RegisterStatsWith(testRegistry(t), "stats", WithQuantiles[int32](0.5))
+
+	// Stats have unexported fields, so generate them by merging random
+	// observations.
+	cfg := &quick.Config{
+		Values: func(args []reflect.Value, rand *rand.Rand) {
+			for i := range args {
+				var s Stats[int32]
+				for range rand.Intn(5) {
+					v := rand.Int31() - rand.Int31()
+					s = attr.merge(s, Stats[int32]{Count: 1, Sum: v, Min: v, Max: v})
+				}
+				args[i] = reflect.ValueOf(s)
+			}
+		},
+	}
+	equal := func(a, b Stats[int32]) bool {
+		return a.Count == b.Count && a.Sum == b.Sum && a.Min == b.Min && a.Max == b.Max &&
+			slices.Equal(a.sketch, b.sketch)
+	}
+	checkMergeProperties(t, attr, equal, cfg)
+}
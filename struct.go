@@ -0,0 +1,111 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structField describes a tagged field of a struct passed to SetStruct.
+type structField struct {
+	index     []int
+	name      string
+	key       string
+	omitEmpty bool
+}
+
+// structFields caches the tagged fields of each struct type passed to
+// SetStruct.
+var structFields sync.Map // reflect.Type -> []structField
+
+// SetStruct sets an attribute in the [Line] attached to ctx for each field
+// of the struct v, or the struct v points to, that has a "canonlog" tag,
+// so that metadata carried by a request or response type can be recorded in
+// one call:
+//
+//	type ChargeRequest struct {
+//		CustomerID string `json:"customer_id" canonlog:"customer_id"`
+//		Amount     int64  `json:"amount" canonlog:"amount"`
+//		Coupon     string `json:"coupon" canonlog:"coupon,omitempty"`
+//		Card       string `json:"card"`
+//	}
+//
+//	canonlog.SetStruct(ctx, req)
+//
+// The tag gives the key of an attribute registered in the line's registry
+// (see [WithRegistry]), whose type must be the same as the field's; the
+// field's value is set as if by [Set]. The "omitempty" option skips the
+// field if it has its zero value, and a tag of "-" is ignored, as are
+// untagged fields. Fields of embedded structs are included, as are those of
+// embedded pointers to structs unless the pointer is nil.
+//
+// SetStruct returns an error describing each tagged field that could not be
+// set, because no attribute is registered with its key or the attribute has
+// a different type; other fields are still set. It returns an error if v is
+// not a struct or a non-nil pointer to one. If the context does not have a
+// Line, SetStruct does nothing and returns nil.
+func SetStruct(ctx context.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("canonlog: SetStruct of non-struct type %T", v)
+	}
+
+	l := FromContext(ctx)
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, f := range fieldsOf(rv.Type()) {
+		fv, err := rv.FieldByIndexErr(f.index)
+		if err != nil || f.omitEmpty && fv.IsZero() {
+			continue // promoted through a nil embedded pointer, or empty
+		}
+		set := l.registry.setter(f.key)
+		if set == nil {
+			errs = append(errs, fmt.Errorf("canonlog: field %s: no attribute registered with key %q", f.name, f.key))
+			continue
+		}
+		if !set(ctx, fv.Interface()) {
+			errs = append(errs, fmt.Errorf("canonlog: field %s: type %s does not match attribute %q", f.name, fv.Type(), f.key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fieldsOf returns the tagged fields of the struct type t.
+func fieldsOf(t reflect.Type) []structField {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]structField)
+	}
+
+	var fields []structField
+	for _, sf := range reflect.VisibleFields(t) {
+		tag, ok := sf.Tag.Lookup("canonlog")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		fields = append(fields, structField{
+			index:     sf.Index,
+			name:      sf.Name,
+			key:       key,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	structFields.Store(t, fields)
+	return fields
+}
+
+// setter returns the function that sets a value of the attribute with the
+// given key registered in r, or nil if there is none.
+func (r *Registry) setter(key string) func(context.Context, any) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.setters[key]
+}
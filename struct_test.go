@@ -0,0 +1,106 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetStruct(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith[string](r, "customer_id")
+	RegisterWith[int64](r, "amount")
+	RegisterWith[string](r, "coupon")
+	RegisterWith[string](r, "region")
+
+	type Meta struct {
+		Region string `canonlog:"region"`
+	}
+	type ChargeRequest struct {
+		Meta
+		CustomerID string `canonlog:"customer_id"`
+		Amount     int64  `canonlog:"amount"`
+		Coupon     string `canonlog:"coupon,omitempty"`
+		Card       string
+		Secret     string `canonlog:"-"`
+	}
+
+	ctx := New(context.Background(), WithRegistry(r))
+	err := SetStruct(ctx, &ChargeRequest{
+		Meta:       Meta{Region: "eu"},
+		CustomerID: "cus_42",
+		Amount:     1299,
+		Card:       "4242",
+		Secret:     "s3cr3t",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line region=eu customer_id=cus_42 amount=1299\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestSetStruct_NilEmbedded(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith[string](r, "customer_id")
+	RegisterWith[string](r, "region")
+
+	type Meta struct {
+		Region string `canonlog:"region"`
+	}
+	type ChargeRequest struct {
+		*Meta
+		CustomerID string `canonlog:"customer_id"`
+	}
+
+	ctx := New(context.Background(), WithRegistry(r))
+	if err := SetStruct(ctx, ChargeRequest{CustomerID: "cus_42"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStruct(ctx, ChargeRequest{Meta: &Meta{Region: "eu"}, CustomerID: "cus_42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+	want := "level=INFO msg=canonical-log-line customer_id=cus_42 region=eu\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestSetStruct_Errors(t *testing.T) {
+	r := testRegistry(t)
+	RegisterWith[int64](r, "amount")
+	attrUser := RegisterWith[string](r, "user")
+
+	ctx := New(context.Background(), WithRegistry(r))
+	err := SetStruct(ctx, struct {
+		Amount  int    `canonlog:"amount"`
+		Unknown string `canonlog:"unknown"`
+		User    string `canonlog:"user"`
+	}{Amount: 1, Unknown: "x", User: "usr_123"})
+
+	if err == nil {
+		t.Fatal("SetStruct succeeded, want error")
+	}
+	for _, want := range []string{`field Amount: type int does not match attribute "amount"`, `field Unknown: no attribute registered with key "unknown"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to contain %q", err, want)
+		}
+	}
+	if got, _ := lookup(FromContext(ctx), attrUser); got != "usr_123" {
+		t.Errorf("user = %q, want valid fields to still be set", got)
+	}
+
+	if err := SetStruct(ctx, 42); err == nil {
+		t.Error("SetStruct of an int succeeded, want error")
+	}
+}
@@ -0,0 +1,57 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// taskRegistry holds the attributes recorded by [Emitter.Task], kept out of
+// [DefaultRegistry] for the same reason as those of [Middleware].
+var taskRegistry = NewRegistry()
+
+var (
+	attrTask         = RegisterWith[string](taskRegistry, "task")
+	attrTaskDuration = RegisterWith[time.Duration](taskRegistry, "duration")
+)
+
+// Task runs fn as a background task with its own canonical log line, the
+// same way [Middleware] does for HTTP requests, for goroutines, cron ticks,
+// queue consumers and the like:
+//
+//	go emitter.Task(ctx, "sync_inventory", func(ctx context.Context) error {
+//		...
+//	})
+//
+// fn is called with a context carrying a new [Line], on which Task records
+// the task name and duration. The line is emitted once fn returns, with the
+// level and outcome derived from its error as described for
+// [Emitter.EmitOnReturn], and that error is returned. If fn panics, the line
+// is emitted at [slog.LevelError] with outcome=panic and the panic value
+// recorded under the "panic" key, and the panic is then resumed.
+func (e *Emitter) Task(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	ctx = New(ctx)
+	Set(ctx, attrTask, name)
+
+	start := time.Now()
+	defer func() {
+		Set(ctx, attrTaskDuration, time.Since(start))
+		if p := recover(); p != nil {
+			e.emit(ctx, slog.LevelError,
+				slog.String("outcome", "panic"),
+				slog.Any("panic", p),
+			)
+			panic(p)
+		}
+		e.EmitOnReturn(ctx, &err)()
+	}()
+
+	return fn(ctx)
+}
+
+// Task runs fn as a background task with its own canonical log line,
+// emitted to [slog.Default]. It is shorthand for
+// NewEmitter(nil).Task(ctx, name, fn); see [Emitter.Task] for details.
+func Task(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	return NewEmitter(nil).Task(ctx, name, fn)
+}
@@ -0,0 +1,65 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestTask(t *testing.T) {
+	r := testRegistry(t)
+	attrItems := RegisterWith[int](r, "items")
+
+	boom := errors.New("boom")
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context) error
+		wantErr error
+		want    string
+	}{
+		{
+			name: "success",
+			fn: func(ctx context.Context) error {
+				Set(ctx, attrItems, 3)
+				return nil
+			},
+			want: `^level=INFO msg=canonical-log-line task=sync_inventory items=3 duration=\S+ outcome=success\n$`,
+		},
+		{
+			name:    "error",
+			fn:      func(ctx context.Context) error { return boom },
+			wantErr: boom,
+			want:    `^level=ERROR msg=canonical-log-line task=sync_inventory duration=\S+ outcome=error error=boom\n$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewEmitter(testLogger(&buf)).Task(context.Background(), "sync_inventory", tt.fn)
+			if err != tt.wantErr {
+				t.Errorf("Task returned %v, want %v", err, tt.wantErr)
+			}
+			if got := buf.String(); !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("log output:\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTask_Panic(t *testing.T) {
+	var buf bytes.Buffer
+	defer func() {
+		if p := recover(); p != "oops" {
+			t.Errorf("recovered %v, want the task's panic", p)
+		}
+		want := `^level=ERROR msg=canonical-log-line task=crashy duration=\S+ outcome=panic panic=oops\n$`
+		if got := buf.String(); !regexp.MustCompile(want).MatchString(got) {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	}()
+	NewEmitter(testLogger(&buf)).Task(context.Background(), "crashy", func(ctx context.Context) error {
+		panic("oops")
+	})
+}
@@ -0,0 +1,154 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// groupRegistry holds the attribute recorded by [TaskGroup], kept out of
+// [DefaultRegistry] for the same reason as those of [Middleware].
+var groupRegistry = NewRegistry()
+
+// attrGoroutines records the goroutines run by a TaskGroup, by name.
+var attrGoroutines = RegisterWith(groupRegistry, "goroutines",
+	WithMerge(MergeMap(mergeGoroutineStats)),
+	WithValue(goroutinesValue),
+)
+
+// goroutineStats describes the goroutines of a TaskGroup with the same name.
+type goroutineStats struct {
+	count    int
+	duration time.Duration
+	errors   int
+	err      string
+}
+
+// mergeGoroutineStats sums counts, durations and errors, and keeps the first
+// error.
+func mergeGoroutineStats(old, new goroutineStats) goroutineStats {
+	err := old.err
+	if err == "" {
+		err = new.err
+	}
+	return goroutineStats{
+		count:    old.count + new.count,
+		duration: old.duration + new.duration,
+		errors:   old.errors + new.errors,
+		err:      err,
+	}
+}
+
+// goroutinesValue converts goroutine statistics to a group with a member
+// per name, sorted by name.
+func goroutinesValue(m map[string]goroutineStats) slog.Value {
+	names := make([]slog.Attr, 0, len(m))
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		st := m[name]
+		attrs := []slog.Attr{
+			slog.Int("count", st.count),
+			slog.Duration("duration", st.duration),
+			slog.Int("errors", st.errors),
+		}
+		if st.err != "" {
+			attrs = append(attrs, slog.String("error", st.err))
+		}
+		names = append(names, slog.Attr{Key: name, Value: slog.GroupValue(attrs...)})
+	}
+	return slog.GroupValue(names...)
+}
+
+// A TaskGroup runs goroutines working on subtasks of a common task, like
+// golang.org/x/sync/errgroup.Group, with each goroutine recording into its
+// own [Line] that is merged into the task's Line once the group is waited
+// for. It is created by [Group].
+type TaskGroup struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+
+	mu       sync.Mutex
+	children []context.Context
+}
+
+// Group returns a new TaskGroup and a context derived from ctx, which is
+// canceled the first time a goroutine started with [TaskGroup.Go] returns an
+// error, or when [TaskGroup.Wait] returns, whichever happens first:
+//
+//	g, gctx := canonlog.Group(ctx)
+//	g.Go("user", func(ctx context.Context) error {
+//		user, err = fetchUser(ctx, id)
+//		return err
+//	})
+//	g.Go("orders", func(ctx context.Context) error {
+//		orders, err = fetchOrders(ctx, id)
+//		return err
+//	})
+//	err := g.Wait()
+//
+// Each goroutine is given a context carrying a [Fork] of the [Line] in
+// ctx, which Wait merges back into it with [Join], in the order the
+// goroutines were started. Wait also records the goroutines in a
+// "goroutines" group, with a member per name containing the number of
+// goroutines started with that name, their total duration, the number that
+// returned an error, and the first such error, as in
+// goroutines.orders.count=1 goroutines.orders.duration=20ms
+// goroutines.orders.errors=1 goroutines.orders.error="connection refused".
+//
+// If ctx does not have a Line, the goroutines record nothing, but the
+// TaskGroup still behaves like an errgroup.Group.
+func Group(ctx context.Context) (*TaskGroup, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &TaskGroup{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go calls fn in a new goroutine, with a context carrying a new [Line]
+// forked from the group's. The first goroutine to return a non-nil error
+// cancels the group's context, and its error is returned by
+// [TaskGroup.Wait].
+func (g *TaskGroup) Go(name string, fn func(ctx context.Context) error) {
+	child := Fork(g.ctx)
+	g.mu.Lock()
+	g.children = append(g.children, child)
+	g.mu.Unlock()
+
+	g.wg.Go(func() {
+		start := time.Now()
+		err := fn(child)
+
+		st := goroutineStats{count: 1, duration: time.Since(start)}
+		if err != nil {
+			st.errors = 1
+			st.err = err.Error()
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+		SetKey(child, attrGoroutines, name, st)
+	})
+}
+
+// Wait waits for all goroutines started with [TaskGroup.Go] to return,
+// merges their lines into the group's [Line], and returns the first non-nil
+// error, if any, returned by one of them.
+func (g *TaskGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+
+	g.mu.Lock()
+	children := g.children
+	g.children = nil
+	g.mu.Unlock()
+
+	for _, child := range children {
+		Join(g.ctx, child)
+	}
+	return g.err
+}
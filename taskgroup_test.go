@@ -0,0 +1,68 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestGroup(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		r := testRegistry(t)
+		attrUser := RegisterWith[string](r, "user")
+		attrQueries := RegisterWith(r, "queries",
+			WithMerge(func(old, new int) int { return old + new }),
+			WithCommutativeMerge[int](),
+		)
+
+		ctx := New(context.Background())
+		Set(ctx, attrUser, "usr_123")
+
+		errRefused := errors.New("connection refused")
+		g, gctx := Group(ctx)
+		for _, d := range []time.Duration{10 * time.Millisecond, 30 * time.Millisecond} {
+			g.Go("shard", func(ctx context.Context) error {
+				Set(ctx, attrQueries, 2)
+				time.Sleep(d)
+				return nil
+			})
+		}
+		g.Go("orders", func(ctx context.Context) error {
+			Set(ctx, attrQueries, 1)
+			time.Sleep(20 * time.Millisecond)
+			return errRefused
+		})
+
+		if err := g.Wait(); err != errRefused {
+			t.Errorf("Wait = %v, want %v", err, errRefused)
+		}
+		if context.Cause(gctx) != errRefused {
+			t.Errorf("group context cause = %v, want %v", context.Cause(gctx), errRefused)
+		}
+
+		var buf bytes.Buffer
+		Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+		want := "level=INFO msg=canonical-log-line user=usr_123 queries=5 " +
+			"goroutines.orders.count=1 goroutines.orders.duration=20ms goroutines.orders.errors=1 goroutines.orders.error=\"connection refused\" " +
+			"goroutines.shard.count=2 goroutines.shard.duration=40ms goroutines.shard.errors=0\n"
+		if got := buf.String(); got != want {
+			t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+}
+
+func TestGroup_WithoutLine(t *testing.T) {
+	g, gctx := Group(context.Background())
+	g.Go("noop", func(ctx context.Context) error { return nil })
+	if err := g.Wait(); err != nil {
+		t.Errorf("Wait = %v, want nil", err)
+	}
+	if gctx.Err() == nil {
+		t.Error("group context not canceled after Wait")
+	}
+}
@@ -0,0 +1,100 @@
+package canonlog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// TokenizationFailed is emitted in place of the value of an attribute
+// registered [WithTokenize] if it could not be tokenized, or its registry
+// has no tokenizer, so that the identifier is never emitted.
+const TokenizationFailed = "!TOKENIZATION_FAILED"
+
+// errNoTokenizer reports that an attribute registered WithTokenize has no
+// tokenizer to use.
+var errNoTokenizer = errors.New("registry has no tokenizer")
+
+// A Tokenizer replaces identifiers, such as user IDs and email addresses,
+// with tokens issued by an external service, which can map them back to the
+// identifiers for as long as the tokens are valid, for [WithTokenize]. It
+// must be safe for concurrent use, and should cache tokens, since it is
+// called whenever the attributes of a line are read.
+type Tokenizer interface {
+	// Tokenize returns the token for value, the value of the attribute
+	// with the given key.
+	Tokenize(key, value string) (string, error)
+}
+
+// SetTokenizer sets the tokenizer used by the attributes of r registered
+// [WithTokenize].
+func (r *Registry) SetTokenizer(t Tokenizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokenizer = t
+}
+
+// getTokenizer returns the tokenizer set with [Registry.SetTokenizer].
+func (r *Registry) getTokenizer() Tokenizer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tokenizer
+}
+
+// WithTokenize makes the attribute emit tokens in place of its values, as
+// issued by the tokenizer of the registry it is registered in (see
+// [Registry.SetTokenizer]), so that user identifiers can be erased from
+// historical logs, such as to honor a deletion request, by invalidating
+// their tokens rather than scrubbing the logs:
+//
+//	canonlog.DefaultRegistry.SetTokenizer(vaultTokenizer)
+//	var AttrUserID = canonlog.Register("user_id", canonlog.WithTokenize[string]())
+//
+// Values are stored as is in the line, and tokenized whenever its
+// attributes are read, such as when it is emitted. The tokenized value is
+// the string form of the value as converted by the attribute's [WithValue]
+// function, if any. If it cannot be tokenized, the value is emitted as
+// [TokenizationFailed], and the error is logged to [slog.Default] in
+// [ModeDebug] or causes a panic in [ModeStrict]. Tokens are encrypted if
+// the attribute is also registered [WithEncrypt].
+func WithTokenize[T any]() Option[T] {
+	return func(a *Attr[T]) {
+		a.tokenize = true
+	}
+}
+
+// tokenizing returns a converter calling convert, or [slog.AnyValue] if it
+// is nil, and replacing the result with its token from the tokenizer of r.
+func tokenizing(r *Registry, key string, convert func(any) slog.Value) func(any) slog.Value {
+	return func(v any) slog.Value {
+		var sv slog.Value
+		if convert != nil {
+			sv = convert(v)
+		} else {
+			sv = slog.AnyValue(v)
+		}
+
+		t := r.getTokenizer()
+		if t == nil {
+			return tokenizationFailed(key, errNoTokenizer)
+		}
+		token, err := t.Tokenize(key, sv.Resolve().String())
+		if err != nil {
+			return tokenizationFailed(key, err)
+		}
+		return slog.StringValue(token)
+	}
+}
+
+// tokenizationFailed handles a failure to tokenize the value of the
+// attribute with the given key according to the current [Mode], and returns
+// the value to emit in its place.
+func tokenizationFailed(key string, err error) slog.Value {
+	switch currentMode() {
+	case ModeDebug:
+		slog.Warn("canonlog: attribute value could not be tokenized", "key", key, "error", err)
+	case ModeStrict:
+		panic(fmt.Sprintf("canonlog: value of attribute %q could not be tokenized: %v", key, err))
+	}
+	return slog.StringValue(TokenizationFailed)
+}
@@ -0,0 +1,56 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// testTokenizer issues sequential tokens, and fails for revoked values.
+type testTokenizer struct {
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func (t *testTokenizer) Tokenize(key, value string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if value == "revoked" {
+		return "", errors.New("token revoked")
+	}
+	tok, ok := t.tokens[value]
+	if !ok {
+		tok = "tok_" + string(rune('a'+len(t.tokens)))
+		t.tokens[value] = tok
+	}
+	return tok, nil
+}
+
+func TestWithTokenize(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith(r, "user", WithTokenize[string]())
+	attrEmail := RegisterWith(r, "email", WithTokenize[string](), WithEncrypt[string](testEncryptor{}))
+	attrOther := RegisterWith(r, "other", WithTokenize[string]())
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrUser, "usr_123")
+	want := []slog.Attr{slog.String("user", TokenizationFailed)}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs without a tokenizer = %v, want %v", got, want)
+	}
+
+	r.SetTokenizer(&testTokenizer{tokens: make(map[string]string)})
+	Set(ctx, attrEmail, "alice@example.com")
+	Set(ctx, attrOther, "revoked")
+	want = []slog.Attr{
+		slog.String("user", "tok_a"),
+		slog.String("email", "email:"+"dG9rX2I="), // base64 of "tok_b"
+		slog.String("other", TokenizationFailed),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package canonlog
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// TopEntry is a named entry of a top-K attribute, ranked by its weight.
+type TopEntry[W cmp.Ordered] struct {
+	Name   string
+	Weight W
+}
+
+// RegisterTopKWith creates a new top-K attribute with the given key in the
+// specified registry. It panics if an attribute with the same key has already
+// been registered in that registry, or if k is not positive.
+//
+// A top-K attribute keeps only the k entries with the greatest weight, added
+// with [AddTop], which bounds the size of the attribute while preserving the
+// most useful detail. An entry added more than once keeps its greatest
+// weight. The attribute is emitted as an [slog.Group] mapping each entry's
+// name to its weight, heaviest first:
+//
+//	var AttrSlowQueries = canonlog.RegisterTopK[time.Duration]("slowest_queries", 5)
+//
+//	canonlog.AddTop(ctx, AttrSlowQueries, "SELECT * FROM users", 120*time.Millisecond)
+func RegisterTopKWith[W cmp.Ordered](r *Registry, key string, k int, opts ...Option[[]TopEntry[W]]) Attr[[]TopEntry[W]] {
+	if k <= 0 {
+		panic("canonlog: top-K attribute " + key + " must have a positive k")
+	}
+	opts = append([]Option[[]TopEntry[W]]{
+		WithMerge(func(old, new []TopEntry[W]) []TopEntry[W] {
+			merged := make([]TopEntry[W], 0, len(old)+len(new))
+			merged = append(merged, old...)
+			merged = append(merged, new...)
+			return topK(merged, k)
+		}),
+		WithCommutativeMerge[[]TopEntry[W]](),
+		WithValue(func(entries []TopEntry[W]) slog.Value {
+			entries = topK(slices.Clone(entries), k)
+			attrs := make([]slog.Attr, len(entries))
+			for i, e := range entries {
+				attrs[i] = slog.Any(e.Name, e.Weight)
+			}
+			return slog.GroupValue(attrs...)
+		}),
+	}, opts...)
+	return RegisterWith(r, key, opts...)
+}
+
+// RegisterTopK creates a new top-K attribute with the given key using
+// [DefaultRegistry]. See [RegisterTopKWith] for details.
+func RegisterTopK[W cmp.Ordered](key string, k int, opts ...Option[[]TopEntry[W]]) Attr[[]TopEntry[W]] {
+	return RegisterTopKWith(DefaultRegistry, key, k, opts...)
+}
+
+// AddTop adds an entry with the given name and weight to the top-K attribute
+// attr in the [Line] attached to ctx. If the context does not have a Line,
+// AddTop silently does nothing.
+func AddTop[W cmp.Ordered](ctx context.Context, attr Attr[[]TopEntry[W]], name string, weight W) {
+	Set(ctx, attr, []TopEntry[W]{{Name: name, Weight: weight}})
+}
+
+// topK sorts entries by descending weight (then by name), keeps the greatest
+// weight for each name, and truncates the result to k entries. It modifies
+// entries in place.
+func topK[W cmp.Ordered](entries []TopEntry[W], k int) []TopEntry[W] {
+	slices.SortFunc(entries, func(a, b TopEntry[W]) int {
+		if c := cmp.Compare(b.Weight, a.Weight); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	// Entries for the same name are not adjacent after sorting, but the
+	// first one seen for each name has its greatest weight.
+	seen := make(map[string]bool, len(entries))
+	result := entries[:0]
+	for _, e := range entries {
+		if seen[e.Name] {
+			continue
+		}
+		seen[e.Name] = true
+		result = append(result, e)
+		if len(result) == k {
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRegisterTopK(t *testing.T) {
+	r := testRegistry(t)
+	attrSlow := RegisterTopKWith[time.Duration](r, "slowest", 2)
+
+	ctx := New(context.Background())
+	AddTop(ctx, attrSlow, "a", 10*time.Millisecond)
+	AddTop(ctx, attrSlow, "b", 30*time.Millisecond)
+	AddTop(ctx, attrSlow, "c", 20*time.Millisecond)
+	AddTop(ctx, attrSlow, "a", 40*time.Millisecond)
+	AddTop(ctx, attrSlow, "a", 5*time.Millisecond)
+
+	var buf bytes.Buffer
+	Emit(ctx, testLogger(&buf), slog.LevelInfo)
+
+	want := "level=INFO msg=canonical-log-line slowest.a=40ms slowest.b=30ms\n"
+	if got := buf.String(); got != want {
+		t.Errorf("log output:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestRegisterTopK_SingleSet(t *testing.T) {
+	r := testRegistry(t)
+	attrTop := RegisterTopKWith[int](r, "top", 2)
+
+	// A single Set of several entries is still truncated and ordered.
+	ctx := New(context.Background())
+	Set(ctx, attrTop, []TopEntry[int]{{"x", 1}, {"y", 3}, {"z", 2}})
+
+	group := Attrs(ctx)[0].Value.Group()
+	var got []string
+	for _, a := range group {
+		got = append(got, a.Key)
+	}
+	if want := []string{"y", "z"}; !slices.Equal(got, want) {
+		t.Errorf("top entries = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterTopK_InvalidK(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("RegisterTopKWith did not panic with k=0")
+		}
+	}()
+	RegisterTopKWith[int](testRegistry(t), "top", 0)
+}
+
+func TestRegisterTopK_MergeProperties(t *testing.T) {
+	attr := RegisterTopKWith[int64](testRegistry(t), "top", 3)
+	checkMergeProperties(t, attr, slices.Equal[[]TopEntry[int64]], nil)
+}
@@ -0,0 +1,161 @@
+package canonlog
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+)
+
+// TraceparentHeader is the header carrying the W3C Trace Context of a
+// request; see https://www.w3.org/TR/trace-context/.
+const TraceparentHeader = "traceparent"
+
+var (
+	attrTraceID      = RegisterWith[string](httpRegistry, "trace_id")
+	attrSpanID       = RegisterWith[string](httpRegistry, "span_id")
+	attrParentSpanID = RegisterWith[string](httpRegistry, "parent_span_id")
+)
+
+// traceContext is the W3C Trace Context of the operation described by a
+// line.
+type traceContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	flags   byte
+}
+
+// traceKey is the context key for storing the traceContext.
+type traceKey struct{}
+
+// WithTraceparent makes the middleware propagate W3C Trace Context, so that
+// the canonical lines of the services handling a request share a trace ID
+// without a full tracing stack. The trace ID is taken from the request's
+// [TraceparentHeader] header if it has a valid one, and otherwise generated
+// randomly; a new span ID is generated for the request. They are recorded
+// under the "trace_id" and "span_id" keys, along with the span ID of the
+// caller under "parent_span_id" if there was one.
+//
+// Outbound requests made with the request's context through a [Transport]
+// carry the trace ID on to the services they call, or use [Traceparent] to
+// propagate it another way.
+func WithTraceparent() MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.onRequest = append(cfg.onRequest, func(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			parent, ok := parseTraceparent(r.Header.Get(TraceparentHeader))
+			if !ok {
+				fillRandomID(parent.traceID[:])
+			}
+			tc := parent
+			fillRandomID(tc.spanID[:])
+
+			Set(ctx, attrTraceID, hex.EncodeToString(tc.traceID[:]))
+			Set(ctx, attrSpanID, hex.EncodeToString(tc.spanID[:]))
+			if ok {
+				Set(ctx, attrParentSpanID, hex.EncodeToString(parent.spanID[:]))
+			}
+			return context.WithValue(ctx, traceKey{}, tc)
+		})
+	}
+}
+
+// Traceparent returns the value of the [TraceparentHeader] header to send
+// on outbound requests made on behalf of the request whose context is ctx,
+// as set up by the middleware with [WithTraceparent], or the empty string
+// if ctx does not carry a trace context.
+func Traceparent(ctx context.Context) string {
+	tc, ok := ctx.Value(traceKey{}).(traceContext)
+	if !ok {
+		return ""
+	}
+	buf := make([]byte, 0, 55)
+	buf = append(buf, "00-"...)
+	buf = hex.AppendEncode(buf, tc.traceID[:])
+	buf = append(buf, '-')
+	buf = hex.AppendEncode(buf, tc.spanID[:])
+	buf = append(buf, '-')
+	buf = hex.AppendEncode(buf, []byte{tc.flags})
+	return string(buf)
+}
+
+// Transport is an [http.RoundTripper] that propagates the trace context of
+// the request whose context an outbound request is made with, as described
+// for [WithTraceparent]:
+//
+//	client := &http.Client{Transport: &canonlog.Transport{}}
+//	req, _ := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+//	resp, err := client.Do(req)
+//
+// Requests that already have a [TraceparentHeader] header are sent
+// unchanged.
+type Transport struct {
+	// Base sends the requests. If nil, [http.DefaultTransport] is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Header.Get(TraceparentHeader) == "" {
+		if tp := Traceparent(req.Context()); tp != "" {
+			// A RoundTripper must not modify the request it is given.
+			req = req.Clone(req.Context())
+			req.Header.Set(TraceparentHeader, tp)
+		}
+	}
+	return base.RoundTrip(req)
+}
+
+// parseTraceparent parses the value of a [TraceparentHeader] header,
+// reporting whether it is valid.
+func parseTraceparent(s string) (traceContext, bool) {
+	var tc traceContext
+	// version "-" trace-id "-" parent-id "-" flags, with later versions
+	// allowed to append further fields.
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || len(s) > 55 && s[55] != '-' {
+		return tc, false
+	}
+	var version [1]byte
+	if !decodeHex(version[:], s[0:2]) || version[0] == 0xff || version[0] == 0 && len(s) != 55 {
+		return tc, false
+	}
+	var flags [1]byte
+	if !decodeHex(tc.traceID[:], s[3:35]) || !decodeHex(tc.spanID[:], s[36:52]) || !decodeHex(flags[:], s[53:55]) {
+		return tc, false
+	}
+	if tc.traceID == [16]byte{} || tc.spanID == [8]byte{} {
+		return tc, false
+	}
+	tc.flags = flags[0]
+	return tc, true
+}
+
+// decodeHex decodes the lowercase hexadecimal s into dst, reporting whether
+// s was valid and of the right length.
+func decodeHex(dst []byte, s string) bool {
+	for i := range len(s) {
+		if c := s[i]; 'A' <= c && c <= 'F' {
+			return false
+		}
+	}
+	n, err := hex.Decode(dst, []byte(s))
+	return err == nil && n == len(dst) && len(s) == 2*len(dst)
+}
+
+// fillRandomID fills id with random bytes, not all zero, for use as a trace
+// or span ID.
+func fillRandomID(id []byte) {
+	for {
+		nonzero := false
+		for i := range id {
+			id[i] = byte(rand.Uint32())
+			nonzero = nonzero || id[i] != 0
+		}
+		if nonzero {
+			return
+		}
+	}
+}
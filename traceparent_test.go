@@ -0,0 +1,95 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g", false},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", false},
+	}
+	for _, tt := range tests {
+		if _, ok := parseTraceparent(tt.in); ok != tt.want {
+			t.Errorf("parseTraceparent(%q) ok = %v, want %v", tt.in, ok, tt.want)
+		}
+	}
+}
+
+func TestWithTraceparent(t *testing.T) {
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+
+	var outbound string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Get(TraceparentHeader)
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	var (
+		buf    bytes.Buffer
+		spanID string
+	)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanID, _ = lookup(FromContext(r.Context()), attrSpanID)
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(TraceparentHeader, "00-"+traceID+"-"+parentID+"-01")
+	Middleware(testLogger(&buf), WithTraceparent())(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := "00-" + traceID + "-" + spanID + "-01"; outbound != want {
+		t.Errorf("outbound traceparent = %q, want %q", outbound, want)
+	}
+	want := " trace_id=" + traceID + " span_id=" + spanID + " parent_span_id=" + parentID + " "
+	if got := buf.String(); len(spanID) != 16 || !strings.Contains(got, want) {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+}
+
+func TestWithTraceparent_Generated(t *testing.T) {
+	var tp string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tp = Traceparent(r.Context())
+	})
+	var buf bytes.Buffer
+	Middleware(testLogger(&buf), WithTraceparent())(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if _, ok := parseTraceparent(tp); !ok {
+		t.Errorf("Traceparent = %q, want a valid generated value", tp)
+	}
+	if strings.Contains(buf.String(), "parent_span_id") {
+		t.Errorf("log output = %q, want no parent_span_id", buf.String())
+	}
+}
+
+func TestTraceparent_WithoutTrace(t *testing.T) {
+	if tp := Traceparent(context.Background()); tp != "" {
+		t.Errorf("Traceparent = %q, want empty", tp)
+	}
+}
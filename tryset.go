@@ -0,0 +1,103 @@
+package canonlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Errors returned by [TrySet], wrapped with the key of the attribute.
+var (
+	// ErrNoLine reports that the context does not have a [Line].
+	ErrNoLine = errors.New("canonlog: no line in context")
+
+	// ErrEmitted reports that the line has already been emitted.
+	ErrEmitted = errors.New("canonlog: line already emitted")
+
+	// ErrTooLarge reports that storing the value would exceed the line's
+	// size limit; see [Registry.SetMaxBytes].
+	ErrTooLarge = errors.New("canonlog: line size limit exceeded")
+)
+
+// WithValidator sets a function that checks each value set for the
+// attribute, before it is merged with any existing value. Values for which
+// fn returns an error are not stored: [TrySet] returns the error, and [Set]
+// drops the value, logging it to [slog.Default] in [ModeDebug] and
+// panicking in [ModeStrict].
+//
+//	var AttrStatus = canonlog.Register("status",
+//		canonlog.WithValidator(func(code int) error {
+//			if code < 100 || code > 599 {
+//				return fmt.Errorf("invalid HTTP status %d", code)
+//			}
+//			return nil
+//		}),
+//	)
+func WithValidator[T any](fn func(T) error) Option[T] {
+	return func(a *Attr[T]) {
+		a.validate = fn
+	}
+}
+
+// valid reports whether value passes a's validator, handling a value that
+// does not according to the current [Mode].
+func (a Attr[T]) valid(value T) bool {
+	if a.validate == nil {
+		return true
+	}
+	err := a.validate(value)
+	if err == nil {
+		return true
+	}
+	invalidValues.Add(1)
+	switch currentMode() {
+	case ModeDebug:
+		slog.Warn("canonlog: invalid attribute value dropped",
+			"key", a.key, "error", err, "caller", caller())
+	case ModeStrict:
+		panic(fmt.Sprintf("canonlog: invalid value for attribute %q set at %s: %v", a.key, caller(), err))
+	}
+	return false
+}
+
+// TrySet is like [Set], but returns an error if the value was not stored,
+// rather than silently dropping it, for libraries that want to surface
+// instrumentation failures, such as in their tests. The error wraps
+// [ErrNoLine] if the context does not have a [Line], [ErrEmitted] if the
+// line has already been emitted, the error of the attribute's validator
+// (see [WithValidator]) if it rejects the value, and [ErrTooLarge] if the
+// value was dropped for exceeding the line's size limit. Such failures are
+// neither logged nor cause panics whatever the [Mode], although late sets
+// and dropped values are still counted as described for [LateSetsKey] and
+// [CappedSetsKey].
+func TrySet[T any](ctx context.Context, attr Attr[T], value T) error {
+	l := FromContext(ctx)
+	if l == nil {
+		return fmt.Errorf("setting %q: %w", attr.key, ErrNoLine)
+	}
+	if l.frozen.Load() {
+		l.lateSets.Add(1)
+		lateSets.Add(1)
+		return fmt.Errorf("setting %q: %w", attr.key, ErrEmitted)
+	}
+	if attr.validate != nil {
+		if err := attr.validate(value); err != nil {
+			invalidValues.Add(1)
+			return fmt.Errorf("setting %q: %w", attr.key, err)
+		}
+	}
+
+	if attr.commutative && attr.merge != nil {
+		if !accumulateOn(ctx, l, attr, value) {
+			return fmt.Errorf("setting %q: %w", attr.key, ErrTooLarge)
+		}
+		return nil
+	}
+	if !store(l, attr, value, attr.merge) {
+		return fmt.Errorf("setting %q: %w", attr.key, ErrTooLarge)
+	}
+	l.recordCaller(attr.key)
+	runSetHooks(ctx, l, attr.key, value)
+	return nil
+}
@@ -0,0 +1,111 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var errNegative = errors.New("negative")
+
+func TestTrySet(t *testing.T) {
+	withMode(t, ModeStrict) // TrySet must not panic
+	r := testRegistry(t)
+	r.SetMaxBytes(64)
+	attrCount := RegisterWith(r, "count", WithValidator(func(n int) error {
+		if n < 0 {
+			return errNegative
+		}
+		return nil
+	}))
+	attrBody := RegisterWith[string](r, "body")
+	attrTags := RegisterSetWith[string](r, "tags")
+
+	if err := TrySet(context.Background(), attrCount, 1); !errors.Is(err, ErrNoLine) {
+		t.Errorf("TrySet without a line = %v, want ErrNoLine", err)
+	}
+
+	ctx := New(context.Background(), WithRegistry(r))
+	if err := TrySet(ctx, attrCount, 1); err != nil {
+		t.Errorf("TrySet = %v, want nil", err)
+	}
+	if err := TrySet(ctx, attrCount, -1); !errors.Is(err, errNegative) {
+		t.Errorf("TrySet with an invalid value = %v, want the validator's error", err)
+	}
+	err := TrySet(ctx, attrBody, strings.Repeat("x", 100))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("TrySet with a large value = %v, want ErrTooLarge", err)
+	}
+	if got, want := err.Error(), `setting "body": canonlog: line size limit exceeded`; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	if err := TrySet(ctx, attrTags, []string{strings.Repeat("x", 100)}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("TrySet with a large set = %v, want ErrTooLarge", err)
+	}
+
+	Emit(ctx, slog.New(slog.DiscardHandler), slog.LevelInfo)
+	if err := TrySet(ctx, attrCount, 2); !errors.Is(err, ErrEmitted) {
+		t.Errorf("TrySet after emitting = %v, want ErrEmitted", err)
+	}
+
+	want := []slog.Attr{
+		slog.Int("count", 1),
+		slog.Int64(LateSetsKey, 1),
+		slog.Int64(CappedSetsKey, 2),
+	}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+}
+
+func TestWithValidator_Set(t *testing.T) {
+	r := testRegistry(t)
+	attrCount := RegisterWith(r, "count",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+		WithValidator(func(n int) error {
+			if n < 0 {
+				return errNegative
+			}
+			return nil
+		}),
+	)
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrCount, 2)
+	Set(ctx, attrCount, -1)
+	Set(ctx, attrCount, 3)
+
+	want := []slog.Attr{slog.Int("count", 5)}
+	if got := Attrs(ctx); !slices.EqualFunc(got, want, slog.Attr.Equal) {
+		t.Errorf("Attrs = %v, want %v", got, want)
+	}
+
+	t.Run("ModeDebug", func(t *testing.T) {
+		withMode(t, ModeDebug)
+		var buf bytes.Buffer
+		prev := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+		t.Cleanup(func() { slog.SetDefault(prev) })
+
+		Set(ctx, attrCount, -1)
+		if got := buf.String(); !strings.Contains(got, "invalid attribute value dropped") ||
+			!strings.Contains(got, "tryset_test.go") {
+			t.Errorf("log output = %q, want a warning with the caller", got)
+		}
+	})
+
+	t.Run("ModeStrict", func(t *testing.T) {
+		withMode(t, ModeStrict)
+		defer func() {
+			if recover() == nil {
+				t.Error("Set of an invalid value did not panic")
+			}
+		}()
+		Set(ctx, attrCount, -1)
+	})
+}
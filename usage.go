@@ -0,0 +1,57 @@
+package canonlog
+
+import "sync/atomic"
+
+// Usage describes how often attributes were set on the lines associated
+// with a [Registry], as returned by [Registry.Usage].
+type Usage struct {
+	// Lines is the number of lines emitted.
+	Lines int64
+
+	// Keys holds, for every attribute registered in the registry and any
+	// other key set on its lines, the number of emitted lines it was set
+	// on. Registered attributes that were never set have a count of zero.
+	// Keys added by [WithAlias] are counted under the key they alias.
+	Keys map[string]int64
+}
+
+// Usage returns statistics about the attributes set on the lines associated
+// with r (see [WithRegistry]) that have been emitted since the process
+// started, so that attributes that are never set can be pruned, and code
+// paths that miss setting expected attributes can be found:
+//
+//	u := canonlog.DefaultRegistry.Usage()
+//	for key, n := range u.Keys {
+//		if n == 0 {
+//			log.Printf("attribute %q is never set", key)
+//		}
+//	}
+//
+// A line is counted once, the first time it is emitted.
+func (r *Registry) Usage() Usage {
+	r.mu.Lock()
+	keys := make(map[string]int64, len(r.setters))
+	for key := range r.setters {
+		keys[key] = 0
+	}
+	r.mu.Unlock()
+
+	u := Usage{Lines: r.lines.Load(), Keys: keys}
+	r.usage.Range(func(key, n any) bool {
+		u.Keys[key.(string)] = n.(*atomic.Int64).Load()
+		return true
+	})
+	return u
+}
+
+// recordUsage records that a line with the given keys set was emitted.
+func (r *Registry) recordUsage(keys []string) {
+	r.lines.Add(1)
+	for _, key := range keys {
+		n, ok := r.usage.Load(key)
+		if !ok {
+			n, _ = r.usage.LoadOrStore(key, new(atomic.Int64))
+		}
+		n.(*atomic.Int64).Add(1)
+	}
+}
@@ -0,0 +1,40 @@
+package canonlog
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	r := testRegistry(t)
+	attrUser := RegisterWith[string](r, "user")
+	attrQueries := RegisterWith(r, "queries",
+		WithMerge(func(old, new int) int { return old + new }),
+		WithCommutativeMerge[int](),
+	)
+	RegisterWith[string](r, "legacy_id")
+	r.SetSchemaVersion("3")
+
+	e := NewEmitter(slog.New(slog.DiscardHandler))
+	for i := range 3 {
+		ctx := New(context.Background(), WithRegistry(r))
+		Set(ctx, attrUser, "usr_123")
+		if i == 0 {
+			Set(ctx, attrQueries, 1)
+			SetMany(ctx, map[string]any{"region": "eu"})
+		}
+		e.Emit(ctx, slog.LevelInfo)
+		e.Emit(ctx, slog.LevelInfo) // counted once
+	}
+
+	u := r.Usage()
+	if u.Lines != 3 {
+		t.Errorf("Lines = %d, want 3", u.Lines)
+	}
+	want := map[string]int64{"user": 3, "queries": 1, "region": 1, "legacy_id": 0}
+	if !maps.Equal(u.Keys, want) {
+		t.Errorf("Keys = %v, want %v", u.Keys, want)
+	}
+}
@@ -0,0 +1,115 @@
+package canonlog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+var (
+	attrUABrowser = RegisterWith[string](httpRegistry, "ua_browser")
+	attrUAOS      = RegisterWith[string](httpRegistry, "ua_os")
+	attrUABot     = RegisterWith[bool](httpRegistry, "ua_bot")
+)
+
+// UserAgent describes the client that made a request, as parsed from its
+// User-Agent header by a [UserAgentParser]. Empty fields are not recorded.
+type UserAgent struct {
+	Browser string // such as "Chrome" or "Firefox"
+	OS      string // such as "Windows" or "iOS"
+	Bot     bool   // whether the client is an automated crawler or tool
+}
+
+// A UserAgentParser parses User-Agent headers. Implementations wrapping a
+// full parsing library can be used with [WithUserAgent] for more detailed
+// results than [BasicUserAgentParser] gives.
+type UserAgentParser interface {
+	ParseUserAgent(ua string) UserAgent
+}
+
+// WithUserAgent makes the middleware parse the User-Agent header of each
+// request with p and record the result under the "ua_browser", "ua_os" and
+// "ua_bot" keys, which are more useful for analysis than the raw header. If
+// p is nil, [BasicUserAgentParser] is used. Requests without a User-Agent
+// header are not recorded.
+func WithUserAgent(p UserAgentParser) MiddlewareOption {
+	if p == nil {
+		p = BasicUserAgentParser{}
+	}
+	return func(cfg *middlewareConfig) {
+		cfg.onRequest = append(cfg.onRequest, func(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+			header := r.UserAgent()
+			if header == "" {
+				return ctx
+			}
+			ua := p.ParseUserAgent(header)
+			if ua.Browser != "" {
+				Set(ctx, attrUABrowser, ua.Browser)
+			}
+			if ua.OS != "" {
+				Set(ctx, attrUAOS, ua.OS)
+			}
+			Set(ctx, attrUABot, ua.Bot)
+			return ctx
+		})
+	}
+}
+
+// BasicUserAgentParser is a [UserAgentParser] that recognizes the most
+// common browsers, operating systems and bots by matching tokens in the
+// User-Agent header, without any dependencies. It does not determine
+// versions.
+type BasicUserAgentParser struct{}
+
+// uaRule maps a token found in a User-Agent header to a name. Rules are
+// tried in order, so more specific tokens come first: Edge and Opera
+// headers also contain "Chrome", and Chrome headers also contain "Safari".
+type uaRule struct{ token, name string }
+
+var (
+	uaBrowsers = []uaRule{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	uaOSes = []uaRule{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+	uaBotTokens = []string{"bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests", "go-http-client"}
+)
+
+// ParseUserAgent implements [UserAgentParser].
+func (BasicUserAgentParser) ParseUserAgent(header string) UserAgent {
+	var ua UserAgent
+	for _, rule := range uaBrowsers {
+		if strings.Contains(header, rule.token) {
+			ua.Browser = rule.name
+			break
+		}
+	}
+	for _, rule := range uaOSes {
+		if strings.Contains(header, rule.token) {
+			ua.OS = rule.name
+			break
+		}
+	}
+	lower := strings.ToLower(header)
+	for _, token := range uaBotTokens {
+		if strings.Contains(lower, token) {
+			ua.Bot = true
+			break
+		}
+	}
+	return ua
+}
@@ -0,0 +1,68 @@
+package canonlog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicUserAgentParser(t *testing.T) {
+	tests := []struct {
+		header string
+		want   UserAgent
+	}{
+		{
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			want:   UserAgent{Browser: "Chrome", OS: "Windows"},
+		},
+		{
+			header: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+			want:   UserAgent{Browser: "Safari", OS: "macOS"},
+		},
+		{
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0",
+			want:   UserAgent{Browser: "Edge", OS: "Windows"},
+		},
+		{
+			header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/126.0.6478.54 Mobile/15E148 Safari/604.1",
+			want:   UserAgent{Browser: "Chrome", OS: "iOS"},
+		},
+		{
+			header: "Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0",
+			want:   UserAgent{Browser: "Firefox", OS: "Linux"},
+		},
+		{
+			header: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:   UserAgent{Bot: true},
+		},
+		{
+			header: "curl/8.7.1",
+			want:   UserAgent{Browser: "curl", Bot: true},
+		},
+	}
+	for _, tt := range tests {
+		if got := (BasicUserAgentParser{}).ParseUserAgent(tt.header); got != tt.want {
+			t.Errorf("ParseUserAgent(%q) = %+v, want %+v", tt.header, got, tt.want)
+		}
+	}
+}
+
+type fixedParser UserAgent
+
+func (p fixedParser) ParseUserAgent(string) UserAgent { return UserAgent(p) }
+
+func TestWithUserAgent(t *testing.T) {
+	var buf bytes.Buffer
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := Middleware(testLogger(&buf), WithUserAgent(fixedParser{Browser: "Lynx", OS: "BeOS"}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", "Lynx/2.9")
+	mw(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	if got, want := buf.String(), " ua_browser=Lynx ua_os=BeOS ua_bot=false "; !strings.Contains(got, want) {
+		t.Errorf("log output = %q, want it to contain %q", got, want)
+	}
+}
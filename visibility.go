@@ -0,0 +1,60 @@
+package canonlog
+
+// Visibility classifies attributes by who may see them, so that each sink
+// only receives the attributes it is cleared for; see [WithVisibility] and
+// [WithAllowedVisibility].
+type Visibility uint8
+
+const (
+	// VisibilityPublic is for attributes that any sink may receive. It is
+	// the default.
+	VisibilityPublic Visibility = iota
+
+	// VisibilityInternal is for attributes that must stay within the
+	// organization, such as internal hostnames.
+	VisibilityInternal
+
+	// VisibilitySensitive is for attributes that only the most trusted
+	// sinks may receive, such as user identifiers.
+	VisibilitySensitive
+)
+
+// WithVisibility sets the attribute's visibility class, which defaults to
+// [VisibilityPublic]:
+//
+//	var AttrEmail = canonlog.Register[string]("email",
+//		canonlog.WithVisibility[string](canonlog.VisibilitySensitive),
+//	)
+func WithVisibility[T any](v Visibility) Option[T] {
+	return func(a *Attr[T]) {
+		a.visibility = v
+	}
+}
+
+// visibilitySet is a set of visibility classes, holding class v if bit v is
+// set. The zero value holds every class.
+type visibilitySet uint8
+
+// has reports whether s holds v.
+func (s visibilitySet) has(v Visibility) bool {
+	return s == 0 || s&(1<<v) != 0
+}
+
+// WithAllowedVisibility makes the [Emitter] emit only the attributes of the
+// given visibility classes, so that, for example, a vendor-hosted sink never
+// receives attributes that an on-premises one does:
+//
+//	vendor := canonlog.NewEmitter(vendorLogger,
+//		canonlog.WithAllowedVisibility(canonlog.VisibilityPublic),
+//	)
+//
+// Attributes not set through a registered [Attr], such as those from
+// enrichers and [SetMany], are public. By default, an Emitter emits
+// attributes of every class.
+func WithAllowedVisibility(vs ...Visibility) EmitterOption {
+	return func(e *Emitter) {
+		for _, v := range vs {
+			e.visibility |= 1 << v
+		}
+	}
+}
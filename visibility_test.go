@@ -0,0 +1,49 @@
+package canonlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestWithAllowedVisibility(t *testing.T) {
+	r := testRegistry(t)
+	attrPath := RegisterWith[string](r, "path")
+	attrHost := RegisterWith(r, "host", WithVisibility[string](VisibilityInternal))
+	attrEmail := RegisterWith(r, "email",
+		WithVisibility[string](VisibilitySensitive),
+		WithGroup[string]("user"),
+	)
+	attrUserID := RegisterWith(r, "id", WithGroup[int]("user"))
+	attrRegion := RegisterWith(r, "region", WithVisibility[string](VisibilityInternal))
+	SetGlobalWith(r, attrRegion, "eu-west-1")
+
+	var vendor, onPrem, all bytes.Buffer
+	e := NewEmitter(testLogger(&all), WithTee(
+		NewEmitter(testLogger(&vendor), WithAllowedVisibility(VisibilityPublic)),
+		NewEmitter(testLogger(&onPrem), WithAllowedVisibility(VisibilityPublic, VisibilityInternal)),
+	))
+
+	ctx := New(context.Background(), WithRegistry(r))
+	Set(ctx, attrPath, "/users")
+	Set(ctx, attrHost, "db-3.internal")
+	Set(ctx, attrEmail, "alice@example.com")
+	Set(ctx, attrUserID, 7)
+	e.Emit(ctx, slog.LevelInfo)
+
+	tests := []struct {
+		name string
+		buf  *bytes.Buffer
+		want string
+	}{
+		{"vendor", &vendor, "level=INFO msg=canonical-log-line path=/users user.id=7\n"},
+		{"on-prem", &onPrem, "level=INFO msg=canonical-log-line path=/users host=db-3.internal user.id=7 region=eu-west-1\n"},
+		{"all", &all, "level=INFO msg=canonical-log-line path=/users host=db-3.internal user.email=alice@example.com user.id=7 region=eu-west-1\n"},
+	}
+	for _, tt := range tests {
+		if got := tt.buf.String(); got != tt.want {
+			t.Errorf("%s output:\ngot:  %q\nwant: %q", tt.name, got, tt.want)
+		}
+	}
+}